@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	Command line mode:
+	- WindowPositioner.exe --list-windows
+	  Prints the visible windows, one per line: Title, ClassName, PID and Rect (left,top,right,bottom)
+	  separated by tabs. Tabs in titles are replaced by spaces.
+	- WindowPositioner.exe --apply-profile <name>
+	  Activates the profile and moves the windows matching its positions.
+	- WindowPositioner.exe --save [--pattern] <title> <x> <y> <width> <height>
+	  Saves a position for the first window with the title. With --pattern the title is a pattern,
+	  see title_pattern.go. The saved rule is for the exact title of the window found.
+	- The commands run without the Fyne app, window or tray and exit with cliOK, cliFailed or cliUsage.
+	  The executable is a GUI program, so the output is only visible when it is redirected or piped.
+	- Without one of these flags the application starts normally.
+*/
+
+// Exit codes of the command line mode
+const (
+	cliOK     = 0 // The command succeeded
+	cliFailed = 1 // The command failed, e.g. a window was not found or could not be moved
+	cliUsage  = 2 // The arguments are invalid
+)
+
+// cliUsageText describes the command line mode
+const cliUsageText = `Usage:
+  WindowPositioner --list-windows
+  WindowPositioner --apply-profile <name>
+  WindowPositioner --save [--pattern] <title> <x> <y> <width> <height>`
+
+// isCLICommand reports whether the arguments start a command line command.
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "--list-windows", "--apply-profile", "--save":
+		return true
+	}
+	return false
+}
+
+// runCLI runs a command line command and returns the exit code.
+// The log is kept in memory and not printed, so it does not mix with the output.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	defer panicHandler()
+	logSilent.Store(true)
+
+	usage := func(message string) int {
+		fmt.Fprintln(stderr, message)
+		fmt.Fprintln(stderr, cliUsageText)
+		return cliUsage
+	}
+	switch args[0] {
+	case "--list-windows":
+		if len(args) != 1 {
+			return usage("--list-windows takes no arguments")
+		}
+		return cliListWindows(stdout, stderr)
+	case "--apply-profile":
+		if len(args) != 2 || strings.TrimSpace(args[1]) == "" {
+			return usage("--apply-profile needs the name of a profile")
+		}
+		return cliApplyProfile(strings.TrimSpace(args[1]), stdout, stderr)
+	case "--save":
+		pattern := len(args) > 1 && args[1] == "--pattern"
+		if pattern {
+			args = append(args[:1:1], args[2:]...)
+		}
+		if len(args) != 6 {
+			return usage("--save needs a title, x, y, width and height")
+		}
+		var values [4]int
+		for i, arg := range args[2:] {
+			value, err := strconv.Atoi(arg)
+			if err != nil {
+				return usage(fmt.Sprintf("not a number: %s", arg))
+			}
+			values[i] = value
+		}
+		if values[2] <= 0 || values[3] <= 0 {
+			return usage("width and height must be positive")
+		}
+		pos := WindowPosition{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+		return cliSave(args[1], pattern, pos, stdout, stderr)
+	}
+	return usage("unknown command: " + args[0])
+}
+
+// cliListWindows prints the visible windows tab-separated.
+func cliListWindows(stdout, stderr io.Writer) int {
+	windows, err := EnumerateWindows()
+	if err != nil {
+		fmt.Fprintln(stderr, "Failed to enumerate windows:", err)
+		return cliFailed
+	}
+	for _, window := range windows {
+		rect := window.WindowRect
+		fmt.Fprintf(stdout, "%s\t%s\t%d\t%d,%d,%d,%d\n",
+			strings.ReplaceAll(window.Title, "\t", " "), window.ClassName, window.ProcessID,
+			rect.Left, rect.Top, rect.Right, rect.Bottom)
+	}
+	return cliOK
+}
+
+// cliApplyProfile activates a profile and repositions the windows matching its positions.
+// Windows of elevated processes that need a confirmation are skipped, as there is nobody to ask.
+func cliApplyProfile(name string, stdout, stderr io.Writer) int {
+	wm := newHeadlessWindowManager()
+	if err := wm.storage.SetActiveProfile(name); err != nil {
+		fmt.Fprintln(stderr, "Failed to apply profile:", err)
+		return cliFailed
+	}
+	result := wm.repositionSavedWindows()
+	fmt.Fprintf(stdout, "Applied profile %s: %s\n", name, result)
+	if result.Err != nil || result.Failed > 0 {
+		return cliFailed
+	}
+	return cliOK
+}
+
+// cliSave saves a position for the first visible window whose title matches.
+// The title is only a pattern if pattern is set.
+func cliSave(title string, pattern bool, pos WindowPosition, stdout, stderr io.Writer) int {
+	wm := newHeadlessWindowManager()
+	windows, err := EnumerateWindows()
+	if err != nil {
+		fmt.Fprintln(stderr, "Failed to enumerate windows:", err)
+		return cliFailed
+	}
+	window, ok := findWindowByTitle(title, pattern, windows)
+	if !ok {
+		fmt.Fprintln(stderr, "No window matches the title:", title)
+		return cliFailed
+	}
+	identifier, err := wm.storeWindowPosition(window, pos)
+	if err != nil {
+		fmt.Fprintln(stderr, "Failed to save position:", err)
+		return cliFailed
+	}
+	fmt.Fprintln(stdout, "Saved position for:", identifier)
+	return cliOK
+}
+
+// newHeadlessWindowManager creates a window manager without the Fyne app and windows,
+// which is enough to reposition windows.
+func newHeadlessWindowManager() *WindowManager {
+	storage, err := NewPositionStorage()
+	if err != nil {
+		log(true, "Settings and positions cannot be saved:", err)
+	}
+	wm := &WindowManager{
+		ctx:     context.Background(),
+		storage: storage,
+		tracker: newWindowTracker(),
+
+		cooldown: newRepositionCooldown(),
+
+		positioned: newPositionedSet(),
+	}
+	settings, err := wm.storage.LoadSettings()
+	if err != nil {
+		log(true, "Failed to load settings, using defaults:", err)
+	}
+	wm.settings = settings
+	logRedactTitles.Store(settings.RedactTitles)
+	setLogLevel(parseLogLevel(settings.LogLevel))
+	setTitleNormalizations(settings.TitleNormalizations)
+	setPositionTolerance(settings.PositionTolerancePixels)
+	stats, err := wm.storage.LoadStrategyStats()
+	if err != nil {
+		log(true, "Failed to load strategy statistics:", err)
+	}
+	wm.strategyStats = stats
+	return wm
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Configuration export and import:
+	- A configuration bundle holds all profiles with their saved positions, the settings and
+	  the monitors the positions were saved on, so the whole configuration can be carried to
+	  a new installation in a single file.
+	- On import, the bundle is either merged into the existing configuration, where saved
+	  positions of the bundle win and the local settings are kept, or replaces it completely.
+	- Positions saved on a monitor that does not exist locally are translated to the local
+	  monitor with the same index, or to the primary monitor, like a workspace import.
+*/
+
+// configBundleVersion is the version of the configuration bundle format
+const configBundleVersion = 1
+
+// ConfigBundle is the exported configuration of the application
+type ConfigBundle struct {
+	Version       int                                  `json:"version"`
+	Product       string                               `json:"product"`
+	Exported      time.Time                            `json:"exported"`
+	Monitors      []Monitor                            `json:"monitors"`
+	ActiveProfile string                               `json:"activeProfile"`
+	Profiles      map[string]map[string]WindowPosition `json:"profiles"`
+	Settings      Settings                             `json:"settings"`
+}
+
+// Export writes all profiles, the settings and the current monitors to a bundle file.
+func (ps *PositionStorage) Export(path string) error {
+	settings, err := ps.LoadSettings()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %v", err)
+	}
+	monitors, err := GetMonitors()
+	if err != nil {
+		logWarn("Failed to get monitors, exporting without monitors:", err)
+	}
+
+	ps.mu.Lock()
+	doc, err := ps.readDocument()
+	ps.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %v", err)
+	}
+
+	bundle := ConfigBundle{
+		Version:       configBundleVersion,
+		Product:       strProductName,
+		Exported:      time.Now(),
+		Monitors:      monitors,
+		ActiveProfile: doc.ActiveProfile,
+		Profiles:      doc.Profiles,
+		Settings:      settings,
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// ReadConfigBundle reads and validates a bundle file without importing it.
+// Settings missing in the bundle keep their default values.
+func ReadConfigBundle(path string) (*ConfigBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &ConfigBundle{Settings: defaultSettings()}
+	if err := json.Unmarshal(data, bundle); err != nil {
+		return nil, fmt.Errorf("not a configuration file: %v", err)
+	}
+	if bundle.Version == 0 || bundle.Profiles == nil {
+		return nil, fmt.Errorf("not a configuration file, it has no version or profiles")
+	}
+	if bundle.Version > configBundleVersion {
+		return nil, fmt.Errorf("the configuration was exported by a newer version (format %d)", bundle.Version)
+	}
+	if _, ok := bundle.Profiles[bundle.ActiveProfile]; !ok {
+		bundle.ActiveProfile = defaultProfile
+	}
+	bundle.Settings = bundle.Settings.clone()
+	return bundle, nil
+}
+
+// Import reads a bundle file and stores its profiles. With replace, all existing profiles and
+// the settings are replaced by the bundle. Otherwise the positions are merged into the existing
+// profiles, positions of the bundle win, and the settings are kept.
+// It returns the imported bundle, its positions translated to the local monitors.
+func (ps *PositionStorage) Import(path string, replace bool) (*ConfigBundle, error) {
+	bundle, err := ReadConfigBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if local, err := GetMonitors(); err != nil {
+		logWarn("Failed to get monitors, importing the coordinates unchanged:", err)
+	} else {
+		bundle.adaptToMonitors(local)
+	}
+
+	ps.mu.Lock()
+	doc, err := ps.readDocument()
+	if err == nil {
+		if replace {
+			doc.Profiles = bundle.Profiles
+			doc.ActiveProfile = bundle.ActiveProfile
+			if doc.Profiles[doc.ActiveProfile] == nil {
+				doc.Profiles[doc.ActiveProfile] = make(map[string]WindowPosition)
+			}
+		} else {
+			for profile, positions := range bundle.Profiles {
+				if doc.Profiles[profile] == nil {
+					doc.Profiles[profile] = make(map[string]WindowPosition)
+				}
+				for identifier, pos := range positions {
+					doc.Profiles[profile][identifier] = pos
+				}
+			}
+		}
+		err = ps.writeDocument(doc)
+	}
+	ps.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save positions: %v", err)
+	}
+
+	if replace {
+		if err := ps.SaveSettings(bundle.Settings); err != nil {
+			return nil, fmt.Errorf("failed to save settings: %v", err)
+		}
+	}
+	return bundle, nil
+}
+
+// adaptToMonitors translates the positions saved on monitors that differ from the local
+// monitors and anchors them to the local monitors. Positions on a monitor with the same
+// device name and bounds are kept unchanged.
+func (bundle *ConfigBundle) adaptToMonitors(local []Monitor) {
+	debug := false
+	if len(bundle.Monitors) == 0 || len(local) == 0 {
+		return
+	}
+	targets := make([]int, len(bundle.Monitors))
+	for i, source := range bundle.Monitors {
+		targets[i] = primaryMonitor(local)
+		if i < len(local) {
+			targets[i] = i
+		}
+		for j, monitor := range local {
+			if monitor.DeviceName == source.DeviceName && monitor.Bounds == source.Bounds {
+				targets[i] = j
+			}
+		}
+	}
+
+	translated := 0
+	for _, positions := range bundle.Profiles {
+		for identifier, pos := range positions {
+			source := monitorForRect(pos.Rect(), bundle.Monitors)
+			if source < 0 {
+				continue
+			}
+			target := local[targets[source]]
+			if target.DeviceName == bundle.Monitors[source].DeviceName && target.Bounds == bundle.Monitors[source].Bounds {
+				continue
+			}
+			positions[identifier] = translateToMonitor(pos, bundle.Monitors[source], target).anchoredToMonitor(local)
+			translated++
+		}
+	}
+	log(debug, "Translated", translated, "imported positions to the local monitors.")
+}
+
+// backupSettingsTab creates the settings tab to export and import the whole configuration.
+// The buttons act immediately, the tab has nothing to save.
+func (wm *WindowManager) backupSettingsTab(window fyne.Window) settingsTab {
+	exportBtn := widget.NewButtonWithIcon("Export configuration…", theme.UploadIcon(), safeCallback(func() {
+		wm.exportConfiguration(window)
+	}))
+	importBtn := widget.NewButtonWithIcon("Import configuration…", theme.DownloadIcon(), safeCallback(func() {
+		wm.importConfiguration(window)
+	}))
+	help := widget.NewLabel("The configuration file contains all profiles with their saved positions and the settings. " +
+		"Positions saved on monitors that are not connected here are moved to the monitor with the same number, " +
+		"or to the primary monitor.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title:   "Backup",
+		content: container.NewVBox(exportBtn, importBtn, help),
+		apply:   func(s *Settings) error { return nil },
+	}
+}
+
+// exportConfiguration writes the configuration to a file chosen by the user.
+func (wm *WindowManager) exportConfiguration(window fyne.Window) {
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		defer panicHandler()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		path := writer.URI().Path()
+		writer.Close()
+		if err := wm.storage.Export(path); err != nil {
+			logError("Failed to export the configuration:", err)
+			dialog.ShowError(err, window)
+			return
+		}
+		logInfo("Exported the configuration to", path)
+	}, window)
+	save.SetFileName("WindowPositioner-configuration.json")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	save.Show()
+}
+
+// importConfiguration reads a configuration file chosen by the user and asks whether it is
+// merged into the existing configuration or replaces it.
+func (wm *WindowManager) importConfiguration(window fyne.Window) {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		defer panicHandler()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		if reader == nil {
+			return // Cancelled
+		}
+		path := reader.URI().Path()
+		reader.Close()
+		bundle, err := ReadConfigBundle(path)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		wm.showImportModeDialog(window, path, bundle)
+	}, window)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	open.Show()
+}
+
+// showImportModeDialog asks how a configuration file is imported and imports it.
+// Replacing the configuration also replaces the settings, so the settings window is closed.
+func (wm *WindowManager) showImportModeDialog(window fyne.Window, path string, bundle *ConfigBundle) {
+	const (
+		merge   = "Merge into the existing configuration, keep the settings"
+		replace = "Replace the existing configuration and settings"
+	)
+	positions := 0
+	for _, profile := range bundle.Profiles {
+		positions += len(profile)
+	}
+	mode := widget.NewRadioGroup([]string{merge, replace}, nil)
+	mode.SetSelected(merge)
+	summary := widget.NewLabel(fmt.Sprintf("%d profiles with %d saved positions, exported %s.",
+		len(bundle.Profiles), positions, bundle.Exported.Format("2006-01-02 15:04")))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("", summary),
+		widget.NewFormItem("", mode),
+	}
+	dialog.ShowForm("Import configuration", "Import", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		replaced := mode.Selected == replace
+		imported, err := wm.storage.Import(path, replaced)
+		if err != nil {
+			logError("Failed to import the configuration:", err)
+			dialog.ShowError(err, window)
+			return
+		}
+		logInfo("Imported the configuration from", path, "replace:", replaced)
+		if replaced {
+			previous := wm.getSettings()
+			if err := wm.updateSettings(func(s *Settings) { *s = imported.Settings.clone() }); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			window.Close()
+			wm.applySettings(wm.mainWindow, previous)
+		}
+		wm.setupMainWindowContent() // Refresh the UI
+	}, window)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+	"golang.org/x/sys/windows"
+)
+
+/*
+	Control pipe:
+	- Other programs, e.g. a launcher, control the running instance through the named pipe
+	  \\.\pipe\WindowPositioner instead of starting a second instance.
+	- Protocol: JSON lines. The client sends a controlRequest per line and receives a controlResponse
+	  per line. A malformed line is answered with an error, the connection stays open.
+	- Actions:
+	  {"action":"enumerate"}                                                  -> windows
+	  {"action":"move","handle":1234,"x":0,"y":0,"width":800,"height":600}     (or "title" instead of "handle")
+	  {"action":"save","title":"* - Notepad","titlePattern":true,"x":0,"y":0,"width":800,"height":600}
+	                                                                          (no size saves the current position)
+	  {"action":"apply_profile","profile":"Work"}                             -> result
+	  {"action":"show"}                                                       (shows the main window)
+	- Titles match exactly unless "titlePattern" is true.
+	- Each client is served on its own goroutine. Moves hold the operationMutex like a repositioning pass,
+	  the storage and the enumeration are guarded by their own mutexes.
+	- The pipe rejects remote clients. Its default security only lets the same user and administrators
+	  write to it, so other users cannot send requests.
+*/
+
+// controlPipeName is the name of the control pipe
+var controlPipeName = `\\.\pipe\` + strProductName
+
+// controlMaxLine is the maximum length of a request line
+const controlMaxLine = 64 * 1024
+
+// Actions of the control pipe
+const (
+	controlEnumerate    = "enumerate"
+	controlMove         = "move"
+	controlSave         = "save"
+	controlApplyProfile = "apply_profile"
+	controlShow         = "show"
+)
+
+// controlRequest is a request received on the control pipe
+type controlRequest struct {
+	Action  string  `json:"action"`
+	Handle  uintptr `json:"handle,omitempty"` // Window to move, alternatively the title
+	Title   string  `json:"title,omitempty"`  // Title of the window, alternatively the handle
+	X       int     `json:"x"`
+	Y       int     `json:"y"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Profile string  `json:"profile,omitempty"`
+
+	TitlePattern bool `json:"titlePattern,omitempty"` // The title is a glob or regular expression, see title_pattern.go
+}
+
+// controlResponse is the answer to a controlRequest
+type controlResponse struct {
+	OK         bool              `json:"ok"`
+	Error      string            `json:"error,omitempty"`
+	Windows    []WindowInfo      `json:"windows,omitempty"`
+	Identifier string            `json:"identifier,omitempty"` // Identifier of a saved position
+	Result     *RepositionResult `json:"result,omitempty"`
+}
+
+// controlServer accepts clients on the control pipe.
+type controlServer struct {
+	mutex  sync.Mutex
+	cancel context.CancelFunc // Stops the accept loop, nil if not running
+	done   chan struct{}      // Closed when the accept loop exits
+}
+
+// createControlPipe creates an instance of the control pipe.
+// The first instance fails if another process, e.g. a second instance of the application, owns the pipe.
+func createControlPipe(first bool) (windows.Handle, error) {
+	mode := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		mode |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	pipe, err := windows.CreateNamedPipe(windows.StringToUTF16Ptr(controlPipeName), mode,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		windows.PIPE_UNLIMITED_INSTANCES, 4096, 4096, 0, nil)
+	if err != nil {
+		return 0, fmt.Errorf("CreateNamedPipe failed for %s: %v", controlPipeName, err)
+	}
+	return pipe, nil
+}
+
+// Start creates the pipe and accepts clients until ctx is cancelled or Stop is called.
+func (cs *controlServer) Start(ctx context.Context, handler func(controlRequest) controlResponse) error {
+	cs.Stop()
+	pipe, err := createControlPipe(true)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	cs.mutex.Lock()
+	cs.cancel, cs.done = cancel, done
+	cs.mutex.Unlock()
+
+	go cs.acceptLoop(ctx, pipe, handler, done)
+	go func() {
+		defer panicHandler()
+		<-ctx.Done()
+		// ConnectNamedPipe blocks, so it is unblocked by connecting to the pipe ourselves
+		if unblock, err := windows.CreateFile(windows.StringToUTF16Ptr(controlPipeName),
+			windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0); err == nil {
+			windows.CloseHandle(unblock)
+		}
+	}()
+	log(true, "Accepting commands on", controlPipeName)
+	return nil
+}
+
+// Stop stops accepting clients. Connected clients are served until they disconnect.
+func (cs *controlServer) Stop() {
+	cs.mutex.Lock()
+	cancel, done := cs.cancel, cs.done
+	cs.cancel, cs.done = nil, nil
+	cs.mutex.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// acceptLoop waits for a client on the listening pipe instance, serves it on its own goroutine
+// and creates the next instance for the next client.
+func (cs *controlServer) acceptLoop(ctx context.Context, pipe windows.Handle, handler func(controlRequest) controlResponse, done chan struct{}) {
+	defer panicHandler()
+	defer close(done)
+	for {
+		err := windows.ConnectNamedPipe(pipe, nil)
+		if err == windows.ERROR_PIPE_CONNECTED {
+			err = nil
+		}
+		if ctx.Err() != nil {
+			windows.CloseHandle(pipe)
+			log(true, "Stopped accepting commands on", controlPipeName)
+			return
+		}
+		if err != nil {
+			log(true, "Failed to accept a control client:", err)
+			windows.CloseHandle(pipe)
+		} else {
+			go serveControlClient(pipe, handler)
+		}
+		if pipe, err = createControlPipe(false); err != nil {
+			logError("Failed to create the control pipe:", err)
+			recordProblem(problemError, "", "Stopped accepting commands:", err)
+			return
+		}
+	}
+}
+
+// serveControlClient answers the requests of a client until it disconnects.
+func serveControlClient(pipe windows.Handle, handler func(controlRequest) controlResponse) {
+	debug := true
+	defer panicHandler()
+	defer windows.CloseHandle(pipe)
+	defer windows.DisconnectNamedPipe(pipe)
+	defer windows.FlushFileBuffers(pipe) // Let the client read the last response
+
+	scanner := bufio.NewScanner(fileReader{pipe})
+	scanner.Buffer(make([]byte, 4096), controlMaxLine)
+	for scanner.Scan() {
+		response := handleControlLine(scanner.Bytes(), handler)
+		data, err := json.Marshal(response)
+		if err != nil {
+			data, _ = json.Marshal(controlResponse{Error: err.Error()})
+		}
+		var written uint32
+		if err := windows.WriteFile(pipe, append(data, '\n'), &written, nil); err != nil {
+			log(debug, "Control client disconnected:", err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log(debug, "Control client disconnected:", err)
+	}
+}
+
+// handleControlLine parses a request line and runs it. A panic in the handler
+// is answered with an error, so it does not end the connection.
+func handleControlLine(line []byte, handler func(controlRequest) controlResponse) (response controlResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			log(true, "Panic while handling a control request:", r)
+			response = controlResponse{Error: fmt.Sprint("internal error: ", r)}
+		}
+	}()
+	var request controlRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		return controlResponse{Error: "malformed request: " + err.Error()}
+	}
+	return handler(request)
+}
+
+// reloadControlPipe starts or stops the control pipe according to the settings.
+func (wm *WindowManager) reloadControlPipe() error {
+	if !wm.getSettings().ControlPipeEnabled {
+		wm.control.Stop()
+		return nil
+	}
+	return wm.control.Start(wm.ctx, wm.handleControlRequest)
+}
+
+// handleControlRequest runs a request received on the control pipe.
+func (wm *WindowManager) handleControlRequest(request controlRequest) controlResponse {
+	debug := true
+	log(debug, "Control request:", request.Action)
+	fail := func(err error) controlResponse {
+		log(true, "Control request", request.Action, "failed:", err)
+		return controlResponse{Error: err.Error()}
+	}
+
+	switch request.Action {
+	case controlEnumerate:
+		windowList, err := EnumerateWindows()
+		if err != nil {
+			return fail(err)
+		}
+		return controlResponse{OK: true, Windows: windowList}
+
+	case controlMove:
+		window, err := wm.controlWindow(request)
+		if err != nil {
+			return fail(err)
+		}
+		if request.Width <= 0 || request.Height <= 0 {
+			return fail(fmt.Errorf("width and height must be positive"))
+		}
+		target := WindowPosition{X: request.X, Y: request.Y, Width: request.Width, Height: request.Height}
+		opts := target.moveOptions(wm.getSettings())
+		wm.operationMutex.Lock()
+		err = wm.moveWindowAnyElevation(window, target, opts)
+		wm.operationMutex.Unlock()
+		if err != nil {
+			return fail(err)
+		}
+		return controlResponse{OK: true}
+
+	case controlSave:
+		window, err := wm.controlWindow(request)
+		if err != nil {
+			return fail(err)
+		}
+		pos := WindowPosition{X: request.X, Y: request.Y, Width: request.Width, Height: request.Height}
+		if request.Width <= 0 || request.Height <= 0 {
+			current, err := getWindowPosition(window.Handle)
+			if err != nil {
+				return fail(err)
+			}
+			captureWindowState(window.Handle, current)
+			pos = *current
+		}
+		identifier, err := wm.storeWindowPosition(window, pos)
+		if err != nil {
+			return fail(err)
+		}
+		fyne.Do(func() {
+			wm.setupMainWindowContent() // Refresh the UI
+		})
+		return controlResponse{OK: true, Identifier: identifier}
+
+	case controlApplyProfile:
+		if request.Profile == "" {
+			return fail(fmt.Errorf("the profile is missing"))
+		}
+		result, err := wm.applyProfile(request.Profile)
+		if err != nil {
+			return fail(err)
+		}
+		return controlResponse{OK: result.Err == nil && result.Failed == 0, Result: &result}
+
+	case controlShow:
+		fyne.Do(func() {
+			wm.mainWindow.Show()
+			wm.mainWindow.RequestFocus()
+		})
+		return controlResponse{OK: true}
+	}
+	return fail(fmt.Errorf("unknown action '%s'", request.Action))
+}
+
+// controlWindow returns the window a request refers to, by handle or by title.
+func (wm *WindowManager) controlWindow(request controlRequest) (WindowInfo, error) {
+	if request.Handle != 0 {
+		hwnd := syscall.Handle(request.Handle)
+		if !isValidWindow(hwnd) {
+			return WindowInfo{}, fmt.Errorf("no window with handle %d", request.Handle)
+		}
+		window := getWindowInfo(hwnd)
+		if isShellWindowClass(window.ClassName) {
+			return WindowInfo{}, fmt.Errorf("shell windows cannot be controlled")
+		}
+		return window, nil
+	}
+	if request.Title == "" {
+		return WindowInfo{}, fmt.Errorf("the handle or the title of the window is missing")
+	}
+	windowList, err := EnumerateWindows()
+	if err != nil {
+		return WindowInfo{}, err
+	}
+	window, ok := findWindowByTitle(request.Title, request.TitlePattern, windowList)
+	if !ok {
+		return WindowInfo{}, fmt.Errorf("no window matches the title '%s'", request.Title)
+	}
+	return window, nil
+}
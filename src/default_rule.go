@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+/*
+	Default rule:
+	- An opt-in catch-all that centers windows matching no saved position at a standard size
+	  on the monitor they appeared on.
+	- It only applies to windows that appeared in this session, within the new window period,
+	  and only once per window, so it never fights the user moving the window afterwards.
+	- Dialogs, tool windows and windows without a caption are left alone.
+*/
+
+// defaultRuleIdentifier identifies the default rule in logs and conflicts, it is never saved
+const defaultRuleIdentifier = "(default rule)"
+
+// isDefaultRuleCandidate checks if a window is a main application window the default rule can apply to.
+func isDefaultRuleCandidate(window WindowInfo) bool {
+	if window.Style&WS_CAPTION != WS_CAPTION || window.ExStyle&WS_EX_TOOLWINDOW != 0 {
+		return false
+	}
+	if getWindowOwner(window.Handle) != 0 {
+		return false // Dialogs are owned by their main window
+	}
+	if isWindowMaximized(window.Handle) || isWindowMinimized(window.Handle) {
+		return false
+	}
+	ownExecutable, _ := os.Executable()
+	return !strings.EqualFold(window.Executable, ownExecutable)
+}
+
+// defaultRuleMatch returns the default rule match for a window without a saved position,
+// if the default rule is enabled and was not applied to the window yet.
+func (wm *WindowManager) defaultRuleMatch(window WindowInfo, settings Settings, monitors []Monitor, now time.Time, grace time.Duration) (repositionMatch, bool) {
+	debug := false
+	if !settings.DefaultRuleEnabled || len(monitors) == 0 {
+		return repositionMatch{}, false
+	}
+	if wm.tracker.age(window.Handle, now) > grace || !isDefaultRuleCandidate(window) {
+		return repositionMatch{}, false
+	}
+	if !wm.tracker.claimDefaultRule(window.Handle) {
+		return repositionMatch{}, false
+	}
+	monitor := monitors[nearestMonitor(window.WindowRect, monitors)]
+	target := centerOnWorkArea(settings.DefaultRuleWidth, settings.DefaultRuleHeight, monitor.WorkArea)
+	log(debug, "Applying the default rule to:", redactTitle(window.Title))
+	return repositionMatch{Window: window, Identifier: defaultRuleIdentifier, Position: target, Target: target}, true
+}
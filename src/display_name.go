@@ -0,0 +1,91 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Display names:
+	- Identifiers like "Title|ClassName|Executable|0x..|0x.." are hard to read, so the saved positions
+	  are listed by a display name: the name the user gave the rule, else the title of the identifier,
+	  else its executable or class name.
+	- Renaming a rule only changes its DisplayName, the identifier stays the matching key.
+*/
+
+// displayName returns the name a saved position is listed with.
+func displayName(identifier string, pos WindowPosition) string {
+	if pos.DisplayName != "" {
+		return pos.DisplayName
+	}
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return identifier
+	}
+	for _, part := range []string{parts[0], filepath.Base(parts[2]), parts[1]} {
+		if part != "" && part != identifierWildcard && part != "." {
+			return part
+		}
+	}
+	return identifier
+}
+
+// sortedByDisplayName returns the identifiers of the positions ordered by their display names.
+func sortedByDisplayName(positions map[string]WindowPosition) []string {
+	identifiers := make([]string, 0, len(positions))
+	for identifier := range positions {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Slice(identifiers, func(i, j int) bool {
+		a := strings.ToLower(displayName(identifiers[i], positions[identifiers[i]]))
+		b := strings.ToLower(displayName(identifiers[j], positions[identifiers[j]]))
+		if a != b {
+			return a < b
+		}
+		return identifiers[i] < identifiers[j]
+	})
+	return identifiers
+}
+
+// showRenameRuleDialog asks for the display name of a saved position.
+// An empty name lists the rule by its title again.
+func (wm *WindowManager) showRenameRuleDialog(identifier string) {
+	pos, err := wm.storage.LoadPosition(identifier)
+	if err != nil {
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(pos.DisplayName)
+	nameEntry.SetPlaceHolder(displayName(identifier, WindowPosition{}))
+	identifierLabel := widget.NewLabel(identifier)
+	identifierLabel.Wrapping = fyne.TextWrapBreak
+	items := []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+		widget.NewFormItem("Identifier", identifierLabel),
+	}
+	form := dialog.NewForm("Rename rule", "Rename", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		name := strings.TrimSpace(nameEntry.Text)
+		err := wm.storage.UpdatePositions([]string{identifier}, func(_ string, pos *WindowPosition) {
+			pos.DisplayName = name
+		})
+		if err != nil {
+			log(true, "Failed to rename rule:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Renamed", redactIdentifier(identifier), "to", name)
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+	form.Resize(fyne.NewSize(500, 0))
+	form.Show()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"syscall"
+)
+
+/*
+	DPI awareness:
+	- A DPI unaware process gets virtualized coordinates on scaled monitors, so positions would be
+	  saved and restored off by the scaling factor. The process is therefore made per-monitor DPI
+	  aware (V2) at startup and works in physical pixels on every monitor.
+	- Saved positions record the DPI of their monitor. If the monitor has another DPI when the
+	  position is restored, e.g. because the scaling was changed, the size is scaled accordingly.
+*/
+
+// dpiContextPerMonitorV2 is DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2, which is defined as (HANDLE)-4
+const dpiContextPerMonitorV2 = ^uintptr(3)
+
+// enableDpiAwareness makes the process per-monitor DPI aware.
+// It must be called before any window is created. It fails harmlessly if the awareness was
+// already set, e.g. by the application manifest.
+func enableDpiAwareness() {
+	debug := false
+	if err := procSetProcessDpiAwarenessContext.Find(); err != nil {
+		log(debug, "SetProcessDpiAwarenessContext is not available:", err)
+		return
+	}
+	ret, _, err := procSetProcessDpiAwarenessContext.Call(dpiContextPerMonitorV2)
+	if ret == 0 {
+		log(debug, "SetProcessDpiAwarenessContext failed:", err)
+		return
+	}
+	log(debug, "Process is per-monitor DPI aware.")
+}
+
+// getDpiForWindow returns the DPI of the monitor the window is on.
+// It falls back to the DPI of the monitor containing the window rectangle on older Windows versions.
+func getDpiForWindow(hwnd syscall.Handle) uint32 {
+	if err := procGetDpiForWindow.Find(); err == nil {
+		if dpi, _, _ := procGetDpiForWindow.Call(uintptr(hwnd)); dpi != 0 {
+			return uint32(dpi)
+		}
+	}
+	pos, err := getWindowPosition(hwnd)
+	if err != nil {
+		return USER_DEFAULT_SCREEN_DPI
+	}
+	return getDpiForRect(pos.Rect())
+}
+
+// scaledToCurrentDpi scales a physical size saved at another DPI to the DPI of the monitor
+// the position is on now. The top-left corner stays in place.
+// Positions saved without DPI are returned unchanged.
+func (pos WindowPosition) scaledToCurrentDpi() WindowPosition {
+	if pos.Dpi == 0 || pos.Logical {
+		return pos
+	}
+	dpi := getDpiForRect(pos.Rect())
+	if dpi == pos.Dpi {
+		return pos
+	}
+	pos.Width = scaleForDpi(pos.Width, pos.Dpi, dpi)
+	pos.Height = scaleForDpi(pos.Height, pos.Dpi, dpi)
+	pos.Dpi = dpi
+	return pos
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+/*
+	Elevated move helper:
+	- Windows of elevated processes cannot be moved by a non-elevated process (UIPI). Instead of running
+	  the whole GUI elevated, the same executable is started once as a small elevated helper via UAC,
+	  which only moves windows on request.
+	- Lifecycle: the helper is started on demand, for the first elevated window to move. It is started
+	  in the background, so a pass does not wait for the UAC prompt; moves fail until it is connected
+	  and are retried by the next cycle. It exits when the pipe is closed, which happens when the
+	  application exits or its context is cancelled.
+	- Channel: the application creates a named pipe with a random name that only accepts one local client,
+	  and passes the name and a random token on the helper's command line.
+	  The application only accepts a client that is an instance of its own executable and sends
+	  the token first. A non-elevated application can often not query the elevation of the client,
+	  so it only rejects clients known to be not elevated. The helper only talks to a server that is an instance of its own executable.
+	- Protocol: JSON lines. After the token line, the application sends a moveHelperRequest per line
+	  and the helper answers each with a moveHelperResponse.
+*/
+
+// moveHelperFlag starts the executable as elevated move helper: <exe> --move-helper <pipe> <token>
+const moveHelperFlag = "--move-helper"
+
+// moveHelperConnectTimeout is how long the user has to confirm the UAC prompt
+const moveHelperConnectTimeout = 60 * time.Second
+
+// moveHelperRequest asks the helper to move a window
+type moveHelperRequest struct {
+	Handle     uintptr  `json:"handle"`
+	X          int      `json:"x"`
+	Y          int      `json:"y"`
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
+	TimeoutMs  int      `json:"timeoutMs,omitempty"`
+	Strategies []string `json:"strategies,omitempty"`
+}
+
+// moveHelperResponse is the result of a moveHelperRequest
+type moveHelperResponse struct {
+	Error    string `json:"error,omitempty"`    // Empty if the window was moved
+	Strategy string `json:"strategy,omitempty"` // Strategy that moved the window, empty if it was already in place
+}
+
+// elevatedHelper is the connection of the application to the elevated move helper.
+type elevatedHelper struct {
+	mutex    sync.Mutex
+	pipe     windows.Handle // 0 if the helper is not running
+	reader   *bufio.Reader
+	starting bool  // The helper is being started by startInBackground
+	failed   bool  // Starting the helper failed or was declined, it is not started again this session
+	elevated *bool // The application itself runs elevated, nil until checked
+}
+
+// selfElevated reports whether the application itself runs elevated, so no helper is needed.
+// It must be called with the mutex held.
+func (h *elevatedHelper) selfElevated() bool {
+	if h.elevated == nil {
+		elevated, err := isProcessElevated(uint32(os.Getpid()))
+		if err != nil {
+			log(true, "Failed to check own elevation:", err)
+		}
+		h.elevated = &elevated
+	}
+	return *h.elevated
+}
+
+// errHelperStarting is returned while the helper waits for the UAC prompt, the move is retried by the next cycle
+var errHelperStarting = errors.New("the elevated move helper is starting")
+
+// moveWindow moves a window of an elevated process through the helper.
+// If the helper is not running, it is started in the background and errHelperStarting is returned.
+func (h *elevatedHelper) moveWindow(ctx context.Context, hwnd syscall.Handle, target WindowPosition, opts moveOptions) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.pipe == 0 {
+		if h.failed {
+			return fmt.Errorf("the elevated move helper is not available")
+		}
+		if !h.starting {
+			h.starting = true
+			go h.startInBackground(ctx)
+		}
+		return errHelperStarting
+	}
+
+	request := moveHelperRequest{
+		Handle: uintptr(hwnd), X: target.X, Y: target.Y, Width: target.Width, Height: target.Height,
+		TimeoutMs: int(opts.Timeout / time.Millisecond),
+	}
+	for _, strategy := range opts.allowedStrategies() {
+		request.Strategies = append(request.Strategies, strategy.Name)
+	}
+	var response moveHelperResponse
+	if err := h.exchange(request, &response); err != nil {
+		h.close()
+		return fmt.Errorf("the elevated move helper stopped: %v", err)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s", response.Error)
+	}
+	if response.Strategy != "" && opts.Report != nil {
+		opts.Report(response.Strategy, true)
+	}
+	return nil
+}
+
+// exchange sends a request line and reads the response line.
+// It must be called with the mutex held.
+func (h *elevatedHelper) exchange(request any, response any) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	var written uint32
+	if err := windows.WriteFile(h.pipe, append(data, '\n'), &written, nil); err != nil {
+		return err
+	}
+	line, err := h.reader.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(line, response)
+}
+
+// startInBackground starts the helper and connects it. The mutex is not held while waiting
+// for the UAC prompt, so moves and passes are not blocked by it.
+func (h *elevatedHelper) startInBackground(ctx context.Context) {
+	defer panicHandler()
+	pipe, reader, err := launchMoveHelper()
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.starting = false
+	if err != nil {
+		h.failed = true
+		logError("Failed to start the elevated move helper:", err)
+		recordProblem(problemError, "", "Failed to start the elevated move helper:", err)
+		return
+	}
+	h.pipe, h.reader = pipe, reader
+	log(true, "Elevated move helper connected.")
+
+	// Closing the pipe makes the helper exit
+	go func() {
+		defer panicHandler()
+		<-ctx.Done()
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		h.close()
+	}()
+}
+
+// launchMoveHelper creates the pipe, launches the helper via UAC and waits until it connected and authenticated.
+func launchMoveHelper() (windows.Handle, *bufio.Reader, error) {
+	debug := true
+	pipeName, err := randomHex(16)
+	if err != nil {
+		return 0, nil, err
+	}
+	pipeName = `\\.\pipe\` + strProductName + `-` + pipeName
+	token, err := randomHex(32)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pipe, err := windows.CreateNamedPipe(windows.StringToUTF16Ptr(pipeName),
+		windows.PIPE_ACCESS_DUPLEX|windows.FILE_FLAG_FIRST_PIPE_INSTANCE,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_REJECT_REMOTE_CLIENTS,
+		1, 4096, 4096, 0, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("CreateNamedPipe failed: %v", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		windows.CloseHandle(pipe)
+		return 0, nil, err
+	}
+	log(debug, "Starting the elevated move helper.")
+	args := strings.Join([]string{moveHelperFlag, pipeName, token}, " ")
+	err = windows.ShellExecute(0, windows.StringToUTF16Ptr("runas"), windows.StringToUTF16Ptr(executable),
+		windows.StringToUTF16Ptr(args), nil, windows.SW_HIDE)
+	if err != nil {
+		windows.CloseHandle(pipe)
+		return 0, nil, fmt.Errorf("the helper was not started (UAC declined?): %v", err)
+	}
+
+	// ConnectNamedPipe blocks, so it is unblocked by connecting to the pipe ourselves on timeout
+	connected := make(chan error, 1)
+	go func() {
+		defer panicHandler()
+		err := windows.ConnectNamedPipe(pipe, nil)
+		if err == windows.ERROR_PIPE_CONNECTED {
+			err = nil
+		}
+		connected <- err
+	}()
+	select {
+	case err = <-connected:
+	case <-time.After(moveHelperConnectTimeout):
+		if unblock, openErr := windows.CreateFile(windows.StringToUTF16Ptr(pipeName),
+			windows.GENERIC_READ, 0, nil, windows.OPEN_EXISTING, 0, 0); openErr == nil {
+			windows.CloseHandle(unblock)
+		}
+		<-connected
+		err = fmt.Errorf("the helper did not connect within %v", moveHelperConnectTimeout)
+	}
+	if err == nil {
+		err = verifyPipePeer(pipe, true, executable)
+	}
+	if err != nil {
+		windows.CloseHandle(pipe)
+		return 0, nil, err
+	}
+
+	reader := bufio.NewReader(fileReader{pipe})
+	line, err := reader.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != token {
+		windows.CloseHandle(pipe)
+		return 0, nil, fmt.Errorf("the helper did not authenticate")
+	}
+	return pipe, reader, nil
+}
+
+// close closes the pipe, which makes the helper exit.
+// It must be called with the mutex held.
+func (h *elevatedHelper) close() {
+	if h.pipe != 0 {
+		windows.CloseHandle(h.pipe)
+		h.pipe = 0
+		h.reader = nil
+	}
+}
+
+// verifyPipePeer checks that the other end of a pipe is an instance of the given executable.
+// If client is true, the client is checked and must not be known to run unelevated, otherwise the server is checked.
+func verifyPipePeer(pipe windows.Handle, client bool, executable string) error {
+	var pid uint32
+	var err error
+	if client {
+		err = windows.GetNamedPipeClientProcessId(pipe, &pid)
+	} else {
+		err = windows.GetNamedPipeServerProcessId(pipe, &pid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to identify the pipe peer: %v", err)
+	}
+	path, err := getProcessExecutablePath(pid)
+	if err != nil {
+		return fmt.Errorf("failed to identify the pipe peer: %v", err)
+	}
+	if !strings.EqualFold(path, executable) {
+		return fmt.Errorf("the pipe peer %s is not %s", path, executable)
+	}
+	// Non-elevated processes can often not query elevated processes, so a failed check counts as elevated
+	if client {
+		if elevated, err := isProcessElevated(pid); err == nil && !elevated {
+			return fmt.Errorf("the pipe peer is not elevated")
+		}
+	}
+	return nil
+}
+
+// fileReader reads from a pipe handle
+type fileReader struct {
+	handle windows.Handle
+}
+
+// Read reads from the pipe. A closed pipe is reported as end of file.
+func (r fileReader) Read(p []byte) (int, error) {
+	var read uint32
+	err := windows.ReadFile(r.handle, p, &read, nil)
+	if err == windows.ERROR_BROKEN_PIPE || err == nil && read == 0 {
+		return 0, io.EOF
+	}
+	return int(read), err
+}
+
+// randomHex returns n random bytes as hex string.
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// runMoveHelper runs the executable as elevated move helper until the application closes the pipe.
+// It returns the exit code.
+func runMoveHelper(pipeName, token string) int {
+	defer panicHandler()
+	executable, err := os.Executable()
+	if err != nil {
+		return 1
+	}
+	pipe, err := windows.CreateFile(windows.StringToUTF16Ptr(pipeName),
+		windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return 1
+	}
+	defer windows.CloseHandle(pipe)
+	if err := verifyPipePeer(pipe, false, executable); err != nil {
+		return 1
+	}
+
+	write := func(data []byte) error {
+		var written uint32
+		return windows.WriteFile(pipe, append(data, '\n'), &written, nil)
+	}
+	if err := write([]byte(token)); err != nil {
+		return 1
+	}
+
+	reader := bufio.NewReader(fileReader{pipe})
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return 0 // The application closed the pipe
+		}
+		var request moveHelperRequest
+		var response moveHelperResponse
+		if err := json.Unmarshal(line, &request); err != nil {
+			response.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			opts := moveOptions{Timeout: time.Duration(request.TimeoutMs) * time.Millisecond, Strategies: request.Strategies}
+			opts.Report = func(strategy string, succeeded bool) {
+				if succeeded {
+					response.Strategy = strategy
+				}
+			}
+			hwnd := syscall.Handle(request.Handle)
+			if err := moveWindowWithOptions(hwnd, request.X, request.Y, request.Width, request.Height, opts); err != nil {
+				response.Error = err.Error()
+			}
+		}
+		data, _ := json.Marshal(response)
+		if err := write(data); err != nil {
+			return 0
+		}
+	}
+}
+
+// moveWindowAnyElevation moves a window. If that fails, the window belongs to an elevated process and
+// the application itself is not elevated, the window is moved through the elevated move helper if it is enabled.
+// Windows known to belong to an elevated process skip the move strategies, which cannot move them anyway.
+func (wm *WindowManager) moveWindowAnyElevation(window WindowInfo, target WindowPosition, opts moveOptions) error {
+	wm.helper.mutex.Lock()
+	selfElevated := wm.helper.selfElevated()
+	wm.helper.mutex.Unlock()
+	useHelper := wm.getSettings().UseElevatedHelper
+	if !selfElevated {
+		if elevated, checkErr := isProcessElevated(window.ProcessID); checkErr == nil && elevated {
+			if current, err := getWindowPosition(window.Handle); err == nil && withinPositionTolerance(current.Rect(), target.Rect()) {
+				return nil // Already in place, nothing to move
+			}
+			if !useHelper {
+				return fmt.Errorf("%w: %s", errElevatedTarget, redactTitle(window.Title))
+			}
+			log(true, "Moving window through the elevated move helper:", redactTitle(window.Title))
+			return wm.helper.moveWindow(wm.ctx, window.Handle, target, opts)
+		}
+	}
+
+	err := moveWindowWithOptions(window.Handle, target.X, target.Y, target.Width, target.Height, opts)
+	if err == nil || !useHelper {
+		return err
+	}
+	// Non-elevated processes can often not query elevated processes, so a failed check counts as elevated
+	if elevated, checkErr := isProcessElevated(window.ProcessID); selfElevated || checkErr == nil && !elevated {
+		return err
+	}
+	log(true, "Moving window through the elevated move helper:", redactTitle(window.Title))
+	return wm.helper.moveWindow(wm.ctx, window.Handle, target, opts)
+}
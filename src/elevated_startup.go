@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/sys/windows"
+)
+
+/*
+	Always run elevated:
+	- Windows of elevated processes can only be moved reliably by an elevated application.
+	  With "Always run elevated", a normal start relaunches the application as administrator
+	  via UAC, see RelaunchElevated. If the UAC prompt is declined, it keeps running normally.
+	- Starting with Windows then uses a scheduled task that runs at logon with the highest
+	  privileges instead of the Run registry key, which cannot start programs elevated without
+	  a UAC prompt at every logon.
+	- Creating and deleting the task needs administrator rights, so schtasks is started through
+	  UAC unless the application already runs elevated.
+	- Switching the setting moves an enabled startup registration between the Run key and the task.
+	- "Start with Windows (elevated)" in the Positioning settings shows and changes the task directly,
+	  it replaces the Run key registration while it is enabled.
+	- A Run key registration of another path, e.g. after the executable was moved, is shown in the
+	  main window with a button that registers the running executable instead.
+*/
+
+// elevatedTaskName is the name of the scheduled task that starts the application elevated at logon
+var elevatedTaskName = strProductName
+
+// elevatedCommandTimeout is how long the user has to confirm the UAC prompt of schtasks
+const elevatedCommandTimeout = 60 * time.Second
+
+// shellExecuteInfo is the SHELLEXECUTEINFOW structure of ShellExecuteEx
+type shellExecuteInfo struct {
+	cbSize       uint32
+	fMask        uint32
+	hwnd         uintptr
+	lpVerb       *uint16
+	lpFile       *uint16
+	lpParameters *uint16
+	lpDirectory  *uint16
+	nShow        int32
+	hInstApp     uintptr
+	lpIDList     uintptr
+	lpClass      *uint16
+	hkeyClass    uintptr
+	dwHotKey     uint32
+	hIcon        uintptr
+	hProcess     windows.Handle
+}
+
+// isSelfElevated reports whether the application runs elevated.
+func isSelfElevated() bool {
+	elevated, err := isProcessElevated(uint32(os.Getpid()))
+	return err == nil && elevated
+}
+
+// relaunchIfAlwaysElevated restarts the application as administrator if the settings ask for it.
+// It returns true if the elevated instance was started and this one must exit.
+func relaunchIfAlwaysElevated() bool {
+	storage, _ := NewPositionStorage() // Reading works without a writable directory
+	settings, err := storage.LoadSettings()
+	if err != nil || !settings.AlwaysRunElevated || isSelfElevated() {
+		return false
+	}
+	if err := RelaunchElevated(); err != nil {
+		logWarn("Always run elevated:", err, "- running without elevation.")
+		return false
+	}
+	return true
+}
+
+// runElevatedCommand runs a program as administrator and returns its exit code.
+// If the application is elevated, the program is started directly, else through UAC.
+func runElevatedCommand(program, params string) (uint32, error) {
+	if isSelfElevated() {
+		cmd := exec.Command(program)
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true, CmdLine: program + " " + params}
+		err := cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return uint32(exitErr.ExitCode()), nil
+		}
+		return 0, err
+	}
+
+	info := shellExecuteInfo{
+		fMask:        SEE_MASK_NOCLOSEPROCESS | SEE_MASK_NOASYNC,
+		lpVerb:       windows.StringToUTF16Ptr("runas"),
+		lpFile:       windows.StringToUTF16Ptr(program),
+		lpParameters: windows.StringToUTF16Ptr(params),
+		nShow:        windows.SW_HIDE,
+	}
+	info.cbSize = uint32(unsafe.Sizeof(info))
+	if ret, _, err := procShellExecuteEx.Call(uintptr(unsafe.Pointer(&info))); ret == 0 {
+		return 0, fmt.Errorf("failed to start %s as administrator (UAC declined?): %v", program, err)
+	}
+	if info.hProcess == 0 {
+		return 0, fmt.Errorf("no process handle for %s", program)
+	}
+	defer windows.CloseHandle(info.hProcess)
+	event, err := windows.WaitForSingleObject(info.hProcess, uint32(elevatedCommandTimeout.Milliseconds()))
+	if err != nil {
+		return 0, err
+	}
+	if event != windows.WAIT_OBJECT_0 {
+		return 0, fmt.Errorf("%s did not finish within %v", program, elevatedCommandTimeout)
+	}
+	var code uint32
+	if err := windows.GetExitCodeProcess(info.hProcess, &code); err != nil {
+		return 0, err
+	}
+	return code, nil
+}
+
+// EnableStartupElevated creates the scheduled task that starts the application at logon with the
+// highest privileges, so it runs elevated without a UAC prompt at every logon.
+func EnableStartupElevated() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	params := fmt.Sprintf(`/Create /F /TN %s /SC ONLOGON /RL HIGHEST /TR "\"%s\""`,
+		strconv.Quote(elevatedTaskName), executable)
+	code, err := runElevatedCommand("schtasks.exe", params)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("schtasks failed to create the startup task (exit code %d)", code)
+	}
+	return nil
+}
+
+// DisableStartupElevated deletes the scheduled task, if it exists.
+func DisableStartupElevated() error {
+	if !IsStartupElevatedEnabled() {
+		return nil
+	}
+	code, err := runElevatedCommand("schtasks.exe", "/Delete /F /TN "+strconv.Quote(elevatedTaskName))
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("schtasks failed to delete the startup task (exit code %d)", code)
+	}
+	return nil
+}
+
+// IsStartupElevatedEnabled checks if the scheduled task exists.
+func IsStartupElevatedEnabled() bool {
+	cmd := exec.Command("schtasks.exe", "/Query", "/TN", elevatedTaskName)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd.Run() == nil
+}
+
+// setStartup registers the application to start with Windows, with the scheduled task if it
+// should always run elevated, else with the Run key, and removes the other registration.
+// If enabled is false, both registrations are removed.
+func setStartup(enabled, elevated bool) error {
+	if enabled && elevated {
+		return setStartupElevated(true)
+	}
+	if enabled {
+		if err := EnableStartup(); err != nil {
+			return err
+		}
+	} else if IsStartupEnabled() != StartupDisabled {
+		if err := DisableStartup(); err != nil {
+			return err
+		}
+	}
+	return DisableStartupElevated()
+}
+
+// startsWithWindows checks if the application starts with Windows, by the Run key or,
+// if it should always run elevated, by the task.
+func startsWithWindows(elevated bool) bool {
+	return IsStartupEnabled() != StartupDisabled || elevated && IsStartupElevatedEnabled()
+}
+
+// setStartupElevated creates or deletes the scheduled task. The Run key registration is removed
+// when the task is created, so the application does not start twice.
+func setStartupElevated(enabled bool) error {
+	if !enabled {
+		return DisableStartupElevated()
+	}
+	if err := EnableStartupElevated(); err != nil {
+		return err
+	}
+	if IsStartupEnabled() != StartupDisabled {
+		return DisableStartup()
+	}
+	return nil
+}
+
+// startupRow shows the startup check, with a button to update the registered path
+// if it does not point to the running executable anymore, e.g. after moving the executable.
+func (wm *WindowManager) startupRow(startupCheck *widget.Check) fyne.CanvasObject {
+	if IsStartupEnabled() != StartupStale {
+		return startupCheck
+	}
+	startupCheck.SetText("Start with Windows (registered for another path)")
+	updateBtn := widget.NewButtonWithIcon("Update path", theme.ViewRefreshIcon(), safeCallback(func() {
+		if err := EnableStartup(); err != nil {
+			logWarn("Failed to update the startup path:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Updated the startup path to the running executable.")
+		wm.setupMainWindowContent()
+	}))
+	return container.NewHBox(startupCheck, updateBtn)
+}
+
+// moveStartupRegistration switches an enabled startup registration between the Run key and
+// the scheduled task after "Always run elevated" changed. Problems are shown on the window.
+func (wm *WindowManager) moveStartupRegistration(window fyne.Window, elevated bool) {
+	go func() {
+		defer panicHandler()
+		if !startsWithWindows(!elevated) {
+			return
+		}
+		if err := setStartup(true, elevated); err != nil {
+			logWarn("Failed to change the startup registration:", err)
+			fyne.Do(func() {
+				dialog.ShowError(err, window)
+			})
+		}
+	}()
+}
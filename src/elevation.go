@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"golang.org/x/sys/windows"
+)
+
+// isProcessElevated checks if a process runs elevated (as administrator) by querying its token.
+func isProcessElevated(pid uint32) (bool, error) {
+	process, err := openProcess(pid)
+	if err != nil {
+		return false, err
+	}
+	defer closeHandle(process)
+
+	var token syscall.Handle
+	ret, _, err := procOpenProcessToken.Call(uintptr(process), TOKEN_QUERY, uintptr(unsafe.Pointer(&token)))
+	if ret == 0 {
+		return false, fmt.Errorf("OpenProcessToken failed for PID %d: %v", pid, err)
+	}
+	defer closeHandle(token)
+
+	var elevation uint32 // TOKEN_ELEVATION.TokenIsElevated
+	var size uint32
+	ret, _, err = procGetTokenInformation.Call(uintptr(token), TokenElevation,
+		uintptr(unsafe.Pointer(&elevation)), unsafe.Sizeof(elevation), uintptr(unsafe.Pointer(&size)))
+	if ret == 0 {
+		return false, fmt.Errorf("GetTokenInformation failed for PID %d: %v", pid, err)
+	}
+	return elevation != 0, nil
+}
+
+// needsMoveConfirmation checks if moving the window of a match must be confirmed by the user first.
+// This is the case for windows of elevated processes when the global setting or the rule asks for it.
+func needsMoveConfirmation(match repositionMatch, settings Settings) bool {
+	if !settings.ConfirmElevatedMoves && !match.Position.ConfirmIfElevated {
+		return false
+	}
+	elevated, err := isProcessElevated(match.Window.ProcessID)
+	if err != nil {
+		// Elevated processes cannot be queried from a non-elevated process, so ask to be safe
+		log(false, "Failed to check elevation of", redactIdentifier(match.Identifier)+":", err)
+		return true
+	}
+	return elevated
+}
+
+// moveDecision returns the user's decision about moving a window of an elevated process.
+// The decision is remembered per window for the session, so the user is asked only once.
+func (wm *WindowManager) moveDecision(hwnd syscall.Handle) (decided, allowed bool) {
+	wm.moveDecisionsMutex.Lock()
+	defer wm.moveDecisionsMutex.Unlock()
+	allowed, decided = wm.moveDecisions[hwnd]
+	return decided, allowed
+}
+
+// setMoveDecision remembers the user's decision about moving a window.
+func (wm *WindowManager) setMoveDecision(hwnd syscall.Handle, allowed bool) {
+	wm.moveDecisionsMutex.Lock()
+	defer wm.moveDecisionsMutex.Unlock()
+	if wm.moveDecisions == nil {
+		wm.moveDecisions = make(map[syscall.Handle]bool)
+	}
+	wm.moveDecisions[hwnd] = allowed
+}
+
+// pruneMoveDecisions forgets the decisions about windows that were closed, so a later window
+// that gets the same handle from Windows does not inherit the decision.
+func (wm *WindowManager) pruneMoveDecisions() {
+	wm.moveDecisionsMutex.Lock()
+	defer wm.moveDecisionsMutex.Unlock()
+	for hwnd := range wm.moveDecisions {
+		if !isValidWindow(hwnd) {
+			delete(wm.moveDecisions, hwnd)
+		}
+	}
+}
+
+// confirmElevatedMove asks the user whether a window of an elevated process may be moved
+// and moves it if confirmed. Until the user answers, the window counts as declined,
+// so the monitoring service does not ask again.
+func (wm *WindowManager) confirmElevatedMove(match repositionMatch, opts moveOptions) {
+	hwnd := match.Window.Handle
+	wm.setMoveDecision(hwnd, false)
+	if wm.mainWindow == nil {
+		log(true, "Cannot ask to move elevated window in command line mode:", redactIdentifier(match.Identifier))
+		return
+	}
+
+	message := fmt.Sprintf("The window '%s' belongs to an elevated (administrator) process.\n"+
+		"Move it to its saved position?", match.Window.Title)
+	fyne.Do(func() {
+		wm.mainWindow.Show()
+		dialog.ShowConfirm("Move elevated window", message, func(confirmed bool) {
+			defer panicHandler()
+			wm.setMoveDecision(hwnd, confirmed)
+			if !confirmed {
+				log(true, "Move of elevated window declined:", redactIdentifier(match.Identifier))
+				return
+			}
+			go func() {
+				defer panicHandler()
+				wm.operationMutex.Lock()
+				defer wm.operationMutex.Unlock()
+				if err := wm.moveWindowAnyElevation(match.Window, match.Target, opts); err != nil {
+					logError("Failed to move elevated window", redactIdentifier(match.Identifier)+":", err)
+					recordProblem(problemError, redactIdentifier(match.Identifier), "Failed to move elevated window:", err)
+				}
+			}()
+		}, wm.mainWindow)
+	})
+}
+
+// errElevatedTarget is returned for windows of elevated processes, which a non-elevated
+// application cannot move without the elevated move helper
+var errElevatedTarget = errors.New("the window belongs to an elevated (administrator) process")
+
+// restartAfterFlag starts the executable after a running instance exited: <exe> --restart-after <pid>
+const restartAfterFlag = "--restart-after"
+
+// restartWaitTimeout is how long a restarted instance waits for the previous one to exit
+const restartWaitTimeout = 15 * time.Second
+
+// offerElevatedRestart asks the user once per session to restart the application as administrator,
+// so windows of elevated processes can be moved.
+func (wm *WindowManager) offerElevatedRestart(window WindowInfo) {
+	if wm.mainWindow == nil || !firstInSession("elevated-restart") {
+		return
+	}
+	message := fmt.Sprintf("The window '%s' belongs to an elevated (administrator) process,\n"+
+		"which %s can only move when it runs as administrator as well.\n"+
+		"Alternatively enable the elevated helper in the Positioning settings.", window.Title, strProductName)
+	fyne.Do(func() {
+		wm.mainWindow.Show()
+		dialog.ShowConfirm("Elevated window", message+"\n\nRestart as administrator now?", func(confirmed bool) {
+			defer panicHandler()
+			if confirmed {
+				wm.restartElevated()
+			}
+		}, wm.mainWindow)
+	})
+}
+
+// RelaunchElevated starts the application again as administrator via UAC. The new instance
+// waits until this one exited, so it takes over the single instance mutex. The caller must exit.
+func RelaunchElevated() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := restartAfterFlag + " " + strconv.Itoa(os.Getpid())
+	err = windows.ShellExecute(0, windows.StringToUTF16Ptr("runas"), windows.StringToUTF16Ptr(executable),
+		windows.StringToUTF16Ptr(args), nil, windows.SW_SHOWNORMAL)
+	if err != nil {
+		return fmt.Errorf("failed to restart as administrator (UAC declined?): %v", err)
+	}
+	return nil
+}
+
+// restartElevated restarts the application as administrator and quits this instance.
+func (wm *WindowManager) restartElevated() {
+	if err := RelaunchElevated(); err != nil {
+		logWarn(err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	log(true, "Restarting as administrator.")
+	wm.app.Quit()
+}
+
+// waitForPreviousInstance waits until the process that restarted the application exited.
+func waitForPreviousInstance(pid string) {
+	id, err := strconv.ParseUint(pid, 10, 32)
+	if err != nil {
+		return
+	}
+	process, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(id))
+	if err != nil {
+		return // Already exited
+	}
+	defer windows.CloseHandle(process)
+	windows.WaitForSingleObject(process, uint32(restartWaitTimeout.Milliseconds()))
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+/*
+	Exclusions:
+	- Windows of excluded executables or window classes are never touched. They are left out of
+	  the window list and skipped by the repositioning, so apps that position their windows
+	  themselves don't fight with the monitoring service.
+	- Executables match on the file name without the directory, class names on the whole name,
+	  both ignoring case.
+	- Windows are excluded with "Ignore this app" in the window list or in the Matching settings.
+*/
+
+// isExcludedWindow checks if a window belongs to an excluded executable or window class.
+func isExcludedWindow(window WindowInfo, settings Settings) bool {
+	executable := filepath.Base(window.Executable)
+	for _, excluded := range settings.ExcludedExecutables {
+		if strings.EqualFold(excluded, executable) {
+			return true
+		}
+	}
+	for _, excluded := range settings.ExcludedClasses {
+		if strings.EqualFold(excluded, window.ClassName) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutExcluded returns the windows that are not excluded by the settings.
+func withoutExcluded(windows []WindowInfo, settings Settings) []WindowInfo {
+	if len(settings.ExcludedExecutables) == 0 && len(settings.ExcludedClasses) == 0 {
+		return windows
+	}
+	var kept []WindowInfo
+	for _, window := range windows {
+		if !isExcludedWindow(window, settings) {
+			kept = append(kept, window)
+		}
+	}
+	return kept
+}
+
+// commaList splits a comma-separated list and drops empty entries.
+func commaList(text string) []string {
+	var list []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// excludeWindow asks to confirm and adds the executable or the class of a window to the exclusions.
+func (wm *WindowManager) excludeWindow(window WindowInfo, byClass bool) {
+	debug := true
+	name := filepath.Base(window.Executable)
+	if byClass {
+		name = window.ClassName
+	}
+	if name == "" || name == "." {
+		dialog.ShowError(fmt.Errorf("the window has no executable or class name to ignore"), wm.mainWindow)
+		return
+	}
+	message := fmt.Sprintf("Never list or position windows of %s again?\nIt can be removed from the Matching settings.", name)
+	dialog.ShowConfirm("Ignore windows", message, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		err := wm.updateSettings(func(s *Settings) {
+			list := &s.ExcludedExecutables
+			if byClass {
+				list = &s.ExcludedClasses
+			}
+			if !slices.ContainsFunc(*list, func(excluded string) bool { return strings.EqualFold(excluded, name) }) {
+				*list = append(*list, name)
+			}
+		})
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Ignoring windows of", name)
+		wm.refreshWindowList()
+	}, wm.mainWindow)
+}
+
+// windowRowMenu creates the menu with additional actions for a window in the window list.
+func (wm *WindowManager) windowRowMenu(window WindowInfo) *fyne.Menu {
+	return fyne.NewMenu("",
+		fyne.NewMenuItem("Ignore this app ("+filepath.Base(window.Executable)+")", safeCallback(func() {
+			wm.excludeWindow(window, false)
+		})),
+		fyne.NewMenuItem("Ignore this window class ("+window.ClassName+")", safeCallback(func() {
+			wm.excludeWindow(window, true)
+		})),
+		fyne.NewMenuItem("Normalize titles of this app…", safeCallback(func() {
+			wm.showTitleNormalizationDialog(window)
+		})),
+	)
+}
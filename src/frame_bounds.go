@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+/*
+	Visible frame:
+	- Since Windows 10, GetWindowRect includes invisible resize borders around the visible frame,
+	  about 7 pixels left, right and bottom at 100% scaling. A window placed at the screen edge by its
+	  window rectangle leaves a visible gap.
+	- DwmGetWindowAttribute(DWMWA_EXTENDED_FRAME_BOUNDS) returns the visible frame. The difference
+	  to the window rectangle are the frame margins of the window.
+	- With "Save the visible frame" enabled, positions are saved as the visible frame and marked
+	  with VisibleFrame. When such a position is applied, the current margins of the window, scaled
+	  to the DPI of the target monitor, are added back, so the visible frame lands on the target.
+	- Positions without the mark keep meaning the window rectangle, so the setting can be changed
+	  at any time. Apps that draw their own frame have no invisible borders, their margins are 0.
+	- The margins cannot be measured for minimized windows, the target is used unchanged then.
+*/
+
+// frameMarginLimit is the largest plausible invisible border, larger values are measurement errors
+const frameMarginLimit = 32
+
+// getExtendedFrameBounds returns the visible frame of a window, without the invisible borders.
+func getExtendedFrameBounds(hwnd syscall.Handle) (RECT, error) {
+	var rect RECT
+	if err := procDwmGetWindowAttribute.Find(); err != nil {
+		return rect, err
+	}
+	ret, _, _ := procDwmGetWindowAttribute.Call(uintptr(hwnd), DWMWA_EXTENDED_FRAME_BOUNDS,
+		uintptr(unsafe.Pointer(&rect)), unsafe.Sizeof(rect))
+	if ret != 0 { // HRESULT
+		return rect, fmt.Errorf("DwmGetWindowAttribute failed: 0x%08X", uint32(ret))
+	}
+	return rect, nil
+}
+
+// frameMargins returns the widths of the invisible borders of a window as a RECT
+// holding the left, top, right and bottom margin.
+func frameMargins(hwnd syscall.Handle) (RECT, bool) {
+	if isWindowMinimized(hwnd) {
+		return RECT{}, false
+	}
+	var window RECT
+	if ret, _, _ := procGetWindowRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&window))); ret == 0 {
+		return RECT{}, false
+	}
+	visible, err := getExtendedFrameBounds(hwnd)
+	if err != nil {
+		return RECT{}, false
+	}
+	margins := RECT{
+		Left:   visible.Left - window.Left,
+		Top:    visible.Top - window.Top,
+		Right:  window.Right - visible.Right,
+		Bottom: window.Bottom - visible.Bottom,
+	}
+	for _, margin := range []int32{margins.Left, margins.Top, margins.Right, margins.Bottom} {
+		if margin < 0 || margin > frameMarginLimit {
+			return RECT{}, false
+		}
+	}
+	return margins, true
+}
+
+// visibleFrame converts a window rectangle position of a window into its visible frame
+// and marks it, see VisibleFrame. Without measurable margins the position is kept.
+func (pos WindowPosition) visibleFrame(hwnd syscall.Handle) WindowPosition {
+	margins, ok := frameMargins(hwnd)
+	if !ok || pos.VisibleFrame {
+		return pos
+	}
+	pos.X += int(margins.Left)
+	pos.Y += int(margins.Top)
+	pos.Width -= int(margins.Left + margins.Right)
+	pos.Height -= int(margins.Top + margins.Bottom)
+	pos.VisibleFrame = true
+	return pos
+}
+
+// PhysicalFor returns the window rectangle to move a window to, like Physical. For a visible frame
+// position, the margins of the window are added, scaled from its current to the target DPI.
+func (pos WindowPosition) PhysicalFor(hwnd syscall.Handle) WindowPosition {
+	target := pos.Physical()
+	if !target.VisibleFrame {
+		return target
+	}
+	target.VisibleFrame = false
+	margins, ok := frameMargins(hwnd)
+	if !ok {
+		return target
+	}
+	from, to := getDpiForWindow(hwnd), getDpiForRect(target.Rect())
+	left := scaleForDpi(int(margins.Left), from, to)
+	top := scaleForDpi(int(margins.Top), from, to)
+	right := scaleForDpi(int(margins.Right), from, to)
+	bottom := scaleForDpi(int(margins.Bottom), from, to)
+	target.X -= left
+	target.Y -= top
+	target.Width += left + right
+	target.Height += top + bottom
+	return target
+}
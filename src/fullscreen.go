@@ -0,0 +1,55 @@
+package main
+
+import (
+	"slices"
+	"syscall"
+)
+
+// Classes of the desktop windows, which cover the whole monitor without a caption
+var desktopWindowClasses = []string{"Progman", "WorkerW"}
+
+// isFullscreenWindow checks if a window covers the whole monitor it is on and has no caption,
+// like a game or a video player in fullscreen mode.
+func isFullscreenWindow(hwnd syscall.Handle, monitors []Monitor) bool {
+	if hwnd == 0 || !isValidWindow(hwnd) {
+		return false
+	}
+	window := getWindowInfo(hwnd)
+	if window.Style&WS_CAPTION == WS_CAPTION || slices.Contains(desktopWindowClasses, window.ClassName) {
+		return false
+	}
+	index := monitorForRect(window.WindowRect, monitors)
+	if index < 0 {
+		return false
+	}
+	bounds := monitors[index].Bounds
+	// Some fullscreen windows extend beyond the monitor, so the window only has to cover it
+	return window.WindowRect.Left <= bounds.Left && window.WindowRect.Top <= bounds.Top &&
+		window.WindowRect.Right >= bounds.Right && window.WindowRect.Bottom >= bounds.Bottom
+}
+
+// pausedForFullscreen reports whether the monitoring cycle should be skipped because a fullscreen
+// window is in the foreground. Pausing and resuming are logged once.
+func (wm *WindowManager) pausedForFullscreen() bool {
+	debug := true
+	if !wm.getSettings().PauseForFullscreen {
+		wm.fullscreenPaused = false
+		return false
+	}
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		log(true, "Failed to get monitors:", err)
+		return false
+	}
+	foreground := getForegroundWindow()
+	fullscreen := isFullscreenWindow(foreground, monitors)
+	if fullscreen != wm.fullscreenPaused {
+		if fullscreen {
+			log(debug, "Fullscreen window in the foreground, pausing positioning:", redactTitle(getWindowText(foreground)))
+		} else {
+			log(debug, "Fullscreen window left the foreground, resuming positioning.")
+		}
+		wm.fullscreenPaused = fullscreen
+	}
+	return fullscreen
+}
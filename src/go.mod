@@ -4,11 +4,11 @@ go 1.24
 
 require (
 	fyne.io/fyne/v2 v2.6.2
+	fyne.io/systray v1.11.0
 	golang.org/x/sys v0.30.0
 )
 
 require (
-	fyne.io/systray v1.11.0 // indirect
 	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fredbi/uri v1.1.0 // indirect
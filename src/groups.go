@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Window groups:
+	- A group is a named set of saved positions that are applied together as a unit,
+	  e.g. the main window and the detached panels of an IDE or a DAW.
+	- Moving a group offsets the saved positions of all members and applies them.
+	- Renaming or merging saved positions rewrites the members of the groups, see rewriteGroupMembers.
+*/
+
+// WindowGroup is a named set of saved position identifiers
+type WindowGroup struct {
+	Name    string   `json:"name"`
+	Members []string `json:"members"`
+}
+
+// findGroup returns the group with the given name.
+func (s Settings) findGroup(name string) (WindowGroup, bool) {
+	for _, group := range s.Groups {
+		if group.Name == name {
+			return group, true
+		}
+	}
+	return WindowGroup{}, false
+}
+
+// addToGroup adds a saved position to a group, creating the group if needed.
+func (wm *WindowManager) addToGroup(name, identifier string) error {
+	return wm.updateSettings(func(s *Settings) {
+		for i, group := range s.Groups {
+			if group.Name == name {
+				if !slices.Contains(group.Members, identifier) {
+					s.Groups[i].Members = append(s.Groups[i].Members, identifier)
+				}
+				return
+			}
+		}
+		s.Groups = append(s.Groups, WindowGroup{Name: name, Members: []string{identifier}})
+	})
+}
+
+// rewriteGroupMembers changes the members of all groups after saved positions got new identifiers,
+// e.g. by editing, merging or migrating them. Members that end up with the same identifier are kept once.
+// The settings are only written if a member changed.
+func (wm *WindowManager) rewriteGroupMembers(rewrite func(identifier string) string) error {
+	changed := false
+	for _, group := range wm.getSettings().Groups {
+		changed = changed || slices.ContainsFunc(group.Members, func(identifier string) bool {
+			return rewrite(identifier) != identifier
+		})
+	}
+	if !changed {
+		return nil
+	}
+	return wm.updateSettings(func(s *Settings) {
+		for i, group := range s.Groups {
+			var members []string
+			for _, identifier := range group.Members {
+				if identifier = rewrite(identifier); !slices.Contains(members, identifier) {
+					members = append(members, identifier)
+				}
+			}
+			s.Groups[i].Members = members
+		}
+	})
+}
+
+// deleteGroup removes a group. The saved positions of its members are kept.
+func (wm *WindowManager) deleteGroup(name string) error {
+	return wm.updateSettings(func(s *Settings) {
+		s.Groups = slices.DeleteFunc(s.Groups, func(group WindowGroup) bool {
+			return group.Name == name
+		})
+	})
+}
+
+// applyGroup moves all open windows of a group to their saved positions.
+// The schedules of the members are ignored, the user applies the group explicitly.
+// It returns the number of moved members and the number of members without an open window.
+func (wm *WindowManager) applyGroup(name string) (int, int, error) {
+	debug := true
+	log(debug, "Applying group:", name)
+	group, ok := wm.getSettings().findGroup(name)
+	if !ok {
+		return 0, 0, fmt.Errorf("group not found: %s", name)
+	}
+
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+	moveHistory.beginArrangement()
+
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return 0, 0, err
+	}
+	positions := wm.storage.GetAllPositions()
+	moved, missing := 0, 0
+	var errs []string
+	for _, identifier := range group.Members {
+		pos, ok := positions[identifier]
+		if !ok {
+			log(true, "Group member has no saved position:", redactIdentifier(identifier))
+			missing++
+			continue
+		}
+		window, ok := findWindowForIdentifier(identifier, pos.TitlePattern, windows)
+		if !ok {
+			log(debug, "No open window for group member:", redactIdentifier(identifier))
+			missing++
+			continue
+		}
+		target := pos.PhysicalFor(window.Handle)
+		opts := pos.moveOptions(wm.getSettings())
+		if err := moveWindowWithOptions(window.Handle, target.X, target.Y, target.Width, target.Height, opts); err != nil {
+			logError("Failed to move group member", redactIdentifier(identifier)+":", err)
+			recordProblem(problemError, redactIdentifier(identifier), "Failed to move group member:", err)
+			errs = append(errs, err.Error())
+			continue
+		}
+		moved++
+	}
+	log(debug, "Group", name, "applied:", moved, "moved,", missing, "not found,", len(errs), "failed")
+	if len(errs) > 0 {
+		return moved, missing, fmt.Errorf("failed to move %d windows: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return moved, missing, nil
+}
+
+// moveGroup offsets the saved positions of all members of a group and applies them.
+func (wm *WindowManager) moveGroup(name string, dx, dy int) (int, int, error) {
+	log(true, "Moving group", name, "by", dx, dy)
+	group, ok := wm.getSettings().findGroup(name)
+	if !ok {
+		return 0, 0, fmt.Errorf("group not found: %s", name)
+	}
+	err := wm.storage.UpdatePositions(group.Members, func(identifier string, pos *WindowPosition) {
+		pos.X += dx
+		pos.Y += dy
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return wm.applyGroup(name)
+}
+
+// showAddToGroupDialog asks for the group a saved position should be added to.
+func (wm *WindowManager) showAddToGroupDialog(identifier string) {
+	var names []string
+	for _, group := range wm.getSettings().Groups {
+		names = append(names, group.Name)
+	}
+	nameEntry := widget.NewSelectEntry(names)
+	nameEntry.SetPlaceHolder("Group name")
+	items := []*widget.FormItem{widget.NewFormItem("Group", nameEntry)}
+	dialog.ShowForm("Add to group", "Add", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		name := strings.TrimSpace(nameEntry.Text)
+		if !confirmed || name == "" {
+			return
+		}
+		if err := wm.addToGroup(name, identifier); err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Added", redactIdentifier(identifier), "to group", name)
+	}, wm.mainWindow)
+}
+
+// showGroupsDialog lists all groups with actions to apply, move, and delete them.
+func (wm *WindowManager) showGroupsDialog() {
+	groups := wm.getSettings().Groups
+	if len(groups) == 0 {
+		dialog.ShowInformation("Groups", "There are no groups yet.\nUse \"Add to group…\" on a saved position to create one.", wm.mainWindow)
+		return
+	}
+
+	var d dialog.Dialog
+	rows := container.NewVBox()
+	for _, group := range groups {
+		name := group.Name
+		label := widget.NewLabel(fmt.Sprintf("%s (%d windows)", name, len(group.Members)))
+		applyBtn := widget.NewButtonWithIcon("Apply", theme.ConfirmIcon(), safeCallback(func() {
+			wm.runGroupAction(func() (int, int, error) { return wm.applyGroup(name) })
+		}))
+		moveBtn := widget.NewButtonWithIcon("Move…", theme.ViewRestoreIcon(), safeCallback(func() {
+			wm.showMoveGroupDialog(name)
+		}))
+		deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), safeCallback(func() {
+			if err := wm.deleteGroup(name); err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			d.Hide()
+			wm.showGroupsDialog()
+		}))
+		rows.Add(container.NewBorder(nil, nil, nil, container.NewHBox(applyBtn, moveBtn, deleteBtn), label))
+	}
+	scroll := container.NewVScroll(rows)
+	scroll.SetMinSize(fyne.NewSize(450, 250))
+	d = dialog.NewCustom("Groups", "Close", scroll, wm.mainWindow)
+	d.Show()
+}
+
+// showMoveGroupDialog asks for the offset to move a group by.
+func (wm *WindowManager) showMoveGroupDialog(name string) {
+	dxEntry := newIntEntry(0)
+	dyEntry := newIntEntry(0)
+	items := []*widget.FormItem{
+		widget.NewFormItem("Horizontal offset", dxEntry),
+		widget.NewFormItem("Vertical offset", dyEntry),
+	}
+	dialog.ShowForm("Move group "+name, "Move", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		dx, _ := strconv.Atoi(dxEntry.Text)
+		dy, _ := strconv.Atoi(dyEntry.Text)
+		wm.runGroupAction(func() (int, int, error) { return wm.moveGroup(name, dx, dy) })
+	}, wm.mainWindow)
+}
+
+// runGroupAction runs a group action in the background and reports the result.
+func (wm *WindowManager) runGroupAction(action func() (int, int, error)) {
+	go func() {
+		defer panicHandler()
+		moved, missing, err := action()
+		fyne.Do(func() {
+			if err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			dialog.ShowInformation("Group applied", fmt.Sprintf("Moved %d windows, %d not open.", moved, missing), wm.mainWindow)
+			wm.setupMainWindowContent() // Refresh the UI
+		})
+	}()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// MSG contains message information from a thread's message queue
+type MSG struct {
+	Hwnd     syscall.Handle
+	Message  uint32
+	WParam   uintptr
+	LParam   uintptr
+	Time     uint32
+	Pt       POINT
+	LPrivate uint32
+}
+
+// hotkeyBinding binds a key combination like "Ctrl+Alt+S" to an action
+type hotkeyBinding struct {
+	Name   string // Human readable name of the action
+	Combo  string // Key combination, e.g. "Ctrl+Alt+S"
+	Action func()
+}
+
+// HotkeyManager registers global hotkeys via RegisterHotKey and dispatches them.
+// Hotkeys are bound to the thread that registered them, so the manager runs its own
+// message loop goroutine on a locked OS thread.
+type HotkeyManager struct {
+	ctx      context.Context
+	mutex    sync.Mutex
+	threadID uintptr       // Thread running the message loop, 0 if not running
+	done     chan struct{} // Closed when the message loop exits
+	failed   []string      // Bindings that could not be registered
+}
+
+// NewHotkeyManager creates a hotkey manager that stops its message loop when ctx is cancelled.
+func NewHotkeyManager(ctx context.Context) *HotkeyManager {
+	hm := &HotkeyManager{ctx: ctx}
+	go func() {
+		defer panicHandler()
+		<-ctx.Done()
+		hm.Stop()
+	}()
+	return hm
+}
+
+// Start registers the given bindings and starts the message loop.
+// A running message loop is stopped first, so Start can be used to reload the bindings.
+// It returns an error describing all bindings that could not be registered.
+func (hm *HotkeyManager) Start(bindings []hotkeyBinding) error {
+	hm.Stop()
+	if len(bindings) == 0 || hm.ctx.Err() != nil {
+		return nil
+	}
+
+	ready := make(chan []string)
+	done := make(chan struct{})
+	go hm.messageLoop(bindings, ready, done)
+	failed := <-ready
+
+	hm.mutex.Lock()
+	hm.done = done
+	hm.failed = failed
+	hm.mutex.Unlock()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to register hotkeys: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Stop unregisters all hotkeys and ends the message loop.
+func (hm *HotkeyManager) Stop() {
+	hm.mutex.Lock()
+	threadID, done := hm.threadID, hm.done
+	hm.mutex.Unlock()
+	if threadID == 0 || done == nil {
+		return
+	}
+	procPostThreadMessage.Call(threadID, WM_QUIT, 0, 0)
+	<-done
+}
+
+// Failed returns the bindings that could not be registered by the last Start.
+func (hm *HotkeyManager) Failed() []string {
+	hm.mutex.Lock()
+	defer hm.mutex.Unlock()
+	return append([]string(nil), hm.failed...)
+}
+
+// messageLoop registers the hotkeys on a locked OS thread and dispatches WM_HOTKEY messages
+// until WM_QUIT is received. The registration result is reported on the ready channel.
+func (hm *HotkeyManager) messageLoop(bindings []hotkeyBinding, ready chan<- []string, done chan struct{}) {
+	debug := true
+	defer panicHandler()
+	defer close(done)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Make sure the thread has a message queue before anyone posts to it
+	var msg MSG
+	procPeekMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, WM_USER, WM_USER, PM_NOREMOVE)
+	threadID, _, _ := procGetCurrentThreadId.Call()
+	hm.mutex.Lock()
+	hm.threadID = threadID
+	hm.mutex.Unlock()
+	defer func() {
+		hm.mutex.Lock()
+		hm.threadID = 0
+		hm.mutex.Unlock()
+	}()
+
+	// Register all bindings, the hotkey ID is the index in the bindings slice + 1
+	var failed []string
+	var registered []uintptr
+	for i, binding := range bindings {
+		modifiers, vk, err := parseHotkey(binding.Combo)
+		if err != nil {
+			logError("Invalid hotkey for", binding.Name+":", err)
+			recordProblem(problemError, binding.Name, "Invalid hotkey:", err)
+			failed = append(failed, fmt.Sprintf("%s (%s: invalid)", binding.Combo, binding.Name))
+			continue
+		}
+		id := uintptr(i + 1)
+		ret, _, err := procRegisterHotKey.Call(0, id, uintptr(modifiers|MOD_NOREPEAT), uintptr(vk))
+		if ret == 0 {
+			// Usually ERROR_HOTKEY_ALREADY_REGISTERED: another application owns this combination
+			logError("Failed to register hotkey", binding.Combo, "for", binding.Name+":", err)
+			recordProblem(problemError, binding.Name, "Failed to register hotkey", binding.Combo+":", err)
+			failed = append(failed, fmt.Sprintf("%s (%s: %v)", binding.Combo, binding.Name, err))
+			continue
+		}
+		log(debug, "Registered hotkey", binding.Combo, "for", binding.Name)
+		registered = append(registered, id)
+	}
+	defer func() {
+		for _, id := range registered {
+			procUnregisterHotKey.Call(0, id)
+		}
+		log(debug, "Unregistered", len(registered), "hotkeys.")
+	}()
+	ready <- failed
+
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(ret) <= 0 { // WM_QUIT or error
+			return
+		}
+		if msg.Message != WM_HOTKEY {
+			continue
+		}
+		index := int(msg.WParam) - 1
+		if index < 0 || index >= len(bindings) {
+			continue
+		}
+		binding := bindings[index]
+		log(debug, "Hotkey pressed:", binding.Combo, "->", binding.Name)
+		go safeCallback(binding.Action)()
+	}
+}
+
+// hotkeyModifiers maps modifier names to RegisterHotKey modifier flags
+var hotkeyModifiers = map[string]uint32{
+	"ALT":     MOD_ALT,
+	"CTRL":    MOD_CONTROL,
+	"CONTROL": MOD_CONTROL,
+	"SHIFT":   MOD_SHIFT,
+	"WIN":     MOD_WIN,
+}
+
+// hotkeyKeys maps named keys to virtual-key codes
+var hotkeyKeys = map[string]uint32{
+	"SPACE": 0x20, "PAGEUP": 0x21, "PAGEDOWN": 0x22, "END": 0x23, "HOME": 0x24,
+	"LEFT": 0x25, "UP": 0x26, "RIGHT": 0x27, "DOWN": 0x28,
+	"INSERT": 0x2D, "DELETE": 0x2E, "ENTER": 0x0D, "ESC": 0x1B, "TAB": 0x09, "BACKSPACE": 0x08,
+	"NUMPAD0": 0x60, "NUMPAD1": 0x61, "NUMPAD2": 0x62, "NUMPAD3": 0x63, "NUMPAD4": 0x64,
+	"NUMPAD5": 0x65, "NUMPAD6": 0x66, "NUMPAD7": 0x67, "NUMPAD8": 0x68, "NUMPAD9": 0x69,
+}
+
+// parseHotkey parses a key combination like "Ctrl+Alt+S" or "F1" into
+// RegisterHotKey modifier flags and a virtual-key code.
+func parseHotkey(combo string) (uint32, uint32, error) {
+	parts := strings.Split(combo, "+")
+	var modifiers, vk uint32
+	for i, part := range parts {
+		name := strings.ToUpper(strings.TrimSpace(part))
+		if name == "" {
+			return 0, 0, fmt.Errorf("empty key in '%s'", combo)
+		}
+		if i < len(parts)-1 {
+			modifier, ok := hotkeyModifiers[name]
+			if !ok {
+				return 0, 0, fmt.Errorf("unknown modifier '%s' in '%s'", part, combo)
+			}
+			modifiers |= modifier
+			continue
+		}
+		switch {
+		case len(name) == 1 && (name[0] >= 'A' && name[0] <= 'Z' || name[0] >= '0' && name[0] <= '9'):
+			vk = uint32(name[0]) // Virtual-key codes of letters and digits match ASCII
+		case len(name) >= 2 && name[0] == 'F':
+			var n int
+			if _, err := fmt.Sscanf(name[1:], "%d", &n); err != nil || n < 1 || n > 24 {
+				return 0, 0, fmt.Errorf("unknown key '%s' in '%s'", part, combo)
+			}
+			vk = VK_F1 + uint32(n-1)
+		default:
+			key, ok := hotkeyKeys[name]
+			if !ok {
+				return 0, 0, fmt.Errorf("unknown key '%s' in '%s'", part, combo)
+			}
+			vk = key
+		}
+	}
+	return modifiers, vk, nil
+}
+
+// hotkeyBindings builds the list of hotkey bindings from the current settings.
+func (wm *WindowManager) hotkeyBindings() []hotkeyBinding {
+	settings := wm.getSettings()
+	var bindings []hotkeyBinding
+
+	// Position slots
+	if settings.SlotsEnabled {
+		for _, key := range settings.SlotKeys {
+			slot := strings.TrimSpace(key)
+			if slot == "" {
+				continue
+			}
+			bindings = append(bindings,
+				hotkeyBinding{Name: "Move to slot " + slot, Combo: slot, Action: func() { wm.moveForegroundWindowToSlot(slot) }},
+				hotkeyBinding{Name: "Save slot " + slot, Combo: settings.SlotSaveModifier + "+" + slot, Action: func() { wm.saveForegroundWindowToSlot(slot) }},
+			)
+		}
+	}
+
+	// Snap the foreground window to its saved position
+	if combo := strings.TrimSpace(settings.SnapHotkey); combo != "" {
+		bindings = append(bindings, hotkeyBinding{Name: "Snap to saved position", Combo: combo, Action: wm.snapForegroundWindow})
+	}
+
+	// Save the foreground window's position as a rule
+	if combo := strings.TrimSpace(settings.SaveHotkey); combo != "" {
+		bindings = append(bindings, hotkeyBinding{Name: "Save position", Combo: combo, Action: wm.saveForegroundWindow})
+	}
+
+	// Center the foreground window on its monitor
+	if combo := strings.TrimSpace(settings.CenterHotkey); combo != "" {
+		bindings = append(bindings, hotkeyBinding{Name: "Center on monitor", Combo: combo, Action: func() { wm.centerWindow(getForegroundWindow()) }})
+	}
+
+	// Undo the last arrangement
+	if combo := strings.TrimSpace(settings.UndoHotkey); combo != "" {
+		bindings = append(bindings, hotkeyBinding{Name: "Undo last arrangement", Combo: combo, Action: wm.runUndoLastArrangement})
+	}
+
+	// Tile the foreground window
+	for _, layout := range tileLayouts {
+		if combo := strings.TrimSpace(settings.TileHotkeys[layout.ID]); combo != "" {
+			bindings = append(bindings, hotkeyBinding{Name: "Tile " + layout.Name, Combo: combo, Action: func() { wm.tileForegroundWindow(layout.ID) }})
+		}
+	}
+	return bindings
+}
+
+// reloadHotkeys (re)registers all global hotkeys from the current settings.
+// It returns an error listing the hotkeys that could not be registered.
+func (wm *WindowManager) reloadHotkeys() error {
+	err := wm.hotkeys.Start(wm.hotkeyBindings())
+	if err != nil {
+		log(true, "Hotkey registration incomplete:", err)
+	}
+	return err
+}
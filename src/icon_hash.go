@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// ICONINFO contains the bitmaps of an icon, returned by GetIconInfo
+type ICONINFO struct {
+	FIcon    int32
+	XHotspot uint32
+	YHotspot uint32
+	HbmMask  syscall.Handle
+	HbmColor syscall.Handle
+}
+
+// BITMAP describes a bitmap, returned by GetObjectW
+type BITMAP struct {
+	BmType       int32
+	BmWidth      int32
+	BmHeight     int32
+	BmWidthBytes int32
+	BmPlanes     uint16
+	BmBitsPixel  uint16
+	BmBits       uintptr
+}
+
+// Icon hashes by icon handle. Icon handles stay valid while the icon exists,
+// so a handle is hashed only once.
+var iconHashCache = make(map[uintptr]string)
+var iconHashMutex sync.Mutex
+
+// maxIconHashCache limits the cache, it is cleared when it grows larger
+const maxIconHashCache = 1024
+
+// windowIconHash returns a hash of the small icon of a window, or an empty string if the
+// window has no icon. The icon is requested with WM_GETICON and falls back to the class icon.
+// This is expensive compared to the textual attributes, see the matching notes in matching.go.
+func windowIconHash(hwnd syscall.Handle) string {
+	debug := false
+	icon := getWindowIcon(hwnd)
+	if icon == 0 {
+		return ""
+	}
+
+	iconHashMutex.Lock()
+	defer iconHashMutex.Unlock()
+	if hash, ok := iconHashCache[icon]; ok {
+		return hash
+	}
+	hash, err := hashIcon(icon)
+	if err != nil {
+		log(debug, "Failed to hash icon of window", hwnd, ":", err)
+		return ""
+	}
+	if len(iconHashCache) >= maxIconHashCache {
+		iconHashCache = make(map[uintptr]string)
+	}
+	iconHashCache[icon] = hash
+	return hash
+}
+
+// getWindowIcon returns the small icon handle of a window, or 0 if it has none.
+// A hung window does not block the caller, as the message times out.
+func getWindowIcon(hwnd syscall.Handle) uintptr {
+	const timeoutMilliseconds = 100
+	var icon uintptr
+	ret, _, _ := procSendMessageTimeout.Call(uintptr(hwnd), WM_GETICON, ICON_SMALL2, 0,
+		SMTO_ABORTIFHUNG, timeoutMilliseconds, uintptr(unsafe.Pointer(&icon)))
+	if ret != 0 && icon != 0 {
+		return icon
+	}
+	if err := procGetClassLongPtr.Find(); err != nil {
+		return 0 // Not available on 32-bit Windows
+	}
+	index := int32(GCLP_HICONSM)
+	icon, _, _ = procGetClassLongPtr.Call(uintptr(hwnd), uintptr(index))
+	return icon
+}
+
+// hashIcon hashes the pixels of an icon. The color bitmap is used if the icon has one,
+// monochrome icons only have the mask bitmap.
+func hashIcon(icon uintptr) (string, error) {
+	var info ICONINFO
+	ret, _, err := procGetIconInfo.Call(icon, uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		return "", fmt.Errorf("GetIconInfo failed: %v", err)
+	}
+	// GetIconInfo creates copies of the bitmaps, which must be deleted
+	defer procDeleteObject.Call(uintptr(info.HbmMask))
+	if info.HbmColor != 0 {
+		defer procDeleteObject.Call(uintptr(info.HbmColor))
+	}
+
+	bitmap := info.HbmColor
+	if bitmap == 0 {
+		bitmap = info.HbmMask
+	}
+	var bm BITMAP
+	if ret, _, _ := procGetObject.Call(uintptr(bitmap), unsafe.Sizeof(bm), uintptr(unsafe.Pointer(&bm))); ret == 0 {
+		return "", fmt.Errorf("GetObject failed for icon bitmap")
+	}
+	size := int(bm.BmWidthBytes) * int(bm.BmHeight)
+	if size <= 0 {
+		return "", fmt.Errorf("empty icon bitmap")
+	}
+	bits := make([]byte, size)
+	if ret, _, _ := procGetBitmapBits.Call(uintptr(bitmap), uintptr(size), uintptr(unsafe.Pointer(&bits[0]))); ret == 0 {
+		return "", fmt.Errorf("GetBitmapBits failed for icon bitmap")
+	}
+
+	hasher := fnv.New64a()
+	hasher.Write(bits)
+	return fmt.Sprintf("%016x", hasher.Sum64()), nil
+}
+
+// captureIconHash returns the icon hash of the first open window matching an identifier.
+// It is used to add the icon to a rule, which requires the window to be open.
+func captureIconHash(identifier string, titlePattern bool) (string, error) {
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return "", err
+	}
+	window, ok := findWindowForIdentifier(withIconHash(identifier, ""), titlePattern, windows)
+	if !ok {
+		return "", fmt.Errorf("open the window to capture its icon")
+	}
+	hash := windowIconHash(window.Handle)
+	if hash == "" {
+		return "", fmt.Errorf("the window '%s' has no icon", window.Title)
+	}
+	return hash, nil
+}
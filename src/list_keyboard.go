@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Keyboard navigation of the window list:
+	- Up and Down move the selection of the window list, the list highlights the selected row.
+	- Enter focuses the selected window, Ctrl+S saves its position and Delete ignores its app.
+	- The keys are handled by the canvas of the main window, so they work while no entry has the focus.
+	  Clicking a row selects it as well.
+	- The selection follows the window, not the row, so it survives refreshing and re-sorting the list.
+*/
+
+// setupListKeyboard registers the key handlers of the window list on the main window's canvas.
+func (wm *WindowManager) setupListKeyboard() {
+	canvas := wm.mainWindow.Canvas()
+	canvas.SetOnTypedKey(func(key *fyne.KeyEvent) {
+		switch key.Name {
+		case fyne.KeyDown:
+			wm.moveListCursor(1)
+		case fyne.KeyUp:
+			wm.moveListCursor(-1)
+		case fyne.KeyReturn, fyne.KeyEnter:
+			if window, ok := wm.cursorWindow(); ok {
+				wm.focusListedWindow(window)
+			}
+		case fyne.KeyDelete:
+			if window, ok := wm.cursorWindow(); ok {
+				wm.excludeWindow(window, false)
+			}
+		}
+	})
+	canvas.AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyS, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+		if window, ok := wm.cursorWindow(); ok {
+			wm.saveListedWindow(window)
+		}
+	})
+}
+
+// bindListCursor keeps the selected window in sync with the selection of the window list.
+func (wm *WindowManager) bindListCursor(list *widget.List) {
+	list.OnSelected = func(id widget.ListItemID) {
+		if windows := wm.listedWindows(); id >= 0 && id < len(windows) {
+			wm.listCursor = windows[id].Handle
+		}
+	}
+	list.OnUnselected = func(widget.ListItemID) {
+		wm.listCursor = 0
+	}
+}
+
+// cursorIndex returns the row of the selected window in the listed windows, -1 if it is not listed.
+func cursorIndex(windows []WindowInfo, cursor syscall.Handle) int {
+	if cursor == 0 {
+		return -1
+	}
+	return slices.IndexFunc(windows, func(window WindowInfo) bool { return window.Handle == cursor })
+}
+
+// cursorWindow returns the selected window of the window list.
+func (wm *WindowManager) cursorWindow() (WindowInfo, bool) {
+	windows := wm.listedWindows()
+	index := cursorIndex(windows, wm.listCursor)
+	if index < 0 {
+		return WindowInfo{}, false
+	}
+	return windows[index], true
+}
+
+// moveListCursor selects the row the given number of rows below the selected one,
+// the first row if no window is selected.
+func (wm *WindowManager) moveListCursor(delta int) {
+	windows := wm.listedWindows()
+	if len(windows) == 0 || wm.windowList == nil {
+		return
+	}
+	index := cursorIndex(windows, wm.listCursor)
+	if index < 0 {
+		index = 0
+	} else {
+		index = min(max(index+delta, 0), len(windows)-1)
+	}
+	wm.windowList.Select(index)
+	wm.windowList.ScrollTo(index)
+}
+
+// restoreListCursor selects the row of the selected window again after the list changed.
+func (wm *WindowManager) restoreListCursor() {
+	if wm.windowList == nil || wm.listCursor == 0 {
+		return
+	}
+	if index := cursorIndex(wm.listedWindows(), wm.listCursor); index >= 0 {
+		wm.windowList.Select(index)
+	} else {
+		wm.windowList.UnselectAll()
+	}
+}
+
+// focusListedWindow brings a window of the window list to the front.
+func (wm *WindowManager) focusListedWindow(window WindowInfo) {
+	// Validate window handle before attempting to focus
+	if !isValidWindow(window.Handle) {
+		log(true, "Cannot focus window - handle is invalid:", window.Handle)
+		dialog.ShowError(fmt.Errorf("window no longer exists: %s", window.Title), wm.mainWindow)
+		return
+	}
+	err := focusWindow(window.Handle)
+	if err != nil {
+		log(true, "Failed to focus window:", err)
+		dialog.ShowError(fmt.Errorf("failed to focus window: %v", err), wm.mainWindow)
+	}
+}
+
+// saveListedWindow saves the position of a window of the window list.
+func (wm *WindowManager) saveListedWindow(window WindowInfo) {
+	// Validate window handle before attempting to save position
+	if !isValidWindow(window.Handle) {
+		log(true, "Cannot save position - window handle is invalid:", window.Handle)
+		dialog.ShowError(fmt.Errorf("window no longer exists: %s", window.Title), wm.mainWindow)
+		return
+	}
+	wm.saveWindowPosition(window)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showLogWindow opens a window with the messages kept in memory, or brings it to the front if it is already open.
+// A second tab lists only the warnings and errors.
+func (wm *WindowManager) showLogWindow() {
+	if wm.logWindow != nil {
+		wm.logWindow.Show()
+		wm.logWindow.RequestFocus()
+		return
+	}
+
+	text := widget.NewMultiLineEntry()
+	text.Wrapping = fyne.TextWrapOff
+	text.TextStyle = fyne.TextStyle{Monospace: true}
+
+	var problemList []problem
+	problemsWidget := widget.NewList(
+		func() int { return len(problemList) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(problemList[id].String())
+		},
+	)
+	noProblems := widget.NewLabel("No warnings or errors.")
+
+	refresh := func() {
+		text.SetText(strings.Join(logSnapshot(), "\n"))
+		text.CursorRow = strings.Count(text.Text, "\n") // Scroll to the newest message
+		problemList = problemsSnapshot()
+		problemsWidget.Refresh()
+		noProblems.Hidden = len(problemList) > 0
+		noProblems.Refresh()
+	}
+	refresh()
+
+	window := wm.app.NewWindow(strProductName + " Log")
+	clearBtn := widget.NewButtonWithIcon("Clear", theme.DeleteIcon(), safeCallback(func() {
+		clearProblems()
+		refresh()
+	}))
+	problemsTab := container.NewTabItem("Problems", container.NewBorder(noProblems, clearBtn, nil, nil, problemsWidget))
+	tabs := container.NewAppTabs(container.NewTabItem("Log", text), problemsTab)
+
+	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), safeCallback(refresh))
+	copyBtn := widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), safeCallback(func() {
+		if tabs.Selected() == problemsTab {
+			lines := make([]string, len(problemList))
+			for i, entry := range problemList {
+				lines[i] = entry.String()
+			}
+			window.Clipboard().SetContent(strings.Join(lines, "\n"))
+			return
+		}
+		window.Clipboard().SetContent(text.Text)
+	}))
+	closeBtn := widget.NewButtonWithIcon("Close", theme.CancelIcon(), func() {
+		window.Close()
+	})
+
+	window.SetContent(container.NewBorder(nil,
+		container.New(layout.NewGridLayout(3), refreshBtn, copyBtn, closeBtn),
+		nil, nil, tabs))
+	window.Resize(fyne.NewSize(800, 500))
+	window.SetOnClosed(func() {
+		wm.logWindow = nil
+	})
+	wm.logWindow = window
+	window.Show()
+}
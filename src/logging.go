@@ -5,13 +5,17 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 /*
 === Lancer's simple logging module ===
 	This module provides a simple logging mechanism that writes debug messages to both the console and a log file.
-	The log file is created in the user's application data directory, and it is purged at the start of the application.
+	The log file is created in the user's application data directory. New messages are appended, and when the file
+	exceeds the size limit it is rotated: log.txt becomes log.1.txt, log.1.txt becomes log.2.txt and so on, up to
+	the number of backups.
 	It is designed to be used for debugging purposes, allowing developers to track the flow of the application.
 
 	It needs three global variables to work:
@@ -21,7 +25,15 @@ import (
 
 	Usage:
 
-	log(true, "Some var", "is", var)
+	logInfo("Some var", "is", var)
+	logWarn("Failed to do something:", err)
+
+	Messages below the minimum level (Info by default) are dropped, see setLogLevel.
+	log(debug, ...) is kept for the existing calls: it logs at Info if debug is true and at Debug otherwise.
+
+	Every message is also kept in a bounded in-memory buffer for the log window.
+	The log file is opened by configureLogging, messages logged before are written to it then.
+	In memory-only mode, the log file is never opened.
 
 */
 
@@ -29,29 +41,104 @@ var strLogFilePath string // eg. <dataFolder>\Dataport\<Product>\log.txt
 var fileLog *os.File
 var strAppTempDir string // like %APPDATA%\Dataport\<Product>\
 
+// maxLogLines is the number of messages kept in memory
+const maxLogLines = 2000
+
+// Defaults of the log rotation, changed by setLogRotation
+const (
+	defaultLogMaxBytes = 5 * 1024 * 1024
+	defaultLogBackups  = 3
+)
+
+// logLevel is the severity of a message
+type logLevel int32
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// logLevelNames are the names of the levels, as stored in the settings and written to the log
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// String returns the name of the level in upper case, e.g. "WARN".
+func (l logLevel) String() string {
+	if l < levelDebug || l > levelError {
+		return "?"
+	}
+	return strings.ToUpper(logLevelNames[l])
+}
+
+// parseLogLevel returns the level with the given name, or Info if the name is unknown.
+func parseLogLevel(name string) logLevel {
+	for i, n := range logLevelNames {
+		if strings.EqualFold(n, name) {
+			return logLevel(i)
+		}
+	}
+	return levelInfo
+}
+
+var logMinLevel atomic.Int32 // Minimum level of the logged messages, Info until setLogLevel is called
+
+func init() {
+	logMinLevel.Store(int32(levelInfo))
+}
+
+// setLogLevel sets the minimum level of the logged messages. It can be changed at any time.
+func setLogLevel(level logLevel) {
+	logMinLevel.Store(int32(level))
+}
+
+var logLines []string     // Ring buffer of the last messages
+var logNext int           // Index of the next message in the ring buffer once it is full
+var logConfigured bool    // configureLogging was called
+var logMemoryOnly bool    // Never write the log file
+var logMutex sync.Mutex   // Mutex to protect the log state
+var logSilent atomic.Bool // Do not print messages to the console, set by the command line mode
+var logFileSize int64     // Size of the log file, protected by the logMutex
+var logMaxBytes int64 = defaultLogMaxBytes
+var logBackups = defaultLogBackups
+
 // log writes a message to the log file and console.
-// If debug is false, it does nothing. If debug is true, it writes the message to the log file and console.
-// It can take multiple arguments, which will be converted to strings.
-// The first argument is the debug flag, the rest are the message parts.
-// It also includes the name of the function that called it.
+// It is the former boolean interface, kept so the existing calls still work: a message
+// with debug set is logged at Info, one without at Debug. New code uses logInfo etc.
 func log(debug bool, arrMessageParts ...any) {
-	if !debug {
-		return
+	level := levelDebug
+	if debug {
+		level = levelInfo
 	}
-	// check if the logfile is ready
-	if fileLog == nil {
-		err := activateLogging()
-		if err != nil {
-			fmt.Println("Error activating logging:", err)
-			return
-		}
+	logAt(level, arrMessageParts...)
+}
+
+// logDebug logs a message that is only of interest while tracing a problem.
+func logDebug(arrMessageParts ...any) { logAt(levelDebug, arrMessageParts...) }
+
+// logInfo logs a message about the normal operation.
+func logInfo(arrMessageParts ...any) { logAt(levelInfo, arrMessageParts...) }
+
+// logWarn logs a failure the application recovers from.
+func logWarn(arrMessageParts ...any) { logAt(levelWarn, arrMessageParts...) }
+
+// logError logs a failure of something the user asked for or a crash.
+func logError(arrMessageParts ...any) { logAt(levelError, arrMessageParts...) }
+
+// logAt writes a message with the given level to the log file and console, unless the level
+// is below the minimum level. It can take multiple arguments, which will be converted to strings.
+// It also includes the name of the function that called the log function.
+// It must be called directly by one of the log functions, for the name of the caller to be right.
+func logAt(level logLevel, arrMessageParts ...any) {
+	if int32(level) < logMinLevel.Load() {
+		return
 	}
 	// Get current time and format it as HH:mm:ss.fff
 	now := time.Now()
 	timestamp := now.Format("15:04:05.000")
 	strParentName := `main.unknown`
-	// Get the parent function's name
-	ptrCaller, _, _, isSuccess := runtime.Caller(1)
+	// Get the name of the function that called log, logInfo etc.
+	ptrCaller, _, _, isSuccess := runtime.Caller(2)
 	if isSuccess {
 		funcCaller := runtime.FuncForPC(ptrCaller)
 		if funcCaller != nil {
@@ -63,14 +150,128 @@ func log(debug bool, arrMessageParts ...any) {
 	for i, v := range arrMessageParts {
 		arrMessages[i] = fmt.Sprint(v)
 	}
-	fmt.Println(timestamp, `[`+strParentName+`]`, strings.Join(arrMessages, " "))
-	fmt.Fprintln(fileLog, timestamp, `[`+strParentName+`]`, strings.Join(arrMessages, " "))
+	line := fmt.Sprintf("%s %-5s [%s] %s", timestamp, level, strParentName, strings.Join(arrMessages, " "))
+	if !logSilent.Load() {
+		fmt.Println(line)
+	}
+
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	appendLogLine(line)
+	if fileLog != nil {
+		n, _ := fmt.Fprintln(fileLog, line)
+		logFileSize += int64(n)
+		if logMaxBytes > 0 && logFileSize > logMaxBytes {
+			rotateLogFile()
+		}
+	}
+}
+
+// setLogRotation sets the size at which the log file is rotated and the number of rotated files kept.
+// A size of 0 disables the rotation.
+func setLogRotation(maxBytes int64, backups int) {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	logMaxBytes = maxBytes
+	logBackups = max(backups, 0)
+}
+
+// logBackupPath returns the path of the n-th rotated log file, e.g. log.1.txt.
+func logBackupPath(n int) string {
+	return strings.TrimSuffix(strLogFilePath, ".txt") + fmt.Sprintf(".%d.txt", n)
+}
+
+// rotateLogFile renames the log file to the first backup, shifting the older backups,
+// and continues with an empty log file. Without backups, the log file is only emptied.
+// It must be called with the logMutex held, so no message is written during the rotation.
+func rotateLogFile() {
+	fileLog.Close()
+	fileLog = nil
+	os.Remove(logBackupPath(logBackups))
+	for n := logBackups - 1; n >= 1; n-- {
+		os.Rename(logBackupPath(n), logBackupPath(n+1))
+	}
+	if logBackups > 0 {
+		os.Rename(strLogFilePath, logBackupPath(1))
+	}
+	file, err := os.OpenFile(strLogFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		fmt.Println("Unable to reopen log file at '"+strLogFilePath+"':", err)
+		return
+	}
+	fileLog = file
+	logFileSize = 0
+}
+
+// appendLogLine adds a message to the in-memory ring buffer.
+// It must be called with the logMutex held.
+func appendLogLine(line string) {
+	if len(logLines) < maxLogLines {
+		logLines = append(logLines, line)
+		return
+	}
+	logLines[logNext] = line
+	logNext = (logNext + 1) % maxLogLines
 }
 
-// Activates the logging module. See function log() for details.
-func activateLogging() error {
+// logSnapshot returns the messages kept in memory, oldest first.
+func logSnapshot() []string {
+	logMutex.Lock()
+	defer logMutex.Unlock()
+	lines := make([]string, 0, len(logLines))
+	lines = append(lines, logLines[logNext:]...)
+	return append(lines, logLines[:logNext]...)
+}
+
+// configureLogging sets whether the log is written to the log file or kept in memory only.
+// When the file is enabled, the messages logged so far are written to it. Switching to
+// memory-only closes and deletes the log file, so no titles or paths stay on disk.
+func configureLogging(memoryOnly bool) error {
+	logMutex.Lock()
+	logConfigured = true
+	logMemoryOnly = memoryOnly
+	if memoryOnly {
+		defer logMutex.Unlock()
+		if fileLog != nil {
+			fileLog.Close()
+			fileLog = nil
+			os.Remove(strLogFilePath)
+			for n := 1; n <= logBackups; n++ {
+				os.Remove(logBackupPath(n))
+			}
+		}
+		return nil
+	}
+	if fileLog != nil {
+		logMutex.Unlock()
+		return nil
+	}
+	lines := make([]string, 0, len(logLines))
+	lines = append(lines, logLines[logNext:]...)
+	lines = append(lines, logLines[:logNext]...)
+	logMutex.Unlock()
+
+	file, err := activateLogging()
+	if err != nil {
+		return err
+	}
+	logMutex.Lock()
+	if info, err := file.Stat(); err == nil {
+		logFileSize = info.Size()
+	}
+	for _, line := range lines {
+		n, _ := fmt.Fprintln(file, line)
+		logFileSize += int64(n)
+	}
+	fileLog = file
+	logMutex.Unlock()
+	log(true, `Logging to `+strLogFilePath)
+	return nil
+}
+
+// Activates the logging module by opening the log file. See function log() for details.
+func activateLogging() (*os.File, error) {
 	// Can have no logging since logger not ready yet!
-	debug := true // set to true to enable debug logging
 	switch runtime.GOOS {
 	case `windows`:
 		strTempDir := os.Getenv("LOCALAPPDATA")
@@ -98,13 +299,12 @@ func activateLogging() error {
 		// If not, create the directory.
 		os.MkdirAll(strAppTempDir, 0755)
 	}
-	var err error
-	fileLog, err = os.OpenFile(strLogFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	// Append, so the log of previous sessions is kept until it is rotated
+	file, err := os.OpenFile(strLogFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		fmt.Println("Unable to open log file at '"+strLogFilePath+"':", err)
-		return err
+		return nil, err
 	}
 	//defer fileLog.Close()
-	log(debug, `Logging to `+strLogFilePath)
-	return nil
+	return file, nil
 }
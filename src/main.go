@@ -37,6 +37,37 @@ func main() {
 
 	defer panicHandler()
 
+	// Use physical pixels on every monitor, before any window or coordinate is touched.
+	// The move helper and the command line mode need it, too.
+	enableDpiAwareness()
+
+	// Started elevated as move helper, see elevated_helper.go
+	if len(os.Args) == 4 && os.Args[1] == moveHelperFlag {
+		os.Exit(runMoveHelper(os.Args[2], os.Args[3]))
+	}
+
+	// Command line mode for scripts, see cli.go
+	if isCLICommand(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	// Restarted as administrator, the previous instance holds the single instance mutex until it exited
+	if len(os.Args) == 3 && os.Args[1] == restartAfterFlag {
+		waitForPreviousInstance(os.Args[2])
+	}
+
+	// Only one tray may run, a second start shows the running instance, see single_instance.go
+	if !claimSingleInstance() {
+		log(true, strProductName, "is already running, activating the running instance.")
+		activateRunningInstance()
+		return
+	}
+
+	// Always run elevated, the elevated instance takes over, see elevated_startup.go
+	if relaunchIfAlwaysElevated() {
+		return
+	}
+
 	debug := true
 	log(true, `Starting`, strAppTitle)
 	log(true, "HEARTBEAT: Application startup initiated at", time.Now().Format("2006-01-02 15:04:05"))
@@ -66,20 +97,65 @@ func main() {
 	myApp := app.NewWithID(strAppId)
 
 	// Initialize the window manager
-	wm = NewWindowManager(myApp)
+	wm = NewWindowManager(ctx, myApp)
+
+	// Open the log file unless the user keeps the log in memory only
+	settings := wm.getSettings()
+	setLogRotation(int64(settings.LogMaxSizeMB)*1024*1024, settings.LogBackups)
+	if err := configureLogging(settings.LogMemoryOnly); err != nil {
+		fmt.Println("Error activating logging:", err)
+	}
 
 	// Set up system tray if supported
 	if desk, ok := myApp.(desktop.App); ok {
 		wm.setupSystemTray(desk)
 	}
 
+	// Tell the user early that nothing can be saved, e.g. on locked-down machines
+	if wm.storageErr != nil {
+		wm.notify("Settings and positions cannot be saved: " + wm.storageErr.Error())
+	}
+
+	// Register the global hotkeys
+	if err := wm.reloadHotkeys(); err != nil {
+		wm.notify(err.Error())
+	}
+
+	// Listen for display and work area changes
+	wm.registerSystemEventHandlers()
+	wm.registerPowerEventHandler()
+	if err := systemEvents.Start(ctx); err != nil {
+		logWarn("Failed to start the system event window:", err)
+		recordProblem(problemWarning, "", "Failed to start the system event window:", err)
+	}
+
+	// Position windows as soon as they appear, the monitoring service polls if this is disabled or fails
+	if err := wm.reloadWindowEvents(); err != nil {
+		logWarn("Failed to install the window event hooks, checking windows at the interval:", err)
+		recordProblem(problemWarning, "", "Failed to install the window event hooks:", err)
+	}
+
+	// Accept commands of other programs
+	if err := wm.reloadControlPipe(); err != nil {
+		logWarn("Failed to start the control pipe:", err)
+		recordProblem(problemWarning, "", "Failed to start the control pipe:", err)
+	}
+
 	go wm.startMonitoringService(ctx)
 
 	// Auto-position any saved windows on startup
 	go func() {
 		defer panicHandler()
-		time.Sleep(2 * time.Second) // Give time for other apps to load
-		wm.repositionSavedWindows()
+		wm.waitForStartupApps(ctx) // Give time for other apps to load
+		if wm.getSettings().RepositionMode == repositionOff {
+			log(debug, "Startup repositioning is off.")
+		} else {
+			result := wm.repositionSavedWindows()
+			log(debug, "Startup repositioning:", result)
+			wm.notify(strProductName + " started. " + result.Summary() + ".")
+		}
+		wm.autoMergeDuplicates()
+		wm.autoPrune()
 	}()
 
 	// Run the application (this blocks until app.Quit() is called)
@@ -155,5 +231,6 @@ func main() {
 
 	myApp.Run()
 	log(debug, "Exiting event loop. App closes now.")
+	wm.flushMatchCounts()
 	log(true, "HEARTBEAT: Application shutdown completed at", time.Now().Format("2006-01-02 15:04:05"))
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+	Window matching:
+	- A saved position is keyed by an identifier "Title|ClassName|Executable|Style|ExStyle".
+	- Components that are not part of the identifier composition are stored as the wildcard "*",
+	  so they match any window. This keeps rules working when e.g. the style bits change with an update.
+	- An identifier can end with "|icon:<hash>" to also match the hash of the window's small icon.
+	  This distinguishes windows whose textual attributes are identical (e.g. Electron apps).
+	  Hashing an icon reads its bitmap from the owning process, so it is computed only for windows
+	  that already match the textual components of such a rule, and cached per icon handle.
+	- A title can carry a tag "[wp:<alias>]" the user added, e.g. in a terminal prompt or via
+	  "Tag title…". The title component of such a window is "alias:<alias>", so its rules keep
+	  matching while the rest of the title changes.
+	- The title component can be a glob or a regular expression, see title_pattern.go.
+	- Titles are normalized per app before they are used, see title_normalize.go. Identifiers saved
+	  before a normalization pattern existed still match the raw title.
+*/
+
+const (
+	identifierSeparator = "|" // Separates the identifier components
+	identifierWildcard  = "*" // Component that matches any value
+	identifierParts     = 5   // Number of identifier components
+
+	identifierIconPrefix = "|icon:" // Starts the optional icon hash at the end of an identifier
+)
+
+// IdentifierFields selects the window attributes that compose the matching identifier.
+type IdentifierFields struct {
+	Title      bool `json:"title"`
+	ClassName  bool `json:"className"`
+	Executable bool `json:"executable"`
+	Style      bool `json:"style"`
+	ExStyle    bool `json:"exStyle"`
+}
+
+// allIdentifierFields returns the composition that includes every component.
+func allIdentifierFields() IdentifierFields {
+	return IdentifierFields{Title: true, ClassName: true, Executable: true, Style: true, ExStyle: true}
+}
+
+// enabled returns the fields as a slice in identifier order.
+func (f IdentifierFields) enabled() [identifierParts]bool {
+	return [identifierParts]bool{f.Title, f.ClassName, f.Executable, f.Style, f.ExStyle}
+}
+
+// windowIdentifierParts returns the identifier components of a window.
+// A tagged title is replaced by its alias, other titles are normalized.
+func windowIdentifierParts(window WindowInfo) [identifierParts]string {
+	title := normalizeTitle(window.Title, window.Executable)
+	if alias, ok := titleAlias(window.Title); ok {
+		title = identifierAliasPrefix + alias
+	}
+	return [identifierParts]string{
+		title,
+		window.ClassName,
+		window.Executable,
+		fmt.Sprintf("0x%08X", window.Style),
+		fmt.Sprintf("0x%08X", window.ExStyle),
+	}
+}
+
+// buildIdentifier creates the identifier of a window using the given composition.
+// Components not included in the composition are replaced by the wildcard.
+func buildIdentifier(window WindowInfo, fields IdentifierFields) string {
+	parts := windowIdentifierParts(window)
+	for i, enabled := range fields.enabled() {
+		if !enabled {
+			parts[i] = identifierWildcard
+		}
+	}
+	return joinIdentifier(parts)
+}
+
+// joinIdentifier joins identifier components into an identifier.
+func joinIdentifier(parts [identifierParts]string) string {
+	return strings.Join(parts[:], identifierSeparator)
+}
+
+// splitIconHash separates the optional icon hash from an identifier.
+// It returns the identifier without the icon hash and the hash, which is empty if there is none.
+func splitIconHash(identifier string) (string, string) {
+	index := strings.LastIndex(identifier, identifierIconPrefix)
+	if index < 0 {
+		return identifier, ""
+	}
+	hash := identifier[index+len(identifierIconPrefix):]
+	if hash == "" || strings.Contains(hash, identifierSeparator) {
+		return identifier, "" // Part of a title, not an icon hash
+	}
+	return identifier[:index], hash
+}
+
+// withIconHash returns the identifier with the given icon hash, replacing an existing one.
+// An empty hash removes the icon hash.
+func withIconHash(identifier, hash string) string {
+	base, _ := splitIconHash(identifier)
+	if hash == "" {
+		return base
+	}
+	return base + identifierIconPrefix + hash
+}
+
+// splitIdentifier splits an identifier into its components, ignoring an icon hash.
+// The title may contain the separator itself, so the identifier is split from the right.
+func splitIdentifier(identifier string) ([identifierParts]string, bool) {
+	var parts [identifierParts]string
+	rest, _ := splitIconHash(identifier)
+	for i := identifierParts - 1; i > 0; i-- {
+		index := strings.LastIndex(rest, identifierSeparator)
+		if index < 0 {
+			return parts, false
+		}
+		parts[i] = rest[index+len(identifierSeparator):]
+		rest = rest[:index]
+	}
+	parts[0] = rest
+	return parts, true
+}
+
+// identifierFieldsOf returns which components of an identifier are concrete (not wildcards).
+func identifierFieldsOf(identifier string) IdentifierFields {
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return allIdentifierFields()
+	}
+	return IdentifierFields{
+		Title:      parts[0] != identifierWildcard,
+		ClassName:  parts[1] != identifierWildcard,
+		Executable: parts[2] != identifierWildcard,
+		Style:      parts[3] != identifierWildcard,
+		ExStyle:    parts[4] != identifierWildcard,
+	}
+}
+
+// reduceIdentifier replaces the components not included in the composition by the wildcard.
+// Components that are already wildcards stay wildcards, as the original values are unknown.
+// An icon hash is kept.
+func reduceIdentifier(identifier string, fields IdentifierFields) string {
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return identifier
+	}
+	for i, enabled := range fields.enabled() {
+		if !enabled {
+			parts[i] = identifierWildcard
+		}
+	}
+	_, hash := splitIconHash(identifier)
+	return withIconHash(joinIdentifier(parts), hash)
+}
+
+// identifierMatches checks if a window matches a saved identifier.
+// Wildcard components match any value, the title is a pattern if titlePattern is set. The icon hash
+// is only computed if the textual components match.
+func identifierMatches(identifier string, titlePattern bool, window WindowInfo) bool {
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return false
+	}
+	windowParts := windowIdentifierParts(window)
+	if !titleMatches(parts[0], windowParts[0], titlePattern) && !titleMatches(parts[0], window.Title, titlePattern) {
+		return false
+	}
+	for i, part := range parts[1:] {
+		if part != identifierWildcard && part != windowParts[i+1] {
+			return false
+		}
+	}
+	if _, hash := splitIconHash(identifier); hash != "" {
+		return windowIconHash(window.Handle) == hash
+	}
+	return true
+}
+
+// findSavedPosition returns the saved position matching a window.
+// The rule with the highest priority wins. Among rules with the same priority, identifiers with
+// an icon hash are preferred, then exact identifiers, then identifiers containing wildcards.
+func findSavedPosition(window WindowInfo, positions map[string]WindowPosition) (string, WindowPosition, bool) {
+	best := ""
+	for _, key := range matchingIdentifiers(window, positions) {
+		if best == "" || positions[key].Priority > positions[best].Priority {
+			best = key
+		}
+	}
+	if best == "" {
+		return "", WindowPosition{}, false
+	}
+	return best, positions[best], true
+}
+
+// matchingIdentifiers returns the identifiers of all saved positions matching a window,
+// most specific first: identifiers with an icon hash, the exact identifier, then the others.
+func matchingIdentifiers(window WindowInfo, positions map[string]WindowPosition) []string {
+	// Sort the keys so the result does not depend on the map order,
+	// identifiers with an icon hash first as they are more specific
+	keys := make([]string, 0, len(positions))
+	for key := range positions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		_, iconI := splitIconHash(keys[i])
+		_, iconJ := splitIconHash(keys[j])
+		if (iconI != "") != (iconJ != "") {
+			return iconI != ""
+		}
+		return keys[i] < keys[j]
+	})
+
+	var matches []string
+	exact := buildIdentifier(window, allIdentifierFields())
+	for _, key := range keys {
+		if base, hash := splitIconHash(key); hash != "" && base == exact && identifierMatches(key, positions[key].TitlePattern, window) {
+			matches = append(matches, key)
+		}
+	}
+	if _, ok := positions[exact]; ok {
+		matches = append(matches, exact)
+	}
+	for _, key := range keys {
+		if base, hash := splitIconHash(key); key == exact || hash != "" && base == exact {
+			continue // Already added
+		}
+		if identifierMatches(key, positions[key].TitlePattern, window) {
+			matches = append(matches, key)
+		}
+	}
+	return matches
+}
+
+// findWindowForIdentifier returns the first window matching a saved identifier.
+// The title is a pattern if titlePattern is set, see WindowPosition.TitlePattern.
+func findWindowForIdentifier(identifier string, titlePattern bool, windows []WindowInfo) (WindowInfo, bool) {
+	for _, window := range windows {
+		if identifierMatches(identifier, titlePattern, window) {
+			return window, true
+		}
+	}
+	return WindowInfo{}, false
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+/*
+	Merging duplicates:
+	- Saved positions accumulated over versions can match the same windows, e.g. an identifier saved
+	  before the identifier composition changed and one saved afterwards, or identifiers that only
+	  differ in the case of the executable path.
+	- The effective identifier is the stored identifier, normalized. It is not reduced to the current
+	  identifier composition: two rules that differ in a component the composition no longer uses still
+	  match different windows, merging them would widen the kept rule and lose a position. Rules whose
+	  title is a pattern are only grouped with rules whose title is a pattern as well.
+	- Positions with the same effective identifier are merged into the identifier of the most recently
+	  applied position, keeping that position.
+	- The merges are listed for review, and the positions file is backed up before they are applied.
+*/
+
+// duplicateGroup is a set of saved positions that are merged into one
+type duplicateGroup struct {
+	Identifier string   // Identifier of the merged position, the identifier of the kept position
+	Members    []string // Identifiers that are merged, the kept position first
+}
+
+// effectiveIdentifier normalizes an identifier as stored, so identifiers with the same match predicate
+// compare equal. Paths and style values are compared case-insensitively, the title exactly.
+func effectiveIdentifier(identifier string) string {
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return identifier
+	}
+	for i := 1; i < identifierParts; i++ {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	parts[2] = strings.ToLower(parts[2])
+	parts[3] = strings.ToUpper(parts[3])
+	parts[4] = strings.ToUpper(parts[4])
+	_, hash := splitIconHash(identifier)
+	return withIconHash(joinIdentifier(parts), hash)
+}
+
+// findDuplicates groups the saved positions with the same effective identifier and title matching.
+// Within a group, the most recently applied position comes first.
+func findDuplicates(positions map[string]WindowPosition) []duplicateGroup {
+	type matchKey struct {
+		Effective    string
+		TitlePattern bool
+	}
+	byEffective := make(map[matchKey][]string)
+	for identifier, pos := range positions {
+		key := matchKey{effectiveIdentifier(identifier), pos.TitlePattern}
+		byEffective[key] = append(byEffective[key], identifier)
+	}
+	var groups []duplicateGroup
+	for _, members := range byEffective {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			a, b := positions[members[i]].lastMatched(), positions[members[j]].lastMatched()
+			if !a.Equal(b) {
+				return a.After(b)
+			}
+			return members[i] < members[j]
+		})
+		groups = append(groups, duplicateGroup{Identifier: members[0], Members: members})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Identifier < groups[j].Identifier
+	})
+	return groups
+}
+
+// showMergeDuplicatesDialog lists the duplicate saved positions and merges them if the user confirms.
+// If quiet is true, nothing is shown when there are no duplicates.
+func (wm *WindowManager) showMergeDuplicatesDialog(window fyne.Window, quiet bool) {
+	debug := true
+	positions := wm.storage.GetAllPositions()
+	groups := findDuplicates(positions)
+	log(debug, "Found", len(groups), "groups of duplicate rules.")
+	if len(groups) == 0 {
+		if !quiet {
+			dialog.ShowInformation("Merge duplicate rules", "No saved positions match the same windows.", window)
+		}
+		return
+	}
+
+	const maxListed = 10 // Keep the dialog on the screen
+	var lines []string
+	for i, group := range groups {
+		if i == maxListed {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(groups)-maxListed))
+			break
+		}
+		lines = append(lines, "Keeping "+redactIdentifier(group.Identifier)+", replacing:")
+		for _, member := range group.Members[1:] {
+			lines = append(lines, "    "+redactIdentifier(member))
+		}
+	}
+	message := fmt.Sprintf("These %d groups of rules match the same windows:\n\n%s\n\n"+
+		"Merge each group into one rule with the most recently applied position?\n"+
+		"The positions file is backed up first.", len(groups), strings.Join(lines, "\n"))
+
+	dialog.ShowConfirm("Merge duplicate rules", message, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		backup, err := wm.storage.BackupPositions()
+		if err != nil {
+			log(true, "Failed to back up positions:", err)
+			dialog.ShowError(fmt.Errorf("failed to back up the positions, nothing was merged: %v", err), window)
+			return
+		}
+		log(debug, "Backed up positions to", backup)
+		merged := make(map[string]WindowPosition, len(groups))
+		replaced := make(map[string][]string, len(groups))
+		for _, group := range groups {
+			merged[group.Identifier] = positions[group.Members[0]]
+			replaced[group.Identifier] = group.Members
+		}
+		if err := wm.storage.MergePositions(merged, replaced); err != nil {
+			log(true, "Failed to merge rules:", err)
+			dialog.ShowError(err, window)
+			return
+		}
+		kept := make(map[string]string)
+		for _, group := range groups {
+			for _, member := range group.Members {
+				kept[member] = group.Identifier
+			}
+		}
+		if err := wm.rewriteGroupMembers(func(identifier string) string {
+			if merged, ok := kept[identifier]; ok {
+				return merged
+			}
+			return identifier
+		}); err != nil {
+			dialog.ShowError(fmt.Errorf("the rules were merged, but the groups could not be updated: %v", err), window)
+		}
+		log(true, "Merged", len(groups), "groups of duplicate rules.")
+		wm.setupMainWindowContent() // Refresh the UI
+	}, window)
+}
+
+// autoMergeDuplicates offers to merge duplicate saved positions on startup if enabled.
+func (wm *WindowManager) autoMergeDuplicates() {
+	if !wm.getSettings().MergeDuplicates {
+		return
+	}
+	positions := wm.storage.GetAllPositions()
+	if len(findDuplicates(positions)) == 0 {
+		return
+	}
+	fyne.Do(func() {
+		wm.mainWindow.Show()
+		wm.showMergeDuplicatesDialog(wm.mainWindow, true)
+	})
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Monitor holds information about a display monitor
+type Monitor struct {
+	Index      int    `json:"index"`      // Position in the enumeration order, starting at 0
+	DeviceName string `json:"deviceName"` // Device name, e.g. \\.\DISPLAY1
+	Bounds     RECT   `json:"bounds"`     // Monitor rectangle in virtual screen coordinates
+	WorkArea   RECT   `json:"workArea"`   // Bounds without the taskbar and docked toolbars
+	IsPrimary  bool   `json:"isPrimary"`
+	DPI        uint32 `json:"dpi"`
+}
+
+// MONITORINFOEX is the structure used by GetMonitorInfoW
+type MONITORINFOEX struct {
+	CbSize    uint32
+	RcMonitor RECT
+	RcWork    RECT
+	DwFlags   uint32
+	SzDevice  [32]uint16
+}
+
+// Global callback for EnumDisplayMonitors, created once like the EnumWindows callback
+var globalMonitorCallback uintptr
+var enumeratedMonitors []Monitor
+var monitorMutex sync.Mutex
+
+func init() {
+	globalMonitorCallback = syscall.NewCallback(enumMonitorsCallbackFunc)
+}
+
+// enumMonitorsCallbackFunc is the callback function for EnumDisplayMonitors
+func enumMonitorsCallbackFunc(hMonitor syscall.Handle, hdc syscall.Handle, rect *RECT, lparam uintptr) uintptr {
+	defer func() {
+		if r := recover(); r != nil {
+			log(true, "Panic in monitor enumeration callback:", r)
+		}
+	}()
+
+	var info MONITORINFOEX
+	info.CbSize = uint32(unsafe.Sizeof(info))
+	ret, _, err := procGetMonitorInfo.Call(uintptr(hMonitor), uintptr(unsafe.Pointer(&info)))
+	if ret == 0 {
+		log(true, "GetMonitorInfo failed:", err)
+		return 1 // Continue enumeration
+	}
+	enumeratedMonitors = append(enumeratedMonitors, Monitor{
+		Index:      len(enumeratedMonitors),
+		DeviceName: syscall.UTF16ToString(info.SzDevice[:]),
+		Bounds:     info.RcMonitor,
+		WorkArea:   info.RcWork,
+		IsPrimary:  info.DwFlags&MONITORINFOF_PRIMARY != 0,
+		DPI:        getDpiForRect(info.RcMonitor),
+	})
+	return 1 // Continue enumeration
+}
+
+// GetMonitors returns all display monitors in the enumeration order.
+func GetMonitors() ([]Monitor, error) {
+	monitorMutex.Lock()
+	defer monitorMutex.Unlock()
+
+	enumeratedMonitors = nil
+	ret, _, err := procEnumDisplayMonitors.Call(0, 0, globalMonitorCallback, 0)
+	if ret == 0 {
+		return nil, fmt.Errorf("EnumDisplayMonitors failed: %v", err)
+	}
+	monitors := make([]Monitor, len(enumeratedMonitors))
+	copy(monitors, enumeratedMonitors)
+	return monitors, nil
+}
+
+// Cached monitors, invalidated when the display configuration or a work area changes
+var cachedMonitors []Monitor
+var cachedMonitorsMutex sync.Mutex
+
+// getCachedMonitors returns the monitors from the cache, querying them if the cache is empty.
+// Use it for frequent computations like snapping and clamping.
+func getCachedMonitors() ([]Monitor, error) {
+	cachedMonitorsMutex.Lock()
+	defer cachedMonitorsMutex.Unlock()
+	if cachedMonitors == nil {
+		monitors, err := GetMonitors()
+		if err != nil {
+			return nil, err
+		}
+		cachedMonitors = monitors
+	}
+	return append([]Monitor(nil), cachedMonitors...), nil
+}
+
+// invalidateMonitorCache forgets the cached monitors, so the next access queries them again.
+func invalidateMonitorCache() {
+	cachedMonitorsMutex.Lock()
+	defer cachedMonitorsMutex.Unlock()
+	cachedMonitors = nil
+}
+
+// String returns a short description of the monitor for the UI.
+func (m Monitor) String() string {
+	label := fmt.Sprintf("Monitor %d (%dx%d at %d,%d)", m.Index+1,
+		m.Bounds.Right-m.Bounds.Left, m.Bounds.Bottom-m.Bounds.Top, m.Bounds.Left, m.Bounds.Top)
+	if m.IsPrimary {
+		label += ", primary"
+	}
+	return label
+}
+
+// monitorForRect returns the index of the monitor that shares the largest area with the rectangle.
+// If the rectangle is outside of all monitors, it returns -1.
+func monitorForRect(rect RECT, monitors []Monitor) int {
+	best, bestArea := -1, int64(0)
+	for i, monitor := range monitors {
+		if area := intersectionArea(rect, monitor.Bounds); area > bestArea {
+			best, bestArea = i, area
+		}
+	}
+	return best
+}
+
+// clampTolerance is how far a window may extend beyond the work area when clamping.
+// Windows 10 and later draw invisible resize borders outside the visible frame, so a window
+// placed flush with the screen edge extends a few pixels beyond it.
+const clampTolerance = 16
+
+// clampToMonitors keeps a target rectangle on the monitor that contains most of it.
+// The size is limited to the work area and the rectangle is shifted inside of it.
+// If the rectangle is on no monitor, e.g. because its monitor was unplugged since the position
+// was saved, it is centered on the work area of the primary monitor instead.
+func clampToMonitors(pos WindowPosition, monitors []Monitor) WindowPosition {
+	if len(monitors) == 0 {
+		return pos
+	}
+	index := monitorForRect(pos.Rect(), monitors)
+	if index < 0 {
+		centered := centerOnWorkArea(pos.Width, pos.Height, monitors[primaryMonitor(monitors)].WorkArea)
+		pos.X, pos.Y, pos.Width, pos.Height = centered.X, centered.Y, centered.Width, centered.Height
+		return pos
+	}
+	area := monitors[index].WorkArea
+	left, top := int(area.Left)-clampTolerance, int(area.Top)-clampTolerance
+	right, bottom := int(area.Right)+clampTolerance, int(area.Bottom)+clampTolerance
+	pos.Width = min(pos.Width, right-left)
+	pos.Height = min(pos.Height, bottom-top)
+	pos.X = max(left, min(pos.X, right-pos.Width))
+	pos.Y = max(top, min(pos.Y, bottom-pos.Height))
+	return pos
+}
+
+// anchoredToMonitor records the monitor that contains most of the position, the position
+// relative to its top-left corner and, for physical sizes, the DPI of the monitor.
+// Without a containing monitor, the anchor is removed.
+func (pos WindowPosition) anchoredToMonitor(monitors []Monitor) WindowPosition {
+	pos.MonitorDeviceName, pos.RelativeX, pos.RelativeY, pos.Dpi = "", 0, 0, 0
+	if index := monitorForRect(pos.Rect(), monitors); index >= 0 {
+		monitor := monitors[index]
+		pos.MonitorDeviceName = monitor.DeviceName
+		pos.RelativeX = pos.X - int(monitor.Bounds.Left)
+		pos.RelativeY = pos.Y - int(monitor.Bounds.Top)
+		if !pos.Logical {
+			pos.Dpi = monitor.DPI
+		}
+	}
+	return pos
+}
+
+// placedOnMonitor moves the position relative to the monitor it was saved on.
+// If that monitor is not connected, the absolute coordinates are kept.
+func (pos WindowPosition) placedOnMonitor(monitors []Monitor) WindowPosition {
+	if monitor, ok := findMonitorByDeviceName(pos.MonitorDeviceName, monitors); ok {
+		pos.X = int(monitor.Bounds.Left) + pos.RelativeX
+		pos.Y = int(monitor.Bounds.Top) + pos.RelativeY
+	}
+	return pos
+}
+
+// anchorPosition anchors a position that is about to be saved to the current monitors.
+// If the monitors cannot be enumerated, the position is kept unchanged.
+func anchorPosition(pos WindowPosition) WindowPosition {
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		log(true, "Failed to get monitors, saving the position without monitor:", err)
+		return pos
+	}
+	return pos.anchoredToMonitor(monitors)
+}
+
+// findMonitorByDeviceName returns the monitor with the given device name.
+func findMonitorByDeviceName(deviceName string, monitors []Monitor) (Monitor, bool) {
+	for _, monitor := range monitors {
+		if monitor.DeviceName == deviceName {
+			return monitor, true
+		}
+	}
+	return Monitor{}, false
+}
+
+// primaryMonitor returns the index of the primary monitor, or 0 if none is marked primary.
+// The monitors must not be empty.
+func primaryMonitor(monitors []Monitor) int {
+	for i, monitor := range monitors {
+		if monitor.IsPrimary {
+			return i
+		}
+	}
+	return 0
+}
+
+// intersectionArea returns the area two rectangles share.
+func intersectionArea(a, b RECT) int64 {
+	width := int64(min(a.Right, b.Right)) - int64(max(a.Left, b.Left))
+	height := int64(min(a.Bottom, b.Bottom)) - int64(max(a.Top, b.Top))
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+	return width * height
+}
+
+// translateToMonitor moves a position from the work area of one monitor to the same relative
+// place on the work area of another monitor. Position and size are scaled to the size of the
+// target work area, so layouts like "left half" survive different resolutions.
+func translateToMonitor(pos WindowPosition, from, to Monitor) WindowPosition {
+	scale := func(value, fromOrigin, fromSize, toOrigin, toSize int32) int {
+		if fromSize <= 0 {
+			return int(toOrigin + value - fromOrigin)
+		}
+		return int(toOrigin + int32(int64(value-fromOrigin)*int64(toSize)/int64(fromSize)))
+	}
+	fromWidth, fromHeight := from.WorkArea.Right-from.WorkArea.Left, from.WorkArea.Bottom-from.WorkArea.Top
+	toWidth, toHeight := to.WorkArea.Right-to.WorkArea.Left, to.WorkArea.Bottom-to.WorkArea.Top
+
+	rect := pos.Rect()
+	left := scale(rect.Left, from.WorkArea.Left, fromWidth, to.WorkArea.Left, toWidth)
+	top := scale(rect.Top, from.WorkArea.Top, fromHeight, to.WorkArea.Top, toHeight)
+	right := scale(rect.Right, from.WorkArea.Left, fromWidth, to.WorkArea.Left, toWidth)
+	bottom := scale(rect.Bottom, from.WorkArea.Top, fromHeight, to.WorkArea.Top, toHeight)
+
+	pos.X, pos.Y = left, top
+	pos.Width, pos.Height = right-left, bottom-top
+	return pos
+}
+
+// maximizeOnMonitor maximizes a window on the monitor containing the restore rectangle.
+// SW_MAXIMIZE maximizes a window on the monitor it currently occupies, so the window is
+// restored and moved to the restore rectangle first, then maximized.
+func maximizeOnMonitor(hwnd syscall.Handle, restore WindowPosition, opts moveOptions) error {
+	if !opts.SkipUndo {
+		moveHistory.capture(hwnd) // Before the window is restored, so undo maximizes it again
+	}
+	if isWindowMaximized(hwnd) {
+		procShowWindow.Call(uintptr(hwnd), SW_RESTORE)
+	}
+	if err := moveWindowWithOptions(hwnd, restore.X, restore.Y, restore.Width, restore.Height, opts); err != nil {
+		return err
+	}
+	procShowWindow.Call(uintptr(hwnd), SW_MAXIMIZE)
+	if !isWindowMaximized(hwnd) {
+		return fmt.Errorf("the window did not maximize")
+	}
+	return nil
+}
+
+// isMaximizedOnMonitor checks if a window is maximized on the monitor containing the rectangle.
+func isMaximizedOnMonitor(window WindowInfo, target RECT, monitors []Monitor) bool {
+	if !isWindowMaximized(window.Handle) {
+		return false
+	}
+	// A maximized window's frame extends beyond the monitor, so compare the monitors sharing the largest area
+	return monitorForRect(window.WindowRect, monitors) == monitorForRect(target, monitors)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestClampToMonitors checks that targets are kept on the monitor containing most of them.
+func TestClampToMonitors(t *testing.T) {
+	monitors := []Monitor{
+		{Index: 0, Bounds: RECT{0, 0, 1920, 1080}, WorkArea: RECT{0, 0, 1920, 1040}, IsPrimary: true},
+		{Index: 1, Bounds: RECT{1920, 0, 3840, 1080}, WorkArea: RECT{1920, 0, 3840, 1040}},
+	}
+	tests := []struct {
+		name     string
+		pos      WindowPosition
+		monitors []Monitor
+		want     WindowPosition
+	}{
+		{"inside stays", WindowPosition{X: 100, Y: 100, Width: 800, Height: 600}, monitors,
+			WindowPosition{X: 100, Y: 100, Width: 800, Height: 600}},
+		{"within the tolerance stays", WindowPosition{X: -7, Y: 0, Width: 800, Height: 600}, monitors,
+			WindowPosition{X: -7, Y: 0, Width: 800, Height: 600}},
+		{"below the work area is shifted up", WindowPosition{X: 100, Y: 900, Width: 800, Height: 600}, monitors,
+			WindowPosition{X: 100, Y: 456, Width: 800, Height: 600}},
+		{"beyond the right monitor is shifted left", WindowPosition{X: 3500, Y: 100, Width: 800, Height: 600}, monitors,
+			WindowPosition{X: 3056, Y: 100, Width: 800, Height: 600}},
+		{"too large is shrunk to the work area", WindowPosition{X: 0, Y: 0, Width: 3000, Height: 2000}, monitors,
+			WindowPosition{X: -16, Y: -16, Width: 1952, Height: 1072}},
+		{"on no monitor is centered on the primary", WindowPosition{X: 5000, Y: 5000, Width: 800, Height: 600}, monitors,
+			WindowPosition{X: 560, Y: 220, Width: 800, Height: 600}},
+		{"other fields are kept", WindowPosition{X: 100, Y: 900, Width: 800, Height: 600, State: windowStateMaximized}, monitors,
+			WindowPosition{X: 100, Y: 456, Width: 800, Height: 600, State: windowStateMaximized}},
+		{"without monitors nothing changes", WindowPosition{X: 5000, Y: 5000, Width: 800, Height: 600}, nil,
+			WindowPosition{X: 5000, Y: 5000, Width: 800, Height: 600}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := clampToMonitors(test.pos, test.monitors); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("clampToMonitors(%v) = %v, want %v", test.pos.Rect(), got.Rect(), test.want.Rect())
+			}
+		})
+	}
+}
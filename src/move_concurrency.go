@@ -0,0 +1,64 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+/*
+	Concurrent repositioning:
+	- Moving a window can take a while, strategies wait for the window to react and busy apps
+	  answer messages late, so the windows of a repositioning pass are moved by up to
+	  repositionConcurrency goroutines instead of one after another.
+	- Windows whose order matters are moved one at a time after the others are done:
+	  owned windows after their owners, the primary window last, and all windows if the stacking
+	  order is restored (RestoreBackToFront), because each move brings a window to the top.
+	- AttachThreadInput joins the input state of our thread with the thread of the window, so the
+	  strategies using it run one at a time on a pinned OS thread, see lockThreadInput.
+	- The elevated helper handles one request at a time, moves through it wait for each other.
+*/
+
+// repositionConcurrency is the maximum number of windows moved at the same time
+const repositionConcurrency = 4
+
+// moveConcurrently calls move for each match and returns when all are done.
+// Independent matches run concurrently, the others sequentially in their order afterwards.
+// With keepOrder all matches run sequentially.
+func moveConcurrently(matches []repositionMatch, keepOrder bool, move func(match repositionMatch)) {
+	var sequential []repositionMatch
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, repositionConcurrency)
+	for _, match := range matches {
+		if keepOrder || match.Position.FinishWithFocus || getWindowOwner(match.Window.Handle) != 0 {
+			sequential = append(sequential, match)
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer panicHandler()
+			move(match)
+		}()
+	}
+	wg.Wait()
+	for _, match := range sequential {
+		move(match)
+	}
+}
+
+// threadInputMutex serializes the strategies that attach to the input of a window's thread
+var threadInputMutex sync.Mutex
+
+// lockThreadInput must be held while the input of the current thread is attached to another thread.
+// It also pins the goroutine to its OS thread, so the input is detached from the thread it was
+// attached to. The returned function releases both.
+func lockThreadInput() func() {
+	threadInputMutex.Lock()
+	runtime.LockOSThread()
+	return func() {
+		runtime.UnlockOSThread()
+		threadInputMutex.Unlock()
+	}
+}
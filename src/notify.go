@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+/*
+	Notifications:
+	- Important events are reported as native toast notifications: a profile applied, a window that
+	  could not be moved by any strategy (often an elevated app that needs attention), the startup
+	  pass completed, rescued and restored windows.
+	- Routine work like the monitoring cycles is only logged, so notifications stay rare.
+	- A failing window is reported once per session, the monitoring service would otherwise repeat
+	  the notification every cycle.
+	- "Show notifications" in the Positioning settings suppresses all of them.
+*/
+
+// notifiedOnce holds the keys of the notifications shown once per session
+var notifiedOnce = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+// notify shows a notification unless notifications are turned off.
+// Without the Fyne app, like in the command line mode, the message is only logged.
+func (wm *WindowManager) notify(message string) {
+	logInfo("Notification:", message)
+	if wm.app == nil || !wm.getSettings().ShowNotifications {
+		return
+	}
+	wm.app.SendNotification(fyne.NewNotification(strProductName, message))
+}
+
+// firstInSession reports whether a key is reported the first time in this session.
+func firstInSession(key string) bool {
+	notifiedOnce.Lock()
+	defer notifiedOnce.Unlock()
+	sent := notifiedOnce.keys[key]
+	notifiedOnce.keys[key] = true
+	return !sent
+}
+
+// notifyOnce shows a notification the first time a key is reported in this session.
+func (wm *WindowManager) notifyOnce(key, message string) {
+	if firstInSession(key) {
+		wm.notify(message)
+	}
+}
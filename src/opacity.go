@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Opacity:
+	- A rule with an Opacity between minOpacity and 254 makes its window translucent. Every
+	  repositioning pass reapplies it, as apps may reset their layered attributes.
+	- The window becomes a layered window (WS_EX_LAYERED) if it is not one already, then
+	  SetLayeredWindowAttributes sets the alpha value. 0 (absent) and 255 leave the window alone.
+	- The opacity button in the window list shows a slider that changes the opacity live.
+	  If a saved position matches the window, the value is stored in it when the slider is released.
+	- Values below minOpacity are not offered, a nearly invisible window is hard to get back.
+*/
+
+const (
+	minOpacity  = 26  // About 10%, the lowest opacity offered
+	fullOpacity = 255 // Fully opaque
+)
+
+// getWindowOpacity returns the alpha value of a window, fullOpacity if it is not layered.
+func getWindowOpacity(hwnd syscall.Handle) int {
+	exStyle, err := getWindowLong(hwnd, GWL_EXSTYLE)
+	if err != nil || uint32(exStyle)&WS_EX_LAYERED == 0 {
+		return fullOpacity
+	}
+	var key uint32
+	var alpha byte
+	var flags uint32
+	ret, _, _ := procGetLayeredWindowAttributes.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&key)),
+		uintptr(unsafe.Pointer(&alpha)), uintptr(unsafe.Pointer(&flags)))
+	if ret == 0 || flags&LWA_ALPHA == 0 {
+		return fullOpacity
+	}
+	return int(alpha)
+}
+
+// setWindowOpacity sets the alpha value of a window, making it a layered window if needed.
+// Setting a non-layered window to fullOpacity changes nothing.
+func setWindowOpacity(hwnd syscall.Handle, opacity int) error {
+	opacity = max(minOpacity, min(opacity, fullOpacity))
+	exStyle, err := getWindowLong(hwnd, GWL_EXSTYLE)
+	if err != nil {
+		return err
+	}
+	if uint32(exStyle)&WS_EX_LAYERED == 0 {
+		if opacity == fullOpacity {
+			return nil
+		}
+		if err := applyExStyleFlags(hwnd, WS_EX_LAYERED, WS_EX_LAYERED); err != nil {
+			return err
+		}
+	}
+	ret, _, err := procSetLayeredWindowAttributes.Call(uintptr(hwnd), 0, uintptr(opacity), LWA_ALPHA)
+	if ret == 0 {
+		return fmt.Errorf("SetLayeredWindowAttributes failed: %v", err)
+	}
+	return nil
+}
+
+// reassertOpacity sets the opacity of the window of a match again if its rule has one
+// and the window lost it.
+func (wm *WindowManager) reassertOpacity(match repositionMatch) {
+	opacity := match.Position.Opacity
+	if opacity <= 0 || opacity >= fullOpacity || !isValidWindow(match.Window.Handle) ||
+		getWindowOpacity(match.Window.Handle) == opacity {
+		return
+	}
+	if err := setWindowOpacity(match.Window.Handle, opacity); err != nil {
+		logError("Failed to set the opacity of", redactIdentifier(match.Identifier)+":", err)
+		recordProblem(problemError, redactIdentifier(match.Identifier), "Failed to set the opacity:", err)
+		return
+	}
+	log(true, "Set the opacity of", redactIdentifier(match.Identifier), "to", opacity)
+}
+
+// storeOpacity stores the opacity in the saved position matching a window, if there is one.
+func (wm *WindowManager) storeOpacity(window WindowInfo, opacity int) error {
+	identifier, pos, ok := findSavedPosition(window, wm.storage.GetAllPositions())
+	if !ok {
+		return nil
+	}
+	if opacity >= fullOpacity {
+		opacity = 0 // Absent, the window is left alone
+	}
+	pos.Opacity = opacity
+	if err := wm.storage.SavePosition(identifier, pos); err != nil {
+		return fmt.Errorf("failed to save the opacity: %v", err)
+	}
+	log(true, "Stored opacity", opacity, "in:", redactIdentifier(identifier))
+	return nil
+}
+
+// showOpacitySlider shows a slider below a button that changes the opacity of a window live.
+func (wm *WindowManager) showOpacitySlider(window WindowInfo, button fyne.CanvasObject) {
+	if !isValidWindow(window.Handle) {
+		return
+	}
+	label := widget.NewLabel("")
+	showValue := func(value float64) {
+		label.SetText(fmt.Sprintf("%d%%", int(value)*100/fullOpacity))
+	}
+	slider := widget.NewSlider(minOpacity, fullOpacity)
+	slider.SetValue(float64(getWindowOpacity(window.Handle)))
+	showValue(slider.Value)
+	slider.OnChanged = func(value float64) {
+		showValue(value)
+		if err := setWindowOpacity(window.Handle, int(value)); err != nil {
+			logWarn("Failed to set the opacity:", err)
+		}
+	}
+	slider.OnChangeEnded = func(value float64) {
+		if err := wm.storeOpacity(window, int(value)); err != nil {
+			logError("Failed to store the opacity:", err)
+		}
+	}
+	content := container.NewBorder(nil, nil, widget.NewLabel("Opacity"), label, slider)
+	canvas := fyne.CurrentApp().Driver().CanvasForObject(button)
+	popUp := widget.NewPopUp(content, canvas)
+	popUp.Resize(fyne.NewSize(320, content.MinSize().Height))
+	popUp.ShowAtRelativePosition(fyne.NewPos(0, button.Size().Height), button)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sort"
+	"syscall"
+)
+
+/*
+	Owned windows:
+	- Tool palettes of e.g. graphic editors are top-level windows owned by the main window.
+	- If both an owner and its owned window have saved positions, the owned window keeps its saved
+	  offset to the owner, so palettes land beside the main window even if the main window's target
+	  differs from its saved position (script, maximize on a monitor).
+	- Owners are applied before their owned windows.
+	- Like Alt+Tab, the window list leaves out owned windows and tool windows without a taskbar button,
+	  unless "Include owned windows" and "Include tool windows" are enabled in the Matching settings.
+	  This only affects the window list: saved rules apply to these windows either way.
+*/
+
+// maxOwnerDepth limits following owner chains, which could be cyclic while windows are destroyed
+const maxOwnerDepth = 8
+
+// getWindowOwner returns the owner of a window, 0 if it has none.
+func getWindowOwner(hwnd syscall.Handle) syscall.Handle {
+	owner, _, _ := procGetWindow.Call(uintptr(hwnd), GW_OWNER)
+	return syscall.Handle(owner)
+}
+
+// listedLikeAltTab reports whether the window list shows a window: tool windows and owned windows
+// only if they ask for a taskbar button, or if the settings include them.
+func listedLikeAltTab(window WindowInfo, settings Settings) bool {
+	if window.ExStyle&WS_EX_APPWINDOW != 0 {
+		return true
+	}
+	if window.ExStyle&WS_EX_TOOLWINDOW != 0 && !settings.IncludeToolWindows {
+		return false
+	}
+	return settings.IncludeOwnedWindows || getWindowOwner(window.Handle) == 0
+}
+
+// arrangeOwnedWindows moves the targets of owned windows relative to their owner's target
+// and orders the matches so owners are applied before their owned windows.
+func arrangeOwnedWindows(matches []repositionMatch) []repositionMatch {
+	debug := false
+	byHandle := make(map[syscall.Handle]int, len(matches))
+	for i, match := range matches {
+		byHandle[match.Window.Handle] = i
+	}
+	owners := make(map[int]int) // Index of an owned window -> index of its matched owner
+	for i, match := range matches {
+		if owner := getWindowOwner(match.Window.Handle); owner != 0 {
+			if index, ok := byHandle[owner]; ok && index != i {
+				owners[i] = index
+			}
+		}
+	}
+	if len(owners) == 0 {
+		return matches
+	}
+
+	// depth returns the number of matched owners above a window
+	depth := make([]int, len(matches))
+	for i := range matches {
+		for current, ok := owners[i]; ok && depth[i] < maxOwnerDepth; current, ok = owners[current] {
+			depth[i]++
+		}
+	}
+
+	// Move the owned windows in depth order, so an owner's target is final before it is used
+	order := make([]int, len(matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return depth[order[a]] < depth[order[b]]
+	})
+	for _, i := range order {
+		ownerIndex, ok := owners[i]
+		if !ok || matches[i].Position.MaximizeMonitor != "" {
+			continue
+		}
+		owner := matches[ownerIndex]
+		saved := owner.Position.PhysicalFor(owner.Window.Handle)
+		matches[i].Target.X += owner.Target.X - saved.X
+		matches[i].Target.Y += owner.Target.Y - saved.Y
+		log(debug, "Positioning owned window relative to its owner:", redactIdentifier(matches[i].Identifier))
+	}
+
+	ordered := make([]repositionMatch, len(matches))
+	for position, i := range order {
+		ordered[position] = matches[i]
+	}
+	return ordered
+}
@@ -25,19 +25,20 @@ import (
 // It logs the panic reason and stack trace to the log file.
 func panicHandler() {
 	if r := recover(); r != nil {
+		recordProblem(problemError, "", "Application panic:", r)
 		// Safely show window and dialog only if wm and mainWindow are available
 		if wm != nil && wm.mainWindow != nil {
 			wm.mainWindow.Show()
 			dialog.ShowError(fmt.Errorf("application crashed: %v", r), wm.mainWindow)
 		}
-		if fileLog != nil {
-			// Write to log file if it is ready
-			log(true, "HEARTBEAT: CRITICAL - Application panic detected!")
-			log(true, "==== PANIC ====")
-			log(true, fmt.Sprintf("Time  : %s", time.Now().Format("2006-01-02 15:04:05")))
-			log(true, fmt.Sprintf("Reason: %v", r))
-			log(true, string(debug.Stack()))
-			log(true, "==== END PANIC ====")
+		if fileLog != nil || logMemoryOnly || !logConfigured {
+			// Write to log file if it is ready, or to memory if the file is not used (yet)
+			logError("HEARTBEAT: CRITICAL - Application panic detected!")
+			logError("==== PANIC ====")
+			logError(fmt.Sprintf("Time  : %s", time.Now().Format("2006-01-02 15:04:05")))
+			logError(fmt.Sprintf("Reason: %v", r))
+			logError(string(debug.Stack()))
+			logError("==== END PANIC ====")
 		} else {
 			// Append to the log file if it is not ready
 			f, err := os.OpenFile(strLogFilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
@@ -3,9 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/sys/windows/registry"
 )
@@ -14,28 +18,70 @@ import (
 // It uses a JSON file to save and load positions, and can also interact with the Windows registry for startup settings.
 type PositionStorage struct {
 	//registryPath string
-	storageFile string
-	mu          sync.Mutex
+	storageFile  string
+	settingsFile string
+	statsFile    string
+	stagingFile  string
+	mu           sync.Mutex
 }
 
 // NewPositionStorage initializes a new PositionStorage instance.
-// It creates the necessary directory for storing positions and initializes the storage file.
-func NewPositionStorage() *PositionStorage {
+// It uses the first writable directory of APPDATA, LOCALAPPDATA, the user configuration directory
+// and TEMP. If none is writable, the storage uses the preferred directory, so existing files can
+// still be read, and an error is returned that the UI reports, as saving will fail.
+func NewPositionStorage() (*PositionStorage, error) {
 	debug := true
-	appData := os.Getenv("APPDATA")
-	if appData == "" {
-		appData = os.Getenv("TEMP")
-		if appData == "" {
-			appData = "."
+	bases := storageBaseDirs()
+	for _, base := range bases {
+		dirPath := filepath.Join(base, strPublisherName, strProductName)
+		if err := checkWritableDir(dirPath); err != nil {
+			log(true, "Storage directory is not writable:", dirPath, err)
+			continue
 		}
+		log(debug, "PositionStorage is using directory:", dirPath)
+		return newPositionStorageIn(dirPath), nil
 	}
-	dirPath := filepath.Join(appData, strPublisherName, strProductName)
-	log(debug, "PositionStorage is using directory:", dirPath)
-	_ = os.MkdirAll(dirPath, 0o755)
+	dirPath := filepath.Join(append(bases, ".")[0], strPublisherName, strProductName)
+	return newPositionStorageIn(dirPath), fmt.Errorf("no writable directory for the settings and positions, tried %s", strings.Join(bases, ", "))
+}
+
+// storageBaseDirs returns the directories to store the files in, the most preferred first.
+func storageBaseDirs() []string {
+	var bases []string
+	add := func(dir string) {
+		if dir != "" && !slices.Contains(bases, dir) {
+			bases = append(bases, dir)
+		}
+	}
+	add(os.Getenv("APPDATA"))
+	add(os.Getenv("LOCALAPPDATA"))
+	if dir, err := os.UserConfigDir(); err == nil {
+		add(dir)
+	}
+	add(os.Getenv("TEMP"))
+	return bases
+}
 
+// checkWritableDir creates a directory and checks that files can be written to it.
+func checkWritableDir(dirPath string) error {
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return err
+	}
+	probe := filepath.Join(dirPath, ".write-test")
+	if err := os.WriteFile(probe, nil, 0o644); err != nil {
+		return err
+	}
+	return os.Remove(probe)
+}
+
+// newPositionStorageIn creates a PositionStorage with its files in a directory.
+func newPositionStorageIn(dirPath string) *PositionStorage {
 	return &PositionStorage{
 		//registryPath: `Software\` + strPublisherName + `\` + strProductName,
-		storageFile: filepath.Join(dirPath, "positions.json"),
+		storageFile:  filepath.Join(dirPath, "positions.json"),
+		settingsFile: filepath.Join(dirPath, "settings.json"),
+		statsFile:    filepath.Join(dirPath, "strategy_stats.json"),
+		stagingFile:  filepath.Join(dirPath, "staging.json"),
 	}
 }
 
@@ -44,12 +90,21 @@ func NewPositionStorage() *PositionStorage {
 // style, and extended style.
 // It serializes the position to a JSON file.
 func (ps *PositionStorage) SavePosition(identifier string, pos WindowPosition) error {
-	positions, err := ps.loadAll()
-	if err != nil {
-		return fmt.Errorf("failed to load positions: %v", err)
-	}
-	positions[identifier] = pos
-	return ps.saveAll(positions)
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		positions[identifier] = anchorPosition(pos)
+		return true
+	})
+}
+
+// SavePositions saves several positions with a single write.
+// Existing positions with the same identifiers are overwritten.
+func (ps *PositionStorage) SavePositions(imported map[string]WindowPosition) error {
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		for identifier, pos := range imported {
+			positions[identifier] = anchorPosition(pos)
+		}
+		return true
+	})
 }
 
 // LoadPosition retrieves the position of a window by its identifier.
@@ -66,15 +121,108 @@ func (ps *PositionStorage) LoadPosition(identifier string) (*WindowPosition, err
 	return &pos, nil
 }
 
+// ReplacePosition stores a position under a new identifier and removes the old identifier.
+// It is used when a rule's matching identifier changes.
+func (ps *PositionStorage) ReplacePosition(oldIdentifier, newIdentifier string, pos WindowPosition) error {
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		delete(positions, oldIdentifier)
+		positions[newIdentifier] = anchorPosition(pos)
+		return true
+	})
+}
+
+// UpdatePositions modifies several saved positions with a single write.
+// Identifiers that are not saved are skipped, the file is not written if none is saved.
+func (ps *PositionStorage) UpdatePositions(identifiers []string, update func(identifier string, pos *WindowPosition)) error {
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		changed := false
+		for _, identifier := range identifiers {
+			pos, ok := positions[identifier]
+			if !ok {
+				continue
+			}
+			update(identifier, &pos)
+			positions[identifier] = pos
+			changed = true
+		}
+		return changed
+	})
+}
+
 // DeletePosition removes a window's position from storage by its identifier.
 // It updates the JSON file to reflect the deletion.
 func (ps *PositionStorage) DeletePosition(identifier string) error {
-	positions, err := ps.loadAll()
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		_, ok := positions[identifier]
+		delete(positions, identifier)
+		return ok
+	})
+}
+
+// DeletePositions removes several saved positions with a single write.
+func (ps *PositionStorage) DeletePositions(identifiers []string) error {
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		count := len(positions)
+		for _, identifier := range identifiers {
+			delete(positions, identifier)
+		}
+		return len(positions) != count
+	})
+}
+
+// MergePositions replaces groups of saved positions by a single position each, with a single write.
+// The merged map holds the new position per identifier and the identifiers it replaces.
+func (ps *PositionStorage) MergePositions(merged map[string]WindowPosition, replaced map[string][]string) error {
+	return ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		for identifier, pos := range merged {
+			for _, old := range replaced[identifier] {
+				delete(positions, old)
+			}
+			positions[identifier] = pos
+		}
+		return true
+	})
+}
+
+// BackupPositions copies the positions file next to it with a timestamp and returns the backup path.
+func (ps *PositionStorage) BackupPositions() (string, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	data, err := os.ReadFile(ps.storageFile)
 	if err != nil {
-		return fmt.Errorf("failed to load positions: %v", err)
+		return "", err
+	}
+	backup := ps.storageFile + "." + time.Now().Format("20060102-150405") + ".bak"
+	if err := os.WriteFile(backup, data, 0o644); err != nil {
+		return "", err
 	}
-	delete(positions, identifier)
-	return ps.saveAll(positions)
+	return backup, nil
+}
+
+// RewriteIdentifiers changes the identifiers of all saved positions using the rewrite function.
+// It is used to migrate the identifiers when the identifier composition changes.
+// If two identifiers collapse into the same new identifier, the last one wins.
+// It returns the number of changed identifiers.
+func (ps *PositionStorage) RewriteIdentifiers(rewrite func(identifier string) string) (int, error) {
+	changed := 0
+	err := ps.updatePositions(func(positions map[string]WindowPosition) bool {
+		rewritten := make(map[string]WindowPosition, len(positions))
+		for identifier, pos := range positions {
+			newIdentifier := rewrite(identifier)
+			if newIdentifier != identifier {
+				changed++
+			}
+			if _, exists := rewritten[newIdentifier]; exists {
+				log(true, "Identifier collision while rewriting, overwriting:", redactIdentifier(newIdentifier))
+			}
+			rewritten[newIdentifier] = pos
+		}
+		clear(positions)
+		maps.Copy(positions, rewritten)
+		return changed > 0
+	})
+	return changed, err
 }
 
 // GetAllPositions retrieves all saved window positions.
@@ -87,44 +235,255 @@ func (ps *PositionStorage) GetAllPositions() map[string]WindowPosition {
 	return positions
 }
 
-// loadAll reads all positions from the JSON file and returns them as a map.
+// loadAll reads the positions of the active profile and returns them as a map.
 // If the file does not exist, it returns an empty map.
 func (ps *PositionStorage) loadAll() (map[string]WindowPosition, error) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	positions := make(map[string]WindowPosition)
+	doc, err := ps.readDocument()
+	if err != nil {
+		return nil, err
+	}
+	return doc.Profiles[doc.ActiveProfile], nil
+}
+
+// updatePositions modifies the positions of the active profile with a single read and write,
+// holding the mutex in between, so concurrent changes are not lost. The file is only written
+// if the update reports a change. Other profiles in the file are kept.
+func (ps *PositionStorage) updatePositions(update func(positions map[string]WindowPosition) bool) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return fmt.Errorf("failed to load positions: %v", err)
+	}
+	if !update(doc.Profiles[doc.ActiveProfile]) {
+		return nil
+	}
+	return ps.writeDocument(doc)
+}
 
+// readDocument reads the positions file. Files of older versions are migrated to the current
+// version and written back in place, the original file is kept as a backup.
+// It must be called with the mutex held.
+func (ps *PositionStorage) readDocument() (*positionsDocument, error) {
 	data, err := os.ReadFile(ps.storageFile)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return positions, nil
+			return newPositionsDocument(), nil
 		}
 		return nil, err
 	}
 
-	if err := json.Unmarshal(data, &positions); err != nil {
+	doc, version, err := migratePositions(data)
+	if err != nil {
 		return nil, err
 	}
-	return positions, nil
+	if version < positionsVersion {
+		backup := fmt.Sprintf("%s.v%d.bak", ps.storageFile, version)
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			if err := os.WriteFile(backup, data, 0o644); err != nil {
+				log(true, "Failed to back up the positions file before migrating it:", err)
+			}
+		}
+		if err := ps.writeDocument(doc); err != nil {
+			log(true, "Failed to write the migrated positions file, it is migrated again on the next read:", err)
+		} else {
+			logInfo("Migrated the positions file from version", version, "to", positionsVersion)
+		}
+	}
+	return doc, nil
+}
+
+// migratePositions converts the content of a positions file of any supported version into the
+// current document and returns the version the content had. Version 1 files hold a bare map of
+// positions, which become the default profile.
+func migratePositions(data []byte) (*positionsDocument, int, error) {
+	doc := newPositionsDocument()
+	var probe struct {
+		Version int `json:"version"`
+	}
+	version := 1
+	if err := json.Unmarshal(data, &probe); err == nil && probe.Version > 0 {
+		version = probe.Version
+	}
+	switch {
+	case version > positionsVersion:
+		return nil, version, fmt.Errorf("positions file version %d is newer than the supported version %d", version, positionsVersion)
+	case version == 1:
+		positions := make(map[string]WindowPosition)
+		if err := json.Unmarshal(data, &positions); err != nil {
+			return nil, version, err
+		}
+		doc.Profiles[defaultProfile] = positions
+	default:
+		if err := json.Unmarshal(data, doc); err != nil {
+			return nil, version, err
+		}
+	}
+
+	if doc.Profiles == nil {
+		doc.Profiles = make(map[string]map[string]WindowPosition)
+	}
+	if doc.ActiveProfile == "" {
+		doc.ActiveProfile = defaultProfile
+	}
+	if doc.Profiles[doc.ActiveProfile] == nil {
+		doc.Profiles[doc.ActiveProfile] = make(map[string]WindowPosition)
+	}
+	doc.Version = positionsVersion
+	return doc, version, nil
+}
+
+// writeDocument writes the positions file.
+// It must be called with the mutex held.
+func (ps *PositionStorage) writeDocument(doc *positionsDocument) error {
+	doc.Version = positionsVersion
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(ps.storageFile, data)
+}
+
+// writeFileAtomic replaces a file by writing a temporary file next to it, flushing it to disk and
+// renaming it over the file, so a crash never leaves a half-written file. Some network shares
+// and file systems refuse the rename, the file is overwritten in place then.
+func writeFileAtomic(path string, data []byte) error {
+	tmpFile := path + ".tmp"
+	if err := writeFileSynced(tmpFile, data); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	err := os.Rename(tmpFile, path)
+	if err == nil {
+		return nil
+	}
+	log(true, "Failed to replace", path, "by renaming, overwriting it instead:", err)
+	defer os.Remove(tmpFile)
+	if err := writeFileSynced(path, data); err != nil {
+		return fmt.Errorf("failed to save %s: %v", filepath.Base(path), err)
+	}
+	return nil
 }
 
-// saveAll writes all positions to the JSON file.
-// It serializes the map of positions to JSON and saves it to the storage file.
-func (ps *PositionStorage) saveAll(positions map[string]WindowPosition) error {
+// writeFileSynced writes data to a file and flushes it to disk before closing it.
+func writeFileSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadSettings reads the settings from the settings file.
+// Settings missing in the file keep their default values. If the file does not exist, the defaults are returned.
+func (ps *PositionStorage) LoadSettings() (Settings, error) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
 
-	data, err := json.MarshalIndent(positions, "", "  ")
+	settings := defaultSettings()
+	data, err := os.ReadFile(ps.settingsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return settings, err
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return defaultSettings(), fmt.Errorf("failed to parse settings: %v", err)
+	}
+	return settings.clone(), nil
+}
+
+// SaveSettings writes the settings to the settings file.
+func (ps *PositionStorage) SaveSettings(settings Settings) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	tmpFile := ps.storageFile + ".tmp"
-	if err := os.WriteFile(tmpFile, data, 0o644); err != nil {
+	return writeFileAtomic(ps.settingsFile, data)
+}
+
+// LoadStrategyStats reads the recorded move strategy outcomes.
+// If the file does not exist, empty statistics are returned.
+func (ps *PositionStorage) LoadStrategyStats() (StrategyStats, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	stats := make(StrategyStats)
+	data, err := os.ReadFile(ps.statsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return stats, err
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return make(StrategyStats), fmt.Errorf("failed to parse strategy statistics: %v", err)
+	}
+	return stats, nil
+}
+
+// SaveStrategyStats writes the recorded move strategy outcomes.
+func (ps *PositionStorage) SaveStrategyStats(stats StrategyStats) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(ps.statsFile, data)
+}
+
+// LoadStaging reads the staged positions, which are not applied until they are promoted.
+// If the file does not exist, an empty map is returned.
+func (ps *PositionStorage) LoadStaging() (map[string]WindowPosition, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	staged := make(map[string]WindowPosition)
+	data, err := os.ReadFile(ps.stagingFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return staged, nil
+		}
+		return staged, err
+	}
+	if err := json.Unmarshal(data, &staged); err != nil {
+		return make(map[string]WindowPosition), fmt.Errorf("failed to parse staged positions: %v", err)
+	}
+	return staged, nil
+}
+
+// SaveStaging writes the staged positions.
+func (ps *PositionStorage) SaveStaging(staged map[string]WindowPosition) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	data, err := json.MarshalIndent(staged, "", "  ")
+	if err != nil {
 		return err
 	}
-	return os.Rename(tmpFile, ps.storageFile)
+
+	return writeFileAtomic(ps.stagingFile, data)
 }
 
 // EnableStartup adds the application to the Windows startup registry key.
@@ -163,17 +522,38 @@ func DisableStartup() error {
 	return key.DeleteValue(appName)
 }
 
-// IsStartupEnabled checks if the application is set to start with Windows.
-func IsStartupEnabled() bool {
+// StartupState tells if and how the application is registered to start with Windows
+type StartupState int
+
+const (
+	StartupDisabled StartupState = iota // Not registered
+	StartupCurrent                      // Registered with the path of the running executable
+	StartupStale                        // Registered with another path, e.g. the executable was moved
+)
+
+// IsStartupEnabled checks if the application is set to start with Windows
+// and if the registered path is the running executable.
+func IsStartupEnabled() StartupState {
 	key, err := registry.OpenKey(registry.CURRENT_USER,
 		`Software\Microsoft\Windows\CurrentVersion\Run`,
 		registry.READ)
 	if err != nil {
-		return false
+		return StartupDisabled
 	}
 	defer key.Close()
 
 	appName := strProductName
-	_, _, err = key.GetStringValue(appName)
-	return err == nil
+	value, _, err := key.GetStringValue(appName)
+	if err != nil {
+		return StartupDisabled
+	}
+	exePath, err := os.Executable()
+	if err != nil {
+		return StartupCurrent // Cannot tell, assume the registration is fine
+	}
+	registered := filepath.Clean(strings.Trim(strings.TrimSpace(value), `"`))
+	if !strings.EqualFold(registered, filepath.Clean(exePath)) {
+		return StartupStale
+	}
+	return StartupCurrent
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fillFields sets every field of a value to a non-zero value, so a round trip that drops a field fails.
+func fillFields(t *testing.T, v reflect.Value) {
+	t.Helper()
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Date(2024, 5, 17, 8, 30, 0, 0, time.UTC)))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			fillFields(t, v.Field(i))
+		}
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillFields(t, v.Elem())
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fillFields(t, v.Index(0))
+	case reflect.String:
+		v.SetString("value-" + v.Type().Name())
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		v.SetInt(42)
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		v.SetUint(42)
+	default:
+		t.Fatalf("fillFields does not support %s, extend it for the new field", v.Type())
+	}
+}
+
+// TestMigratePositionsV1 checks that a version 1 positions file, a bare map of positions,
+// is migrated into the default profile without losing data, backed up and written back once.
+func TestMigratePositionsV1(t *testing.T) {
+	var pos WindowPosition
+	fillFields(t, reflect.ValueOf(&pos).Elem())
+	v1 := map[string]WindowPosition{
+		"Document - Editor|EditorClass|C:\\Apps\\editor.exe|0x14CF0000|0x00000100": pos,
+		"*|ConsoleWindowClass|*|*|*": {X: -8, Y: 0, Width: 960, Height: 1040},
+	}
+	data, err := json.Marshal(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, version, err := migratePositions(data)
+	if err != nil {
+		t.Fatalf("migratePositions: %v", err)
+	}
+	if version != 1 || doc.Version != positionsVersion || doc.ActiveProfile != defaultProfile {
+		t.Fatalf("got version %d, document version %d, active profile %q", version, doc.Version, doc.ActiveProfile)
+	}
+	if !reflect.DeepEqual(doc.Profiles[defaultProfile], v1) {
+		t.Fatalf("migrated positions differ:\n got %+v\nwant %+v", doc.Profiles[defaultProfile], v1)
+	}
+
+	dir := t.TempDir()
+	ps := newPositionStorageIn(dir)
+	if err := os.WriteFile(ps.storageFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	read := func() *positionsDocument {
+		t.Helper()
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		doc, err := ps.readDocument()
+		if err != nil {
+			t.Fatalf("readDocument: %v", err)
+		}
+		return doc
+	}
+
+	doc = read()
+	if doc.Version != positionsVersion || !reflect.DeepEqual(doc.Profiles[defaultProfile], v1) {
+		t.Fatalf("read document differs: version %d, positions %+v", doc.Version, doc.Profiles[defaultProfile])
+	}
+	backup, err := os.ReadFile(filepath.Join(dir, "positions.json.v1.bak"))
+	if err != nil {
+		t.Fatalf("backup not written: %v", err)
+	}
+	if string(backup) != string(data) {
+		t.Fatal("backup differs from the original file")
+	}
+	migrated, err := os.ReadFile(ps.storageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var onDisk positionsDocument
+	if err := json.Unmarshal(migrated, &onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.Version != positionsVersion || !reflect.DeepEqual(onDisk.Profiles[defaultProfile], v1) {
+		t.Fatalf("migrated file differs: version %d, positions %+v", onDisk.Version, onDisk.Profiles[defaultProfile])
+	}
+
+	// A second load finds the current version and leaves the file alone
+	info, err := os.Stat(ps.storageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc = read()
+	if !reflect.DeepEqual(doc.Profiles[defaultProfile], v1) {
+		t.Fatal("second read differs")
+	}
+	again, err := os.ReadFile(ps.storageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoAgain, err := os.Stat(ps.storageFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(migrated) || !infoAgain.ModTime().Equal(info.ModTime()) {
+		t.Fatal("second read rewrote the positions file")
+	}
+}
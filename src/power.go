@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+)
+
+/*
+	Power profiles:
+	- Switches to a chosen profile when the power source changes between battery and AC.
+	- Windows broadcasts WM_POWERBROADCAST with PBT_APMPOWERSTATUSCHANGE to the system event window,
+	  also when only the battery level changes, so the power source is compared to the last known one.
+	- A plugged-in cable can bounce, so the switch is debounced.
+*/
+
+const powerDebounce = 5 * time.Second // Time the power source must stay unchanged before switching
+
+// SYSTEM_POWER_STATUS contains the power status returned by GetSystemPowerStatus
+type SYSTEM_POWER_STATUS struct {
+	ACLineStatus        byte // 0 = offline (battery), 1 = online (AC), 255 = unknown
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// powerSource is the source the system is powered from
+type powerSource int
+
+const (
+	powerUnknown powerSource = iota
+	powerBattery
+	powerAC
+)
+
+// String returns the name of the power source.
+func (p powerSource) String() string {
+	switch p {
+	case powerBattery:
+		return "battery"
+	case powerAC:
+		return "AC"
+	}
+	return "unknown"
+}
+
+// getPowerSource returns the source the system is currently powered from.
+func getPowerSource() (powerSource, error) {
+	var status SYSTEM_POWER_STATUS
+	ret, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status)))
+	if ret == 0 {
+		return powerUnknown, fmt.Errorf("GetSystemPowerStatus failed: %v", err)
+	}
+	switch status.ACLineStatus {
+	case 0:
+		return powerBattery, nil
+	case 1:
+		return powerAC, nil
+	}
+	return powerUnknown, nil
+}
+
+// powerWatcher switches profiles when the power source changes.
+type powerWatcher struct {
+	mutex  sync.Mutex
+	source powerSource // Last known power source
+	timer  *time.Timer // Pending debounced switch
+}
+
+// registerPowerEventHandler connects the power broadcasts to the profile switching.
+func (wm *WindowManager) registerPowerEventHandler() {
+	debug := true
+	source, err := getPowerSource()
+	if err != nil {
+		log(true, "Failed to get the power source:", err)
+	}
+	wm.power.source = source
+	log(debug, "Power source on startup:", source)
+
+	systemEvents.Handle(WM_POWERBROADCAST, func(wParam, lParam uintptr) {
+		if wParam != PBT_APMPOWERSTATUSCHANGE {
+			return
+		}
+		// Runs on the message loop thread, so the switch is done by the timer
+		wm.power.mutex.Lock()
+		defer wm.power.mutex.Unlock()
+		if wm.power.timer != nil {
+			wm.power.timer.Stop()
+		}
+		wm.power.timer = time.AfterFunc(powerDebounce, wm.onPowerSourceSettled)
+	})
+}
+
+// onPowerSourceSettled switches to the profile of the power source if it changed.
+func (wm *WindowManager) onPowerSourceSettled() {
+	defer panicHandler()
+	debug := true
+
+	source, err := getPowerSource()
+	if err != nil {
+		log(true, "Failed to get the power source:", err)
+		return
+	}
+	wm.power.mutex.Lock()
+	changed := source != wm.power.source
+	wm.power.source = source
+	wm.power.mutex.Unlock()
+	if !changed || source == powerUnknown {
+		return
+	}
+	log(debug, "Power source changed to", source)
+
+	settings := wm.getSettings()
+	profile := settings.ACProfile
+	if source == powerBattery {
+		profile = settings.BatteryProfile
+	}
+	if profile == "" || profile == wm.storage.ActiveProfile() {
+		return
+	}
+	if err := wm.storage.SetActiveProfile(profile); err != nil {
+		log(true, "Failed to switch to the", source, "profile:", err)
+		return
+	}
+	log(true, "Switched to profile", profile, "for power source", source)
+	fyne.Do(func() {
+		wm.setupMainWindowContent() // Refresh the UI
+	})
+	message := fmt.Sprintf("Switched to profile %s (%s)", profile, source)
+	if !wm.isSnoozed() {
+		message += ": " + wm.repositionSavedWindows().Summary()
+	}
+	wm.notify(message)
+}
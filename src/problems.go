@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Problems:
+	- Warnings and errors are also kept in a short list, so the user sees what went wrong
+	  without scrolling through the verbose log.
+	- A problem that occurs again (e.g. a rule failing every monitoring cycle) is not added twice,
+	  it moves to the end of the list with an increased count.
+	- The list is kept in memory only and can be cleared in the log window.
+*/
+
+// maxProblems is the number of problems kept in memory
+const maxProblems = 200
+
+// Problem levels
+const (
+	problemWarning = "WARN"
+	problemError   = "ERROR"
+)
+
+// problem is a warning or error shown in the problems list
+type problem struct {
+	Time    time.Time // Last occurrence
+	Level   string    // problemWarning or problemError
+	Subject string    // Related rule or window, empty if none
+	Message string
+	Count   int // Number of occurrences
+}
+
+// String returns the problem as a line of the problems list.
+func (p problem) String() string {
+	var builder strings.Builder
+	builder.WriteString(p.Time.Format("2006-01-02 15:04:05"))
+	builder.WriteString(" " + p.Level)
+	if p.Subject != "" {
+		builder.WriteString(" [" + p.Subject + "]")
+	}
+	builder.WriteString(" " + p.Message)
+	if p.Count > 1 {
+		builder.WriteString(fmt.Sprintf(" (%d times)", p.Count))
+	}
+	return builder.String()
+}
+
+var problems []problem       // Problems, oldest first
+var problemsMutex sync.Mutex // Mutex to protect the problems
+
+// recordProblem adds a warning or error to the problems list.
+// The message parts are joined like the parts of a log message.
+func recordProblem(level, subject string, messageParts ...any) {
+	parts := make([]string, len(messageParts))
+	for i, v := range messageParts {
+		parts[i] = fmt.Sprint(v)
+	}
+	entry := problem{Time: time.Now(), Level: level, Subject: subject, Message: strings.Join(parts, " "), Count: 1}
+
+	problemsMutex.Lock()
+	defer problemsMutex.Unlock()
+	for i, existing := range problems {
+		if existing.Level == entry.Level && existing.Subject == entry.Subject && existing.Message == entry.Message {
+			entry.Count += existing.Count
+			problems = append(problems[:i], problems[i+1:]...)
+			break
+		}
+	}
+	if len(problems) >= maxProblems {
+		problems = problems[1:]
+	}
+	problems = append(problems, entry)
+}
+
+// problemsSnapshot returns the problems, newest first.
+func problemsSnapshot() []problem {
+	problemsMutex.Lock()
+	defer problemsMutex.Unlock()
+	snapshot := make([]problem, len(problems))
+	for i, entry := range problems {
+		snapshot[len(problems)-1-i] = entry
+	}
+	return snapshot
+}
+
+// clearProblems removes all problems.
+func clearProblems() {
+	problemsMutex.Lock()
+	defer problemsMutex.Unlock()
+	problems = nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Profile preview:
+	- Before a profile is applied from the menu or the tray, the windows it would move are listed
+	  with their current and target rectangles, and the profile is only applied if confirmed.
+	- The preview computes the targets like a repositioning pass, but does not move anything.
+	  Targets of script rules are not computed, and rules that depend on the pass itself, like
+	  "only apply to new windows", are listed as if they applied.
+	- Profile switches by the power source, the command line or the control pipe are not previewed.
+*/
+
+// plannedMove is a window that a profile would move
+type plannedMove struct {
+	Identifier string
+	Handle     syscall.Handle
+	Title      string
+	Current    RECT
+	Target     RECT
+	Note       string // Why the target is not exact, e.g. computed by the script hook
+}
+
+// String returns the move as "Title: 100,100 800x600 → 800,200 800x600".
+// If the target is not a plain rectangle, the note is shown instead.
+func (move plannedMove) String() string {
+	rect := func(r RECT) string {
+		return fmt.Sprintf("%d,%d %dx%d", r.Left, r.Top, r.Right-r.Left, r.Bottom-r.Top)
+	}
+	target := rect(move.Target)
+	if move.Note != "" {
+		target = move.Note
+	}
+	return fmt.Sprintf("%s: %s → %s", move.Title, rect(move.Current), target)
+}
+
+// previewProfile returns the windows that applying a profile would move, without moving them.
+func (wm *WindowManager) previewProfile(name string) ([]plannedMove, error) {
+	positions, err := wm.storage.LoadProfilePositions(name)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return nil, err
+	}
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		return nil, err
+	}
+	settings := wm.getSettings()
+	positions = activeRules(positions, time.Now())
+
+	var moves []plannedMove
+	for _, window := range withoutExcluded(windows, settings) {
+		identifier, pos, ok := findSavedPosition(window, positions)
+		if !ok {
+			continue
+		}
+		move := plannedMove{Identifier: identifier, Handle: window.Handle, Title: window.Title, Current: window.WindowRect}
+		if pos.UseScript {
+			move.Note = "computed by the script hook"
+			moves = append(moves, move)
+			continue
+		}
+		target := pos.PhysicalFor(window.Handle)
+		target, err := pos.onMaximizeMonitor(target, monitors)
+		if err != nil {
+			continue // Skipped by the pass, too
+		}
+		move.Target = clampToMonitors(target, monitors).Rect()
+		switch {
+		case pos.maximizes():
+			if isMaximizedOnMonitor(window, move.Target, monitors) {
+				continue
+			}
+			move.Note = "maximized"
+		case pos.State == windowStateMinimized:
+			if isWindowMinimized(window.Handle) {
+				continue
+			}
+			move.Note = "minimized"
+		case move.Current == move.Target:
+			continue
+		}
+		moves = append(moves, move)
+	}
+	sort.SliceStable(moves, func(i, j int) bool {
+		return strings.ToLower(moves[i].Title) < strings.ToLower(moves[j].Title)
+	})
+	return moves, nil
+}
+
+// requestProfile applies a profile chosen by the user. If previews are enabled, the planned
+// moves are shown first and the profile is only applied if the user confirms them.
+func (wm *WindowManager) requestProfile(name string) {
+	if !wm.getSettings().PreviewProfiles {
+		go wm.runApplyProfile(name)
+		return
+	}
+	go func() {
+		defer panicHandler()
+		moves, err := wm.previewProfile(name)
+		fyne.Do(func() {
+			if err != nil {
+				logWarn("Failed to preview profile", name+":", err)
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			wm.showProfilePreview(name, moves)
+		})
+	}()
+}
+
+// showProfilePreview lists the planned moves of a profile and applies it if confirmed.
+func (wm *WindowManager) showProfilePreview(name string, moves []plannedMove) {
+	text := "No window would move."
+	if len(moves) > 0 {
+		lines := make([]string, len(moves))
+		for i, move := range moves {
+			lines[i] = move.String()
+		}
+		text = strings.Join(lines, "\n")
+	}
+	list := widget.NewLabel(text)
+	list.TextStyle = fyne.TextStyle{Monospace: true}
+	scroll := container.NewScroll(list)
+	scroll.SetMinSize(fyne.NewSize(500, 250))
+	header := widget.NewLabel(fmt.Sprintf("Applying profile '%s' moves %d windows:", name, len(moves)))
+
+	wm.mainWindow.Show() // The dialog needs a visible window, also when started from the tray
+	confirm := dialog.NewCustomConfirm("Apply profile", "Apply", "Cancel",
+		container.NewBorder(header, nil, nil, nil, scroll), func(confirmed bool) {
+			defer panicHandler()
+			if confirmed {
+				go wm.runApplyProfile(name)
+			}
+		}, wm.mainWindow)
+	confirm.Show()
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// positionsVersion is the version of the positions file format.
+// Version 1 was a bare map of positions without profiles.
+const positionsVersion = 2
+
+// defaultProfile is the profile that holds the positions of files without profiles
+const defaultProfile = "Default"
+
+// positionsDocument is the content of the positions file.
+// Each profile is a separate set of saved positions, only the active profile is applied.
+// The settings are not part of the document, they stay in settings.json: they apply to all profiles,
+// are written far more often than the positions, and a damaged positions file does not reset them.
+type positionsDocument struct {
+	Version       int                                  `json:"version"`
+	ActiveProfile string                               `json:"activeProfile"`
+	Profiles      map[string]map[string]WindowPosition `json:"profiles"`
+}
+
+// newPositionsDocument returns an empty document with the default profile.
+func newPositionsDocument() *positionsDocument {
+	return &positionsDocument{
+		Version:       positionsVersion,
+		ActiveProfile: defaultProfile,
+		Profiles: map[string]map[string]WindowPosition{
+			defaultProfile: make(map[string]WindowPosition),
+		},
+	}
+}
+
+// copyPosition returns a copy of a position that shares no memory with the original.
+func copyPosition(pos WindowPosition) WindowPosition {
+	pos.Strategies = slices.Clone(pos.Strategies)
+	if pos.Schedule != nil {
+		schedule := *pos.Schedule
+		schedule.Days = slices.Clone(schedule.Days)
+		pos.Schedule = &schedule
+	}
+	return pos
+}
+
+// ProfileNames returns the names of all profiles, sorted.
+func (ps *PositionStorage) ProfileNames() []string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		log(true, "Failed to read profiles:", err)
+		return []string{defaultProfile}
+	}
+	return slices.Sorted(maps.Keys(doc.Profiles))
+}
+
+// ActiveProfile returns the name of the profile whose positions are applied.
+func (ps *PositionStorage) ActiveProfile() string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return defaultProfile
+	}
+	return doc.ActiveProfile
+}
+
+// SetActiveProfile switches the profile whose positions are applied.
+func (ps *PositionStorage) SetActiveProfile(name string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	if _, ok := doc.Profiles[name]; !ok {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	doc.ActiveProfile = name
+	return ps.writeDocument(doc)
+}
+
+// CopyPosition copies a saved position of the active profile into another profile.
+// The target profile is created if it does not exist, an existing rule with the same identifier is overwritten.
+func (ps *PositionStorage) CopyPosition(identifier, profile string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	pos, ok := doc.Profiles[doc.ActiveProfile][identifier]
+	if !ok {
+		return fmt.Errorf("position not found for identifier '%s'", identifier)
+	}
+	if doc.Profiles[profile] == nil {
+		doc.Profiles[profile] = make(map[string]WindowPosition)
+	}
+	doc.Profiles[profile][identifier] = copyPosition(pos)
+	return ps.writeDocument(doc)
+}
+
+// LoadProfilePositions returns the positions of a profile without activating it.
+func (ps *PositionStorage) LoadProfilePositions(profile string) (map[string]WindowPosition, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return nil, err
+	}
+	positions, ok := doc.Profiles[profile]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' does not exist", profile)
+	}
+	return positions, nil
+}
+
+// SaveProfilePositions saves positions into a profile, which is created if it does not exist.
+// Existing positions with the same identifiers are overwritten.
+func (ps *PositionStorage) SaveProfilePositions(profile string, positions map[string]WindowPosition) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	if doc.Profiles[profile] == nil {
+		doc.Profiles[profile] = make(map[string]WindowPosition)
+	}
+	for identifier, pos := range positions {
+		doc.Profiles[profile][identifier] = anchorPosition(copyPosition(pos))
+	}
+	return ps.writeDocument(doc)
+}
+
+// CreateProfile creates a new empty profile.
+func (ps *PositionStorage) CreateProfile(name string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	if _, exists := doc.Profiles[name]; exists {
+		return fmt.Errorf("profile '%s' already exists", name)
+	}
+	doc.Profiles[name] = make(map[string]WindowPosition)
+	return ps.writeDocument(doc)
+}
+
+// DeleteProfile deletes a profile with all its positions. The last profile cannot be deleted.
+// If the active profile is deleted, the default profile or the first remaining one becomes active.
+func (ps *PositionStorage) DeleteProfile(name string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	if _, ok := doc.Profiles[name]; !ok {
+		return fmt.Errorf("profile '%s' does not exist", name)
+	}
+	if len(doc.Profiles) == 1 {
+		return fmt.Errorf("the last profile cannot be deleted")
+	}
+	delete(doc.Profiles, name)
+	if doc.ActiveProfile == name {
+		doc.ActiveProfile = defaultProfile
+		if _, ok := doc.Profiles[defaultProfile]; !ok {
+			doc.ActiveProfile = slices.Sorted(maps.Keys(doc.Profiles))[0]
+		}
+	}
+	return ps.writeDocument(doc)
+}
+
+// RenameProfile renames a profile, it stays active if it was.
+func (ps *PositionStorage) RenameProfile(from, to string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	positions, ok := doc.Profiles[from]
+	if !ok {
+		return fmt.Errorf("profile '%s' does not exist", from)
+	}
+	if _, exists := doc.Profiles[to]; exists {
+		return fmt.Errorf("profile '%s' already exists", to)
+	}
+	delete(doc.Profiles, from)
+	doc.Profiles[to] = positions
+	if doc.ActiveProfile == from {
+		doc.ActiveProfile = to
+	}
+	return ps.writeDocument(doc)
+}
+
+// CopyProfile creates a new profile with copies of all positions of an existing profile.
+func (ps *PositionStorage) CopyProfile(from, to string) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	doc, err := ps.readDocument()
+	if err != nil {
+		return err
+	}
+	source, ok := doc.Profiles[from]
+	if !ok {
+		return fmt.Errorf("profile '%s' does not exist", from)
+	}
+	if _, exists := doc.Profiles[to]; exists {
+		return fmt.Errorf("profile '%s' already exists", to)
+	}
+	copied := make(map[string]WindowPosition, len(source))
+	for identifier, pos := range source {
+		copied[identifier] = copyPosition(pos)
+	}
+	doc.Profiles[to] = copied
+	return ps.writeDocument(doc)
+}
+
+// profileBar creates the profile selector shown above the saved positions.
+func (wm *WindowManager) profileBar() fyne.CanvasObject {
+	profileSelect := widget.NewSelect(wm.storage.ProfileNames(), nil)
+	profileSelect.SetSelected(wm.storage.ActiveProfile())
+	profileSelect.OnChanged = func(name string) {
+		defer panicHandler()
+		if name == wm.storage.ActiveProfile() {
+			return
+		}
+		if err := wm.storage.SetActiveProfile(name); err != nil {
+			log(true, "Failed to switch profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Switched to profile:", name)
+		wm.setupMainWindowContent() // Refresh the UI
+	}
+	var menuBtn *widget.Button
+	menuBtn = widget.NewButtonWithIcon("Profiles", theme.MenuIcon(), safeCallback(func() {
+		canvas := fyne.CurrentApp().Driver().CanvasForObject(menuBtn)
+		widget.ShowPopUpMenuAtRelativePosition(wm.profileMenu(), canvas, fyne.NewPos(0, menuBtn.Size().Height), menuBtn)
+	}))
+	return container.NewBorder(nil, nil, widget.NewLabel("Profile:"), menuBtn, profileSelect)
+}
+
+// profileMenu creates the menu with the actions on the profiles.
+func (wm *WindowManager) profileMenu() *fyne.Menu {
+	return fyne.NewMenu("",
+		fyne.NewMenuItem("Apply now", safeCallback(func() {
+			wm.requestProfile(wm.storage.ActiveProfile())
+		})),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("New profile…", safeCallback(wm.showNewProfileDialog)),
+		fyne.NewMenuItem("Copy profile…", safeCallback(wm.showCopyProfileDialog)),
+		fyne.NewMenuItem("Rename profile…", safeCallback(wm.showRenameProfileDialog)),
+		fyne.NewMenuItem("Delete profile…", safeCallback(wm.showDeleteProfileDialog)),
+	)
+}
+
+// profilesTrayItem creates the system tray submenu that lists the profiles.
+// Choosing a profile activates it and repositions all windows matching its positions.
+func (wm *WindowManager) profilesTrayItem() *fyne.MenuItem {
+	active := wm.storage.ActiveProfile()
+	var items []*fyne.MenuItem
+	for _, name := range wm.storage.ProfileNames() {
+		item := fyne.NewMenuItem(name, safeCallback(func() {
+			wm.requestProfile(name)
+		}))
+		item.Checked = name == active
+		items = append(items, item)
+	}
+	profiles := fyne.NewMenuItem("Profiles", nil)
+	profiles.ChildMenu = fyne.NewMenu("", items...)
+	return profiles
+}
+
+// applyProfile activates a profile and repositions the windows matching its positions.
+// It must run on a background goroutine, as repositioning takes a while.
+func (wm *WindowManager) applyProfile(name string) (RepositionResult, error) {
+	defer panicHandler()
+	debug := true
+	if err := wm.storage.SetActiveProfile(name); err != nil {
+		logError("Failed to apply profile:", err)
+		recordProblem(problemError, name, "Failed to apply profile:", err)
+		return RepositionResult{}, err
+	}
+	fyne.Do(func() {
+		wm.setupMainWindowContent() // Refresh the UI and the tray menu
+	})
+	result := wm.repositionSavedWindows()
+	wm.restoreZOrder(wm.storage.GetAllPositions())
+	log(debug, "Applied profile", name+":", result)
+	return result, nil
+}
+
+// runApplyProfile applies a profile chosen by the user and reports the result in a notification.
+func (wm *WindowManager) runApplyProfile(name string) {
+	defer panicHandler()
+	result, err := wm.applyProfile(name)
+	if err != nil {
+		wm.notify(fmt.Sprintf("Failed to apply profile %s: %v", name, err))
+		return
+	}
+	wm.notify(fmt.Sprintf("Applied profile %s. %s.", name, result.Summary()))
+}
+
+// showNewProfileDialog asks for a name and creates an empty profile, which becomes active.
+func (wm *WindowManager) showNewProfileDialog() {
+	nameEntry := widget.NewEntry()
+	items := []*widget.FormItem{widget.NewFormItem("Name", nameEntry)}
+	dialog.ShowForm("New profile", "Create", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		name := strings.TrimSpace(nameEntry.Text)
+		if !confirmed || name == "" {
+			return
+		}
+		if err := wm.storage.CreateProfile(name); err != nil {
+			log(true, "Failed to create profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		if err := wm.storage.SetActiveProfile(name); err != nil {
+			log(true, "Failed to switch profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+		}
+		log(true, "Created profile", name)
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
+
+// showRenameProfileDialog asks for a new name of the active profile.
+// The power profiles referring to it are renamed as well.
+func (wm *WindowManager) showRenameProfileDialog() {
+	active := wm.storage.ActiveProfile()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(active)
+	items := []*widget.FormItem{widget.NewFormItem("New name", nameEntry)}
+	dialog.ShowForm(fmt.Sprintf("Rename profile '%s'", active), "Rename", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		name := strings.TrimSpace(nameEntry.Text)
+		if !confirmed || name == "" || name == active {
+			return
+		}
+		if err := wm.storage.RenameProfile(active, name); err != nil {
+			log(true, "Failed to rename profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		err := wm.updateSettings(func(s *Settings) {
+			if s.BatteryProfile == active {
+				s.BatteryProfile = name
+			}
+			if s.ACProfile == active {
+				s.ACProfile = name
+			}
+		})
+		if err != nil {
+			log(true, "Failed to update the power profiles:", err)
+		}
+		log(true, "Renamed profile", active, "to", name)
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
+
+// showDeleteProfileDialog asks for confirmation and deletes the active profile with all its positions.
+func (wm *WindowManager) showDeleteProfileDialog() {
+	active := wm.storage.ActiveProfile()
+	message := fmt.Sprintf("Delete profile '%s' with all its saved positions?", active)
+	dialog.ShowConfirm("Delete profile", message, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		if err := wm.storage.DeleteProfile(active); err != nil {
+			log(true, "Failed to delete profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Deleted profile", active)
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
+
+// showCopyRuleToProfileDialog asks for a profile and copies a saved position into it.
+// Typing a new name creates the profile.
+func (wm *WindowManager) showCopyRuleToProfileDialog(identifier string) {
+	active := wm.storage.ActiveProfile()
+	var others []string
+	for _, name := range wm.storage.ProfileNames() {
+		if name != active {
+			others = append(others, name)
+		}
+	}
+	nameEntry := widget.NewSelectEntry(others)
+	nameEntry.SetPlaceHolder("Profile name")
+	items := []*widget.FormItem{widget.NewFormItem("Profile", nameEntry)}
+	dialog.ShowForm("Copy rule to profile", "Copy", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		name := strings.TrimSpace(nameEntry.Text)
+		if !confirmed || name == "" || name == active {
+			return
+		}
+		if err := wm.storage.CopyPosition(identifier, name); err != nil {
+			log(true, "Failed to copy rule:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Copied", redactIdentifier(identifier), "to profile", name)
+		wm.setupMainWindowContent() // Refresh the UI, the profile may be new
+	}, wm.mainWindow)
+}
+
+// showCopyProfileDialog asks for a name and copies the active profile with all its positions.
+func (wm *WindowManager) showCopyProfileDialog() {
+	active := wm.storage.ActiveProfile()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(active + " (copy)")
+	items := []*widget.FormItem{widget.NewFormItem("New profile", nameEntry)}
+	dialog.ShowForm(fmt.Sprintf("Copy profile '%s'", active), "Copy", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		name := strings.TrimSpace(nameEntry.Text)
+		if !confirmed || name == "" {
+			return
+		}
+		if err := wm.storage.CopyProfile(active, name); err != nil {
+			log(true, "Failed to copy profile:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Copied profile", active, "to", name)
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// staleRule is a saved position offered for pruning
+type staleRule struct {
+	Identifier string
+	Reason     string // Why the rule is stale, shown in the confirmation
+}
+
+// stalePositions returns the saved positions whose executable no longer exists or that did not
+// match a window for the given number of days, sorted by identifier.
+// Positions that never matched are kept unless their executable is gone, because they were
+// saved before the timestamp was recorded and their age is unknown.
+func stalePositions(positions map[string]WindowPosition, days int, now time.Time) []staleRule {
+	cutoff := now.AddDate(0, 0, -days)
+	var stale []staleRule
+	for identifier, pos := range positions {
+		if executable, missing := missingExecutable(identifier); missing {
+			stale = append(stale, staleRule{identifier, fmt.Sprintf("%s no longer exists", executable)})
+			continue
+		}
+		last := pos.lastMatched()
+		if last.IsZero() {
+			continue
+		}
+		if last.Before(cutoff) {
+			stale = append(stale, staleRule{identifier, "last matched " + last.Format("2006-01-02")})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Identifier < stale[j].Identifier
+	})
+	return stale
+}
+
+// missingExecutable reports whether the executable of an identifier was uninstalled.
+// Only full paths are checked, executables saved by name or as a wildcard cannot be looked up.
+// Other errors than a missing file, e.g. a denied access or a disconnected drive, keep the rule.
+func missingExecutable(identifier string) (string, bool) {
+	parts, ok := splitIdentifier(identifier)
+	if !ok || !filepath.IsAbs(parts[2]) {
+		return "", false
+	}
+	_, err := os.Stat(parts[2])
+	return parts[2], errors.Is(err, fs.ErrNotExist)
+}
+
+// showPruneDialog previews the rules that would be pruned and deletes them if the user confirms.
+func (wm *WindowManager) showPruneDialog(window fyne.Window, days int) {
+	debug := true
+	positions := wm.storage.GetAllPositions()
+	stale := stalePositions(positions, days, time.Now())
+	log(debug, "Found", len(stale), "rules not matched for", days, "days or without executable.")
+	if len(stale) == 0 {
+		dialog.ShowInformation("Prune unused rules", fmt.Sprintf("No rules were unused for %d days or lost their executable.", days), window)
+		return
+	}
+
+	const maxListed = 15 // Keep the dialog on the screen
+	var lines []string
+	identifiers := make([]string, 0, len(stale))
+	for i, rule := range stale {
+		identifiers = append(identifiers, rule.Identifier)
+		if i == maxListed {
+			lines = append(lines, fmt.Sprintf("… and %d more", len(stale)-maxListed))
+		}
+		if i >= maxListed {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s)", displayName(rule.Identifier, positions[rule.Identifier]), rule.Reason))
+	}
+	message := fmt.Sprintf("These %d rules did not match a window for %d days or their application was uninstalled:\n\n%s\n\nDelete them?",
+		len(stale), days, strings.Join(lines, "\n"))
+
+	dialog.ShowConfirm("Prune unused rules", message, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		if err := wm.storage.DeletePositions(identifiers); err != nil {
+			log(true, "Failed to prune rules:", err)
+			dialog.ShowError(err, window)
+			return
+		}
+		log(true, "Pruned", len(stale), "unused rules.")
+		wm.setupMainWindowContent() // Refresh the UI
+	}, window)
+}
+
+// autoPrune offers to prune the unused rules if pruning is enabled and there is anything to prune.
+// It is called once on startup and shows the main window for the confirmation.
+func (wm *WindowManager) autoPrune() {
+	settings := wm.getSettings()
+	if !settings.PruneEnabled {
+		return
+	}
+	if len(stalePositions(wm.storage.GetAllPositions(), settings.PruneDays, time.Now())) == 0 {
+		return
+	}
+	fyne.Do(func() {
+		wm.mainWindow.Show()
+		wm.showPruneDialog(wm.mainWindow, settings.PruneDays)
+	})
+}
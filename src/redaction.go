@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// logRedactTitles replaces window titles in the log by a hash when set
+var logRedactTitles atomic.Bool
+
+// redactTitle returns the title for the log. With redaction enabled, the title is replaced
+// by a short hash, so the same title can still be followed through a log without revealing it.
+func redactTitle(title string) string {
+	if !logRedactTitles.Load() || title == "" || title == identifierWildcard {
+		return title
+	}
+	hasher := fnv.New32a()
+	hasher.Write([]byte(title))
+	return fmt.Sprintf("<title %08x>", hasher.Sum32())
+}
+
+// redactIdentifier returns the identifier for the log with its title redacted.
+// Class name, executable, and styles are kept, as they are needed for support.
+func redactIdentifier(identifier string) string {
+	if !logRedactTitles.Load() {
+		return identifier
+	}
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return redactTitle(identifier)
+	}
+	_, hash := splitIconHash(identifier)
+	parts[0] = redactTitle(parts[0])
+	return withIconHash(joinIdentifier(parts), hash)
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Policies for saved positions whose target rectangles overlap during a repositioning pass
+const (
+	conflictOverlap = "overlap" // Move the windows anyway and let them overlap
+	conflictOffset  = "offset"  // Cascade the later window until it no longer overlaps
+	conflictSkip    = "skip"    // Do not move the later window
+)
+
+// repositionMatch is a window that matches a saved position during a repositioning pass
+type repositionMatch struct {
+	Window     WindowInfo
+	Identifier string
+	Position   WindowPosition // Saved position
+	Target     WindowPosition // Physical target position
+	ZOrder     int            // Index in the enumeration, 0 is the topmost window
+}
+
+// repositionSavedWindows repositions all saved windows based on their stored positions
+// This is called on startup and when the user applies the positions, e.g. by switching the profile.
+// It returns a summary of the pass, which is also kept as the last result.
+func (wm *WindowManager) repositionSavedWindows() RepositionResult {
+	return wm.repositionWindows(false)
+}
+
+// repositionWindows runs a repositioning pass. The monitoring service sets respectCooldown,
+// so windows moved recently are left alone, see reposition_cooldown.go.
+func (wm *WindowManager) repositionWindows(respectCooldown bool) (result RepositionResult) {
+	debug := false
+	log(debug, "Repositioning saved windows.")
+	result.Started = time.Now()
+	defer func() {
+		result.Duration = time.Since(result.Started)
+		setLastReposition(result)
+	}()
+
+	// Ensure we handle panics gracefully
+	defer panicHandler()
+
+	// Thread-safe operation to avoid concurrent modifications
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+	moveHistory.beginArrangement()
+
+	// Get all saved positions and enumerate current windows
+	settings := wm.getSettings()
+	positions := wm.storage.GetAllPositions()
+	windows, err := EnumerateWindows()
+	if err != nil {
+		logError("-> Failed to enumerate windows:", err)
+		recordProblem(problemError, "", "Failed to enumerate windows:", err)
+		result.Err = err
+		return result
+	}
+
+	windows = withoutExcluded(windows, settings)
+	log(debug, "-> Found", len(windows), "windows to check for saved positions.")
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		log(true, "-> Failed to get monitors:", err)
+	}
+	now := time.Now()
+	positions = activeRules(positions, now)
+	wm.tracker.update(windows, now)
+	wm.pruneScriptTargets()
+	wm.pruneMoveDecisions()
+	grace := time.Duration(settings.NewWindowGraceSeconds) * time.Second
+	cooldown := time.Duration(settings.RepositionCooldownSeconds) * time.Second
+
+	errorCount := 0
+	maxErrors := 10 // Stop processing if too many errors occur
+
+	// The windows are moved concurrently, see moveConcurrently, so the error count, the number
+	// of moved windows and the result are only accessed with resultMutex held
+	var resultMutex sync.Mutex
+	countError := func() int {
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		errorCount++
+		return errorCount
+	}
+	addResult := func(identifier string, window WindowInfo, outcome string, detail ...any) {
+		resultMutex.Lock()
+		defer resultMutex.Unlock()
+		result.add(identifier, window, outcome, detail...)
+	}
+
+	// process runs the work for a single window and recovers from panics
+	process := func(window WindowInfo, work func()) {
+		defer func() {
+			if r := recover(); r != nil {
+				log(true, "Panic in repositionSavedWindows for window", window.Handle, ":", r)
+				if countError() >= maxErrors {
+					log(true, "Too many errors in repositionSavedWindows, stopping processing for this cycle")
+					return
+				}
+			}
+		}()
+
+		// Skip processing if too many errors have occurred
+		resultMutex.Lock()
+		tooMany := errorCount >= maxErrors
+		resultMutex.Unlock()
+		if tooMany {
+			return
+		}
+		work()
+	}
+
+	// Find the windows with saved positions and compute their targets
+	var matches []repositionMatch
+	found := make(map[string]bool)
+	for zOrder, window := range windows {
+		process(window, func() {
+			identifier, pos, exists := findSavedPosition(window, positions)
+			if !exists {
+				if match, ok := wm.defaultRuleMatch(window, settings, monitors, now, grace); ok {
+					match.ZOrder = zOrder
+					matches = append(matches, match)
+				}
+				return
+			}
+			found[identifier] = true
+			if wm.tracker.claimMatch(window.Handle, identifier) {
+				if wm.pendingMatches == nil {
+					wm.pendingMatches = make(map[string]int)
+				}
+				wm.pendingMatches[identifier]++
+			}
+			if respectCooldown && settings.RepositionMode == repositionOnce && wm.positioned.has(identifier) {
+				result.add(identifier, window, outcomeSkipped, "already positioned this session")
+				return
+			}
+			if pos.ApplyOnlyWhenNew && wm.tracker.age(window.Handle, now) > grace {
+				log(debug, "Skipping window that is no longer new:", redactIdentifier(identifier))
+				result.add(identifier, window, outcomeSkipped, "no longer new")
+				return
+			}
+			// Splash screens often share the class of the real window, they are gone before they are stable
+			if stable := time.Duration(pos.StableForMilliseconds) * time.Millisecond; wm.tracker.stableFor(window.Handle, now) < stable {
+				log(debug, "Skipping window that is not stable yet:", redactIdentifier(identifier))
+				result.add(identifier, window, outcomeSkipped, "not stable yet")
+				return
+			}
+			if !applyVirtualDesktopPolicy(window.Handle, identifier, pos) {
+				result.add(identifier, window, outcomeSkipped, "on another virtual desktop")
+				return
+			}
+			target := pos.PhysicalFor(window.Handle)
+			if pos.UseScript {
+				scripted, err := wm.scriptedTarget(identifier, window, pos, settings)
+				if err != nil {
+					countError()
+					logError("Position script failed for", redactIdentifier(identifier)+":", err)
+					recordProblem(problemError, redactIdentifier(identifier), "Position script failed:", err)
+					result.add(identifier, window, outcomeFailed, "position script failed: ", err)
+					return
+				}
+				target = scripted
+			}
+			target, err := pos.onMaximizeMonitor(target, monitors)
+			if err != nil {
+				log(debug, "Monitor", pos.MaximizeMonitor, "is not connected, skipping:", redactIdentifier(identifier))
+				recordProblem(problemWarning, redactIdentifier(identifier), "Monitor", pos.MaximizeMonitor, "to maximize on is not connected")
+				result.add(identifier, window, outcomeSkipped, "monitor ", pos.MaximizeMonitor, " not connected")
+				return
+			}
+			target = clampToMonitors(target, monitors)
+			matches = append(matches, repositionMatch{Window: window, Identifier: identifier, Position: pos, Target: target, ZOrder: zOrder})
+		})
+	}
+
+	resolved := wm.resolveConflicts(matches, settings.ConflictPolicy)
+	if len(resolved) < len(matches) {
+		kept := make(map[syscall.Handle]bool, len(resolved))
+		for _, match := range resolved {
+			kept[match.Window.Handle] = true
+		}
+		for _, match := range matches {
+			if !kept[match.Window.Handle] {
+				result.add(match.Identifier, match.Window, outcomeSkipped, "overlaps another saved position")
+			}
+		}
+	}
+	matches = resolved
+
+	// Moving a window brings it to the top, so applying the windows from the bottom of the
+	// Z-order to the top keeps the stacking order. The primary window is applied last.
+	if settings.RestoreBackToFront {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].ZOrder > matches[j].ZOrder
+		})
+	}
+	matches = arrangeOwnedWindows(matches)
+	sort.SliceStable(matches, func(i, j int) bool {
+		return !matches[i].Position.FinishWithFocus && matches[j].Position.FinishWithFocus
+	})
+
+	moved := 0
+	moveConcurrently(matches, settings.RestoreBackToFront, func(match repositionMatch) {
+		process(match.Window, func() {
+			// Additional validation before attempting to move
+			if !isValidWindow(match.Window.Handle) {
+				log(debug, "Skipping invalid window handle:", redactIdentifier(match.Identifier))
+				addResult(match.Identifier, match.Window, outcomeSkipped, "window no longer exists")
+				return
+			}
+
+			target := match.Target
+			opts := match.Position.moveOptions(settings)
+			var succeededWith string
+			opts.Report = func(strategy string, succeeded bool) {
+				wm.recordStrategyOutcome(match.Window.Executable, strategy, succeeded)
+				if succeeded {
+					succeededWith = strategy
+				}
+			}
+			if opts.Preferred == "" && settings.LearnStrategyOrder {
+				opts.Preferred = wm.preferredStrategy(match.Window.Executable)
+			}
+
+			state := match.Position.State
+			maximize := match.Position.maximizes()
+			inPlace := inSavedState(match.Window.Handle, state, withinPositionTolerance(placementRect(match.Window), target.Rect()))
+			if maximize {
+				inPlace = isMaximizedOnMonitor(match.Window, target.Rect(), monitors)
+			}
+			if inPlace {
+				wm.positioned.mark(match.Identifier)
+				addResult(match.Identifier, match.Window, outcomeInPlace)
+				wm.reassertStyles(match)
+				return
+			}
+
+			// Leave windows alone that were moved recently and only drifted a little since
+			if respectCooldown && cooldown > 0 &&
+				wm.cooldown.active(match.Identifier, match.Window.Handle, match.Window.WindowRect, now, cooldown, settings.DriftTolerancePixels) {
+				log(debug, "Skipping window in cooldown:", redactIdentifier(match.Identifier))
+				addResult(match.Identifier, match.Window, outcomeSkipped, "moved recently")
+				return
+			}
+
+			// Windows of elevated processes are only moved after the user confirmed it once
+			if needsMoveConfirmation(match, settings) {
+				decided, allowed := wm.moveDecision(match.Window.Handle)
+				if !decided {
+					wm.confirmElevatedMove(match, opts)
+				}
+				if !allowed {
+					log(debug, "Skipping elevated window without confirmation:", redactIdentifier(match.Identifier))
+					addResult(match.Identifier, match.Window, outcomeSkipped, "elevated window not confirmed")
+					return
+				}
+			}
+
+			resultMutex.Lock()
+			moved++
+			resultMutex.Unlock()
+			err := wm.moveToSavedState(match.Window, match.Position, target, opts)
+			if errors.Is(err, errHelperStarting) {
+				log(debug, "Waiting for the elevated move helper:", redactIdentifier(match.Identifier))
+				addResult(match.Identifier, match.Window, outcomeSkipped, "waiting for the elevated move helper")
+				return
+			}
+			if err != nil {
+				countError()
+				log(debug, "Failed to auto-position window:", redactIdentifier(match.Identifier), err) // Changed to debug to reduce log spam
+				recordProblem(problemError, redactIdentifier(match.Identifier), "Failed to position window:", err)
+				addResult(match.Identifier, match.Window, outcomeFailed, err)
+				if errors.Is(err, errElevatedTarget) {
+					wm.offerElevatedRestart(match.Window)
+				} else if errors.Is(err, errMoveExhausted) {
+					wm.notifyOnce("move:"+match.Identifier, fmt.Sprintf("Could not move %s by any method. "+
+						"If it runs as administrator, allow the elevated helper in the settings.", redactTitle(match.Window.Title)))
+				}
+			} else {
+				log(debug, "Auto-positioned:", redactIdentifier(match.Identifier))
+				wm.positioned.mark(match.Identifier)
+				if succeededWith != "" && succeededWith != match.Position.SuccessfulMethod && match.Identifier != defaultRuleIdentifier {
+					wm.rememberSuccessfulMethod(match.Identifier, succeededWith)
+				}
+				addResult(match.Identifier, match.Window, outcomeMoved)
+				// Remember where the window actually ended up, apps may adjust the target slightly
+				if moved, err := getWindowPosition(match.Window.Handle); err == nil {
+					wm.cooldown.record(match.Identifier, match.Window.Handle, moved.Rect(), time.Now())
+					if match.Position.UseScript {
+						wm.scriptedWindowMoved(match.Window.Handle, moved.Rect())
+					}
+				}
+			}
+			wm.reassertStyles(match)
+		})
+	})
+
+	// Give the focus to the primary window, but only if the layout changed,
+	// so the monitoring service does not steal the focus every cycle
+	if moved > 0 && len(matches) > 0 {
+		if primary := matches[len(matches)-1]; primary.Position.FinishWithFocus {
+			log(debug, "Focusing primary window:", redactIdentifier(primary.Identifier))
+			if err := focusWindow(primary.Window.Handle); err != nil {
+				logWarn("Failed to focus primary window:", err)
+				recordProblem(problemWarning, redactIdentifier(primary.Identifier), "Failed to focus primary window:", err)
+			}
+		}
+	}
+
+	wm.recordMatches(positions, found, matches)
+	wm.saveStrategyStats()
+	for identifier := range positions {
+		if !found[identifier] {
+			result.NotFound++
+		}
+	}
+
+	if errorCount > 0 {
+		log(true, "repositionSavedWindows completed with", errorCount, "errors")
+	}
+	return result
+}
+
+// reassertStyles restores the topmost flag, the click-through styles and the opacity of a rule's window,
+// also for windows that are already in place.
+func (wm *WindowManager) reassertStyles(match repositionMatch) {
+	wm.reassertTopmost(match)
+	if match.Position.RestoreExStyle {
+		if err := applyExStyleFlags(match.Window.Handle, clickThroughExStyles, match.Position.ExStyleFlags); err != nil {
+			logError("Failed to restore click-through styles for", redactIdentifier(match.Identifier)+":", err)
+			recordProblem(problemError, redactIdentifier(match.Identifier), "Failed to restore click-through styles:", err)
+		}
+	}
+	wm.reassertOpacity(match) // After the styles, restoring them may reset the alpha value
+}
+
+// errNoMatchingWindow is returned when a saved position is applied but no open window matches it
+var errNoMatchingWindow = errors.New("no matching window open")
+
+// applySavedPosition moves the first open window matching a saved position to it right away.
+// Unlike a repositioning pass it ignores the cooldown, the rule's schedule and the reposition mode.
+func (wm *WindowManager) applySavedPosition(identifier string) error {
+	debug := true
+	log(debug, "Applying saved position:", redactIdentifier(identifier))
+
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+	moveHistory.beginArrangement()
+
+	pos, err := wm.storage.LoadPosition(identifier)
+	if err != nil {
+		return err
+	}
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return err
+	}
+	window, ok := findWindowForIdentifier(identifier, pos.TitlePattern, windows)
+	if !ok {
+		return errNoMatchingWindow
+	}
+
+	settings := wm.getSettings()
+	target := pos.PhysicalFor(window.Handle)
+	if monitors, err := getCachedMonitors(); err == nil {
+		if target, err = pos.onMaximizeMonitor(target, monitors); err != nil {
+			return err
+		}
+		target = clampToMonitors(target, monitors)
+	}
+	opts := pos.moveOptions(settings)
+	if err := wm.moveToSavedState(window, *pos, target, opts); err != nil {
+		logError("Failed to apply saved position", redactIdentifier(identifier)+":", err)
+		recordProblem(problemError, redactIdentifier(identifier), "Failed to apply saved position:", err)
+		if errors.Is(err, errElevatedTarget) {
+			wm.offerElevatedRestart(window)
+		}
+		return err
+	}
+	wm.positioned.mark(identifier)
+	if moved, err := getWindowPosition(window.Handle); err == nil {
+		wm.cooldown.record(identifier, window.Handle, moved.Rect(), time.Now())
+	}
+	log(debug, "Applied saved position:", redactIdentifier(identifier))
+	return nil
+}
+
+// moveOptions returns the move options of a rule. The rule's timeout overrides the global timeout
+// and the rule's strategies, tried in the order they are listed, override the global strategy order.
+// The strategy that last moved a window of the rule is tried first.
+func (pos WindowPosition) moveOptions(settings Settings) moveOptions {
+	timeout := settings.MoveTimeoutMilliseconds
+	if pos.MoveTimeoutMilliseconds > 0 {
+		timeout = pos.MoveTimeoutMilliseconds
+	}
+	strategies := pos.Strategies
+	if len(strategies) == 0 && len(settings.StrategyOrder) > 0 {
+		strategies = orderedStrategyNames(settings.StrategyOrder)
+	}
+	return moveOptions{
+		Timeout:    time.Duration(timeout) * time.Millisecond,
+		Strategies: strategies,
+		Preferred:  pos.SuccessfulMethod,
+	}
+}
+
+// lastMatched returns the last time an open window matched the rule. Rules saved before
+// LastMatchedAt existed only have LastAppliedAt, which was recorded for matches then.
+func (pos WindowPosition) lastMatched() time.Time {
+	if pos.LastMatchedAt.IsZero() {
+		return pos.LastAppliedAt
+	}
+	return pos.LastMatchedAt
+}
+
+// recordMatches updates the statistics of the rules: LastMatchedAt of the rules that matched an
+// open window, even if the window was skipped, LastAppliedAt of the rules in matches, which the
+// pass applied, and the MatchCount. The timestamps only need day precision for pruning and the
+// count is a statistic, so they are written at most once per hour per rule instead of every
+// monitoring cycle. It must be called with the operationMutex held.
+func (wm *WindowManager) recordMatches(positions map[string]WindowPosition, matched map[string]bool, matches []repositionMatch) {
+	const interval = time.Hour
+
+	now := time.Now()
+	applied := make(map[string]bool)
+	for _, match := range matches {
+		if match.Identifier != defaultRuleIdentifier && now.Sub(match.Position.LastAppliedAt) >= interval {
+			applied[match.Identifier] = true
+		}
+	}
+	var identifiers []string
+	for identifier := range matched {
+		if identifier == defaultRuleIdentifier {
+			continue
+		}
+		if applied[identifier] || now.Sub(positions[identifier].LastMatchedAt) >= interval {
+			identifiers = append(identifiers, identifier)
+		}
+	}
+	wm.writeMatchStats(identifiers, applied, now)
+}
+
+// flushMatchCounts writes the match counts that were not written yet, it is called on exit.
+func (wm *WindowManager) flushMatchCounts() {
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+	identifiers := make([]string, 0, len(wm.pendingMatches))
+	for identifier := range wm.pendingMatches {
+		identifiers = append(identifiers, identifier)
+	}
+	wm.writeMatchStats(identifiers, nil, time.Time{})
+}
+
+// writeMatchStats adds the pending match counts to the rules and sets their LastMatchedAt
+// timestamp and, for the applied rules, their LastAppliedAt timestamp, unless now is zero.
+// It must be called with the operationMutex held.
+func (wm *WindowManager) writeMatchStats(identifiers []string, applied map[string]bool, now time.Time) {
+	if len(identifiers) == 0 {
+		return
+	}
+	err := wm.storage.UpdatePositions(identifiers, func(identifier string, pos *WindowPosition) {
+		if !now.IsZero() {
+			pos.LastMatchedAt = now
+			if applied[identifier] {
+				pos.LastAppliedAt = now
+			}
+		}
+		pos.MatchCount += wm.pendingMatches[identifier]
+	})
+	if err != nil {
+		log(true, "Failed to record applied rules:", err)
+		return
+	}
+	// Also forget the counts of rules that were deleted in the meantime
+	for _, identifier := range identifiers {
+		delete(wm.pendingMatches, identifier)
+	}
+}
+
+// resolveConflicts detects matches whose target rectangles overlap and handles them
+// according to the conflict policy. The matches are processed in identifier order, so
+// the result does not depend on the window enumeration order.
+// It must be called with the operationMutex held.
+func (wm *WindowManager) resolveConflicts(matches []repositionMatch, policy string) []repositionMatch {
+	const cascadeOffset = 30   // Pixels to shift a conflicting window per step
+	const maxCascadeSteps = 10 // Give up cascading after this many steps
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Identifier < matches[j].Identifier
+	})
+
+	var placed []repositionMatch
+	for _, match := range matches {
+		other, conflict := findOverlap(match.Target, placed)
+		if conflict {
+			wm.reportConflict(match, other, policy)
+			switch policy {
+			case conflictSkip:
+				continue
+			case conflictOffset:
+				for step := 0; conflict && step < maxCascadeSteps; step++ {
+					match.Target.X += cascadeOffset
+					match.Target.Y += cascadeOffset
+					_, conflict = findOverlap(match.Target, placed)
+				}
+			}
+		}
+		placed = append(placed, match)
+	}
+	return placed
+}
+
+// reportConflict logs a conflict between two saved positions once per session,
+// so users can fix their configuration without the log being flooded every cycle.
+// It must be called with the operationMutex held.
+func (wm *WindowManager) reportConflict(match, other repositionMatch, policy string) {
+	key := match.Identifier + "\n" + other.Identifier
+	if wm.reportedConflicts == nil {
+		wm.reportedConflicts = make(map[string]bool)
+	}
+	if wm.reportedConflicts[key] {
+		return
+	}
+	wm.reportedConflicts[key] = true
+	log(true, fmt.Sprintf("Conflict (policy %s): '%s' overlaps '%s'", policy, redactIdentifier(match.Identifier), redactIdentifier(other.Identifier)))
+}
+
+// findOverlap returns the first placed match whose target overlaps the rectangle.
+func findOverlap(target WindowPosition, placed []repositionMatch) (repositionMatch, bool) {
+	for _, other := range placed {
+		if rectsOverlap(target.Rect(), other.Target.Rect()) {
+			return other, true
+		}
+	}
+	return repositionMatch{}, false
+}
+
+// rectsOverlap checks if two rectangles share an area. Rectangles that only touch do not overlap.
+func rectsOverlap(a, b RECT) bool {
+	return a.Left < b.Right && b.Left < a.Right && a.Top < b.Bottom && b.Top < a.Bottom
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+/*
+	Repositioning cooldown:
+	- Some apps nudge their own windows, so the monitoring service would move them back every cycle,
+	  which makes the windows jump and fills the log.
+	- After a window was moved, the monitoring service leaves it alone for the cooldown period,
+	  unless it drifted more than the tolerance away from where it was put.
+	- The cooldown is per saved position and window, as a rule with a title pattern can match several windows.
+	  Passes started by the user, like applying a profile, ignore the cooldown.
+*/
+
+// cooldownKey identifies a window positioned by a saved position
+type cooldownKey struct {
+	Identifier string
+	Handle     syscall.Handle
+}
+
+// cooledWindow is where a window was put and when
+type cooledWindow struct {
+	Rect RECT
+	At   time.Time
+}
+
+// repositionCooldown remembers the windows moved recently
+type repositionCooldown struct {
+	mutex   sync.Mutex
+	windows map[cooldownKey]cooledWindow
+}
+
+// newRepositionCooldown creates an empty cooldown.
+func newRepositionCooldown() *repositionCooldown {
+	return &repositionCooldown{windows: make(map[cooldownKey]cooledWindow)}
+}
+
+// record remembers that a window was moved to the rectangle now.
+func (c *repositionCooldown) record(identifier string, hwnd syscall.Handle, rect RECT, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.windows[cooldownKey{identifier, hwnd}] = cooledWindow{Rect: rect, At: now}
+}
+
+// active checks if a window was moved less than the period ago and is still within the
+// tolerance of where it was put. Entries older than the period are forgotten.
+func (c *repositionCooldown) active(identifier string, hwnd syscall.Handle, current RECT, now time.Time, period time.Duration, tolerance int) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, cooled := range c.windows {
+		if now.Sub(cooled.At) >= period {
+			delete(c.windows, key)
+		}
+	}
+	cooled, ok := c.windows[cooldownKey{identifier, hwnd}]
+	return ok && rectDrift(cooled.Rect, current) <= tolerance
+}
+
+// rectDrift returns the largest distance between the corresponding edges of two rectangles.
+func rectDrift(a, b RECT) int {
+	abs := func(v int32) int {
+		if v < 0 {
+			return int(-v)
+		}
+		return int(v)
+	}
+	return max(abs(a.Left-b.Left), abs(a.Top-b.Top), abs(a.Right-b.Right), abs(a.Bottom-b.Bottom))
+}
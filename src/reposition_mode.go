@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+/*
+	Repositioning modes:
+	- off: windows are never positioned automatically, not even at startup.
+	- startup: only the startup pass positions windows, the monitoring service does nothing.
+	- once: the monitoring service positions each saved identifier a single time per session,
+	  after its window first appears. Windows the user moves afterwards are left alone.
+	  New windows are noticed through the window event hooks and the periodic ticker, which runs
+	  slowly while the hooks run, see win_events.go.
+	- continuous: the monitoring service keeps windows at their saved positions (the default).
+	- Passes started by the user, like applying a profile, position all windows in every mode.
+	- The positioned identifiers are kept in memory, so a restart positions every window once again.
+	- The mode is chosen in the Positioning settings or in the tray menu.
+*/
+
+// Repositioning modes, stored in Settings.RepositionMode
+const (
+	repositionOff         = "off"
+	repositionStartupOnly = "startup"
+	repositionOnce        = "once"
+	repositionContinuous  = "continuous"
+)
+
+// repositionModes lists the modes with their labels in the order they are offered
+var repositionModes = []struct {
+	mode  string
+	label string
+}{
+	{repositionOff, "Off"},
+	{repositionStartupOnly, "Only at startup"},
+	{repositionOnce, "Once per window and session"},
+	{repositionContinuous, "Continuously"},
+}
+
+// repositionModeLabel returns the label of a mode, unknown modes are shown as continuous.
+func repositionModeLabel(mode string) string {
+	for _, m := range repositionModes {
+		if m.mode == mode {
+			return m.label
+		}
+	}
+	return repositionModeLabel(repositionContinuous)
+}
+
+// usesMonitoring reports whether the monitoring service positions windows at all in a mode.
+func usesMonitoring(mode string) bool {
+	return mode != repositionOff && mode != repositionStartupOnly
+}
+
+// setRepositionMode stores a new mode and restarts the monitoring service to honor it.
+func (wm *WindowManager) setRepositionMode(mode string) {
+	if err := wm.updateSettings(func(s *Settings) { s.RepositionMode = mode }); err != nil {
+		return
+	}
+	log(true, "Repositioning mode:", repositionModeLabel(mode))
+	wm.resetMonitoring()
+	wm.updateSystemTray()
+}
+
+// repositionModeTrayItem creates the tray submenu showing and switching the repositioning mode.
+func (wm *WindowManager) repositionModeTrayItem() *fyne.MenuItem {
+	current := wm.getSettings().RepositionMode
+	var items []*fyne.MenuItem
+	for _, m := range repositionModes {
+		item := fyne.NewMenuItem(m.label, safeCallback(func() {
+			wm.setRepositionMode(m.mode)
+		}))
+		item.Checked = repositionModeLabel(current) == m.label
+		items = append(items, item)
+	}
+	mode := fyne.NewMenuItem("Positioning: "+repositionModeLabel(current), nil)
+	mode.ChildMenu = fyne.NewMenu("", items...)
+	return mode
+}
+
+// positionedSet holds the identifiers positioned in this session
+type positionedSet struct {
+	mutex       sync.Mutex
+	identifiers map[string]bool
+}
+
+// newPositionedSet creates an empty set.
+func newPositionedSet() *positionedSet {
+	return &positionedSet{identifiers: make(map[string]bool)}
+}
+
+// mark records that the window of an identifier was positioned.
+func (s *positionedSet) mark(identifier string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.identifiers[identifier] = true
+}
+
+// has checks if the window of an identifier was positioned in this session.
+func (s *positionedSet) has(identifier string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.identifiers[identifier]
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Outcomes of a rule in a repositioning pass
+const (
+	outcomeMoved   = "moved"    // The window was moved to its target
+	outcomeInPlace = "in place" // The window already was at its target
+	outcomeSkipped = "skipped"  // The rule matched, but the window was not moved this pass
+	outcomeFailed  = "failed"   // Moving the window failed
+)
+
+// RuleResult is the outcome of a matched rule in a repositioning pass
+type RuleResult struct {
+	Identifier string `json:"identifier"`
+	Title      string `json:"title"`
+	Outcome    string `json:"outcome"`
+	Detail     string `json:"detail,omitempty"` // Reason for skipping, or the error
+}
+
+// RepositionResult summarizes a repositioning pass, so callers can report it
+type RepositionResult struct {
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Matched  int           `json:"matched"` // Windows that matched a rule
+	Moved    int           `json:"moved"`
+	InPlace  int           `json:"inPlace"`
+	Skipped  int           `json:"skipped"`
+	Failed   int           `json:"failed"`
+	NotFound int           `json:"notFound"` // Saved positions whose window is not open
+	Rules    []RuleResult  `json:"rules,omitempty"`
+	Errors   []string      `json:"errors,omitempty"` // Why windows could not be moved
+	Err      error         `json:"-"`                // The pass could not run, e.g. the windows could not be enumerated
+}
+
+// add records the outcome of a matched rule.
+func (r *RepositionResult) add(identifier string, window WindowInfo, outcome string, detail ...any) {
+	r.Matched++
+	switch outcome {
+	case outcomeMoved:
+		r.Moved++
+	case outcomeInPlace:
+		r.InPlace++
+	case outcomeSkipped:
+		r.Skipped++
+	case outcomeFailed:
+		r.Failed++
+		r.Errors = append(r.Errors, redactIdentifier(identifier)+": "+fmt.Sprint(detail...))
+	}
+	r.Rules = append(r.Rules, RuleResult{
+		Identifier: redactIdentifier(identifier),
+		Title:      redactTitle(window.Title),
+		Outcome:    outcome,
+		Detail:     fmt.Sprint(detail...),
+	})
+}
+
+// String returns a one-line summary of the pass.
+func (r RepositionResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("Repositioning failed: %v", r.Err)
+	}
+	return fmt.Sprintf("%d matched, %d moved, %d in place, %d skipped, %d failed, %d not found",
+		r.Matched, r.Moved, r.InPlace, r.Skipped, r.Failed, r.NotFound)
+}
+
+// Summary returns a short sentence for notifications, like "Repositioned 6 of 8 windows; 2 not found".
+func (r RepositionResult) Summary() string {
+	if r.Err != nil {
+		return r.String()
+	}
+	summary := fmt.Sprintf("Repositioned %d of %d windows", r.Moved+r.InPlace, r.Matched+r.NotFound)
+	for _, part := range []struct {
+		count int
+		text  string
+	}{{r.NotFound, "not found"}, {r.Skipped, "skipped"}, {r.Failed, "failed"}} {
+		if part.count > 0 {
+			summary += fmt.Sprintf("; %d %s", part.count, part.text)
+		}
+	}
+	return summary
+}
+
+// lastReposition holds the result of the last repositioning pass
+var (
+	lastReposition      RepositionResult
+	lastRepositionMutex sync.Mutex
+)
+
+// setLastReposition stores the result of a repositioning pass.
+func setLastReposition(result RepositionResult) {
+	lastRepositionMutex.Lock()
+	defer lastRepositionMutex.Unlock()
+	lastReposition = result
+}
+
+// getLastReposition returns the result of the last repositioning pass.
+func getLastReposition() RepositionResult {
+	lastRepositionMutex.Lock()
+	defer lastRepositionMutex.Unlock()
+	return lastReposition
+}
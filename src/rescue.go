@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// isWindowMinimized checks if a window is minimized.
+// Minimized windows are parked at -32000,-32000 and must not count as off-screen.
+func isWindowMinimized(hwnd syscall.Handle) bool {
+	ret, _, _ := procIsIconic.Call(uintptr(hwnd))
+	return ret != 0
+}
+
+// isWindowMaximized checks if a window is maximized.
+func isWindowMaximized(hwnd syscall.Handle) bool {
+	ret, _, _ := procIsZoomed.Call(uintptr(hwnd))
+	return ret != 0
+}
+
+// nearestMonitor returns the index of the monitor whose work area is closest to the center of the rectangle.
+func nearestMonitor(rect RECT, monitors []Monitor) int {
+	centerX := int64(rect.Left+rect.Right) / 2
+	centerY := int64(rect.Top+rect.Bottom) / 2
+	best, bestDistance := 0, int64(-1)
+	for i, monitor := range monitors {
+		// Distance from the center to the closest point of the work area
+		dx := max(int64(monitor.WorkArea.Left)-centerX, 0, centerX-int64(monitor.WorkArea.Right))
+		dy := max(int64(monitor.WorkArea.Top)-centerY, 0, centerY-int64(monitor.WorkArea.Bottom))
+		if distance := dx*dx + dy*dy; bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// centerOnWorkArea returns the position of a window of the given size centered on a work area.
+// Windows larger than the work area are shrunk to fit, like the recovery in focusWindow.
+func centerOnWorkArea(width, height int, workArea RECT) WindowPosition {
+	areaWidth := int(workArea.Right - workArea.Left)
+	areaHeight := int(workArea.Bottom - workArea.Top)
+	width = min(width, areaWidth)
+	height = min(height, areaHeight)
+	return WindowPosition{
+		X:      int(workArea.Left) + (areaWidth-width)/2,
+		Y:      int(workArea.Top) + (areaHeight-height)/2,
+		Width:  width,
+		Height: height,
+	}
+}
+
+// rescueOffscreenWindows moves all windows that are not visible on any monitor to the
+// nearest monitor. Windows in gaps of the virtual screen between monitors of different
+// sizes count as off-screen, too. It returns the number of rescued windows.
+func (wm *WindowManager) rescueOffscreenWindows() (int, error) {
+	debug := true
+	defer panicHandler()
+
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+	moveHistory.beginArrangement()
+
+	monitors, err := GetMonitors()
+	if err != nil {
+		return 0, err
+	}
+	if len(monitors) == 0 {
+		return 0, fmt.Errorf("no monitors found")
+	}
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return 0, err
+	}
+
+	rescued := 0
+	for _, window := range windows {
+		rect := window.WindowRect
+		if isWindowMinimized(window.Handle) || monitorForRect(rect, monitors) >= 0 {
+			continue
+		}
+		monitor := monitors[nearestMonitor(rect, monitors)]
+		target := centerOnWorkArea(int(rect.Right-rect.Left), int(rect.Bottom-rect.Top), monitor.WorkArea)
+		log(debug, "Rescuing off-screen window", redactTitle(window.Title), "from", rect, "to", monitor.String())
+		if err := MoveWindowAccurate(window.Handle, target.X, target.Y, target.Width, target.Height); err != nil {
+			logError("Failed to rescue window", redactTitle(window.Title)+":", err)
+			recordProblem(problemError, redactTitle(window.Title), "Failed to rescue window:", err)
+			continue
+		}
+		rescued++
+	}
+	log(true, "Rescued", rescued, "off-screen windows.")
+	return rescued, nil
+}
+
+// runRescueOffscreenWindows rescues the off-screen windows in the background and reports the result.
+func (wm *WindowManager) runRescueOffscreenWindows() {
+	go func() {
+		defer panicHandler()
+		rescued, err := wm.rescueOffscreenWindows()
+		message := fmt.Sprintf("Rescued %d off-screen windows.", rescued)
+		if err != nil {
+			message = fmt.Sprintf("Failed to rescue off-screen windows: %v", err)
+		}
+		wm.notify(message)
+	}()
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// showRuleEditor opens a dialog to edit the saved position (rule) identified by its identifier.
+// Changes are written back to the PositionStorage when the dialog is confirmed.
+func (wm *WindowManager) showRuleEditor(identifier string) {
+	debug := true
+	log(debug, "Opening rule editor for:", redactIdentifier(identifier))
+
+	pos, err := wm.storage.LoadPosition(identifier)
+	if err != nil {
+		log(true, "Failed to load position for rule editor:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+
+	xEntry := newIntEntry(pos.X)
+	yEntry := newIntEntry(pos.Y)
+	widthEntry := newIntEntry(pos.Width)
+	heightEntry := newIntEntry(pos.Height)
+
+	// Convert the entered size when the unit changes, so the window keeps its visible size
+	logicalCheck := widget.NewCheck("Size in logical units (DPI-independent)", nil)
+	logicalCheck.SetChecked(pos.Logical)
+	logicalCheck.OnChanged = func(logical bool) {
+		width, errW := strconv.Atoi(widthEntry.Text)
+		height, errH := strconv.Atoi(heightEntry.Text)
+		if errW != nil || errH != nil {
+			return
+		}
+		dpi := getDpiForRect(WindowPosition{X: pos.X, Y: pos.Y, Width: width, Height: height}.Rect())
+		if logical {
+			width = scaleForDpi(width, dpi, USER_DEFAULT_SCREEN_DPI)
+			height = scaleForDpi(height, dpi, USER_DEFAULT_SCREEN_DPI)
+		} else {
+			width = scaleForDpi(width, USER_DEFAULT_SCREEN_DPI, dpi)
+			height = scaleForDpi(height, USER_DEFAULT_SCREEN_DPI, dpi)
+		}
+		widthEntry.SetText(strconv.Itoa(width))
+		heightEntry.SetText(strconv.Itoa(height))
+	}
+
+	// Shift and shrink the entered rectangle so it fits on the monitor that contains most of it
+	clampButton := widget.NewButton("Clamp to monitor", safeCallback(func() {
+		rect, err := rectFromEntries(xEntry, yEntry, widthEntry, heightEntry)
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		monitors, err := getCachedMonitors()
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		dpi := getDpiForRect(rect.Rect())
+		if logicalCheck.Checked {
+			rect.Width = scaleForDpi(rect.Width, USER_DEFAULT_SCREEN_DPI, dpi)
+			rect.Height = scaleForDpi(rect.Height, USER_DEFAULT_SCREEN_DPI, dpi)
+		}
+		clamped := clampToMonitors(rect, monitors)
+		if logicalCheck.Checked {
+			clamped.Width = scaleForDpi(clamped.Width, dpi, USER_DEFAULT_SCREEN_DPI)
+			clamped.Height = scaleForDpi(clamped.Height, dpi, USER_DEFAULT_SCREEN_DPI)
+		}
+		xEntry.SetText(strconv.Itoa(clamped.X))
+		yEntry.SetText(strconv.Itoa(clamped.Y))
+		widthEntry.SetText(strconv.Itoa(clamped.Width))
+		heightEntry.SetText(strconv.Itoa(clamped.Height))
+	}))
+
+	visibleFrameCheck := widget.NewCheck("Position of the visible frame (without invisible borders)", nil)
+	visibleFrameCheck.SetChecked(pos.VisibleFrame)
+
+	scriptCheck := widget.NewCheck("Compute the position with the script hook", nil)
+	scriptCheck.SetChecked(pos.UseScript)
+	focusCheck := widget.NewCheck("Primary window: apply last and give it the focus", nil)
+	focusCheck.SetChecked(pos.FinishWithFocus)
+	confirmCheck := widget.NewCheck("Ask before moving if the process is elevated", nil)
+	confirmCheck.SetChecked(pos.ConfirmIfElevated)
+	newOnlyCheck := widget.NewCheck("Only apply to new windows (then leave them alone)", nil)
+	newOnlyCheck.SetChecked(pos.ApplyOnlyWhenNew)
+	topmostCheck := widget.NewCheck("Keep the window always on top", nil)
+	topmostCheck.SetChecked(pos.AlwaysOnTop)
+
+	// Click-through styles, clearly labeled as they can make a window unusable with the mouse
+	layeredCheck := widget.NewCheck("Layered (WS_EX_LAYERED)", nil)
+	layeredCheck.SetChecked(pos.ExStyleFlags&WS_EX_LAYERED != 0)
+	transparentCheck := widget.NewCheck("Click-through (WS_EX_TRANSPARENT)", nil)
+	transparentCheck.SetChecked(pos.ExStyleFlags&WS_EX_TRANSPARENT != 0)
+	restoreExStyleCheck := widget.NewCheck("Reapply these styles on restore", nil)
+	restoreExStyleCheck.SetChecked(pos.RestoreExStyle)
+	exStyleWarning := widget.NewLabel("Warning: a click-through window ignores all mouse input\nuntil the style is removed again.")
+
+	// Window state, saved with the position
+	states := map[string]string{
+		"Keep the current state": "",
+		"Normal":                 windowStateNormal,
+		"Maximized":              windowStateMaximized,
+		"Minimized":              windowStateMinimized,
+	}
+	stateSelect := widget.NewSelect([]string{"Keep the current state", "Normal", "Maximized", "Minimized"}, nil)
+	for label, state := range states {
+		if state == pos.State {
+			stateSelect.SetSelected(label)
+		}
+	}
+
+	// Maximize on a monitor, the size above is used as the restore size
+	const noMaximize = "Don't maximize"
+	maximizeOptions := []string{noMaximize}
+	maximizeDevices := map[string]string{noMaximize: ""}
+	maximizeSelected := noMaximize
+	monitors, _ := getCachedMonitors()
+	for _, monitor := range monitors {
+		maximizeOptions = append(maximizeOptions, monitor.String())
+		maximizeDevices[monitor.String()] = monitor.DeviceName
+		if monitor.DeviceName == pos.MaximizeMonitor {
+			maximizeSelected = monitor.String()
+		}
+	}
+	if maximizeSelected == noMaximize && pos.MaximizeMonitor != "" {
+		// Keep the setting of a monitor that is not connected
+		maximizeSelected = pos.MaximizeMonitor + " (not connected)"
+		maximizeOptions = append(maximizeOptions, maximizeSelected)
+		maximizeDevices[maximizeSelected] = pos.MaximizeMonitor
+	}
+	maximizeSelect := widget.NewSelect(maximizeOptions, nil)
+	maximizeSelect.SetSelected(maximizeSelected)
+
+	// Behavior if the window is on another virtual desktop
+	desktopPolicies := map[string]string{
+		"Position it anyway":              virtualDesktopLeave,
+		"Only position it when current":   virtualDesktopCurrent,
+		"Move it to the stored desktop":   virtualDesktopMove,
+		"Bring it to the current desktop": virtualDesktopBring,
+	}
+	desktopOptions := []string{"Position it anyway", "Only position it when current", "Move it to the stored desktop", "Bring it to the current desktop"}
+	desktopSelect := widget.NewSelect(desktopOptions, nil)
+	for label, policy := range desktopPolicies {
+		if policy == pos.VirtualDesktop {
+			desktopSelect.SetSelected(label)
+		}
+	}
+
+	// Schedule, Monday first as in most calendars
+	var schedule RuleSchedule
+	if pos.Schedule != nil {
+		schedule = *pos.Schedule
+	}
+	scheduleCheck := widget.NewCheck("Only active on these days and times", nil)
+	scheduleCheck.SetChecked(pos.Schedule != nil)
+	var dayChecks []fyne.CanvasObject
+	for i := range scheduleDays {
+		day := scheduleDays[(i+1)%len(scheduleDays)]
+		check := widget.NewCheck(day, nil)
+		check.SetChecked(slices.Contains(schedule.Days, day))
+		dayChecks = append(dayChecks, check)
+	}
+	fromEntry := widget.NewEntry()
+	fromEntry.SetPlaceHolder("09:00")
+	fromEntry.SetText(schedule.From)
+	untilEntry := widget.NewEntry()
+	untilEntry.SetPlaceHolder("12:00")
+	untilEntry.SetText(schedule.Until)
+
+	// Move behavior overrides for slow or fragile apps
+	timeoutEntry := newIntEntry(pos.MoveTimeoutMilliseconds)
+	stableEntry := newIntEntry(pos.StableForMilliseconds)
+	priorityEntry := newIntEntry(pos.Priority)
+	orderEntry := newIntEntry(pos.Order)
+	opacityEntry := newIntEntry(pos.Opacity)
+	var strategyChecks []fyne.CanvasObject
+	for _, name := range orderedStrategyNames(pos.Strategies) { // Keep the order of the rule
+		check := widget.NewCheck(name, nil)
+		check.SetChecked(len(pos.Strategies) == 0 || slices.Contains(pos.Strategies, name))
+		strategyChecks = append(strategyChecks, check)
+	}
+
+	// Identifier composition of this rule. Components that are already wildcards
+	// cannot be re-enabled, as their original values are unknown.
+	fields := identifierFieldsOf(identifier)
+	newFieldCheck := func(label string, enabled bool) *widget.Check {
+		check := widget.NewCheck(label, nil)
+		check.SetChecked(enabled)
+		if !enabled {
+			check.Disable()
+		}
+		return check
+	}
+	titleCheck := newFieldCheck("Title", fields.Title)
+	// The title can be edited into a pattern, e.g. "* - Notepad" or "/^Report \d+/"
+	parts, _ := splitIdentifier(identifier)
+	// Titles are only patterns when asked for, titles like "Save changes?" match exactly
+	patternCheck := widget.NewCheck("Title is a pattern", nil)
+	patternCheck.SetChecked(pos.TitlePattern)
+	titleEntry := widget.NewEntry()
+	titleEntry.SetText(parts[0])
+	titleEntry.Validator = func(text string) error {
+		if !patternCheck.Checked || text == identifierWildcard {
+			return nil
+		}
+		_, err := compileTitlePattern(text)
+		return err
+	}
+	patternCheck.OnChanged = func(bool) {
+		titleEntry.Validate()
+	}
+	titleCheck.OnChanged = func(checked bool) {
+		if checked {
+			titleEntry.Enable()
+			patternCheck.Enable()
+		} else {
+			titleEntry.Disable()
+			patternCheck.Disable()
+		}
+	}
+	titleCheck.OnChanged(fields.Title)
+	classCheck := newFieldCheck("Class name", fields.ClassName)
+	exeCheck := newFieldCheck("Executable", fields.Executable)
+	styleCheck := newFieldCheck("Style", fields.Style)
+	exStyleCheck := newFieldCheck("Extended style", fields.ExStyle)
+	_, iconHash := splitIconHash(identifier)
+	iconCheck := widget.NewCheck("Icon (slow, only for otherwise identical windows)", nil)
+	iconCheck.SetChecked(iconHash != "")
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("X", xEntry),
+		widget.NewFormItem("Y", yEntry),
+		widget.NewFormItem("Width", widthEntry),
+		widget.NewFormItem("Height", heightEntry),
+		widget.NewFormItem("", clampButton),
+		widget.NewFormItem("", logicalCheck),
+		widget.NewFormItem("", visibleFrameCheck),
+		widget.NewFormItem("Window state", stateSelect),
+		widget.NewFormItem("Maximize on", maximizeSelect),
+		widget.NewFormItem("Other virtual desktop", desktopSelect),
+		widget.NewFormItem("Schedule", container.NewVBox(
+			scheduleCheck,
+			container.NewGridWithColumns(4, dayChecks...),
+			container.NewGridWithColumns(4, widget.NewLabel("From"), fromEntry, widget.NewLabel("Until"), untilEntry),
+		)),
+		widget.NewFormItem("", scriptCheck),
+		widget.NewFormItem("", focusCheck),
+		widget.NewFormItem("", confirmCheck),
+		widget.NewFormItem("", newOnlyCheck),
+		widget.NewFormItem("", topmostCheck),
+		widget.NewFormItem("Opacity (26-255, 0 = keep)", opacityEntry),
+		widget.NewFormItem("Click-through", container.NewVBox(layeredCheck, transparentCheck, restoreExStyleCheck, exStyleWarning)),
+		widget.NewFormItem("Move timeout (ms)", timeoutEntry),
+		widget.NewFormItem("Require stable for (ms)", stableEntry),
+		widget.NewFormItem("Priority", priorityEntry),
+		widget.NewFormItem("Stacking order (1 = top, 0 = keep)", orderEntry),
+		widget.NewFormItem("Move strategies", container.NewGridWithColumns(3, strategyChecks...)),
+		widget.NewFormItem("Match on", container.NewVBox(
+			container.NewBorder(nil, nil, widget.NewLabel("Title"), patternCheck, titleEntry),
+			widget.NewLabel("Patterns: * matches any text, ? a single character, /regex/ a regular expression"),
+			container.NewGridWithColumns(2, titleCheck, classCheck, exeCheck, styleCheck, exStyleCheck),
+			iconCheck,
+		)),
+	}
+
+	dialog.ShowForm("Edit saved position", "Save", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		rect, err := rectFromEntries(xEntry, yEntry, widthEntry, heightEntry)
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		var strategies []string
+		for _, object := range strategyChecks {
+			if check := object.(*widget.Check); check.Checked {
+				strategies = append(strategies, check.Text)
+			}
+		}
+		if len(strategies) == 0 {
+			dialog.ShowError(fmt.Errorf("at least one move strategy must be allowed"), wm.mainWindow)
+			return
+		}
+		if slices.Equal(strategies, orderedStrategyNames(nil)) {
+			strategies = nil // All strategies are allowed in the global order
+		}
+
+		updated := *pos
+		updated.X, updated.Y, updated.Width, updated.Height = rect.X, rect.Y, rect.Width, rect.Height
+		updated.Logical = logicalCheck.Checked
+		if updated.Rect() != pos.Rect() || updated.Logical != pos.Logical {
+			// The monitor anchor takes precedence over X and Y when repositioning, so move it along
+			updated = anchorPosition(updated)
+		}
+		updated.VisibleFrame = visibleFrameCheck.Checked
+		updated.UseScript = scriptCheck.Checked
+		updated.FinishWithFocus = focusCheck.Checked
+		updated.ConfirmIfElevated = confirmCheck.Checked
+		updated.ApplyOnlyWhenNew = newOnlyCheck.Checked
+		updated.AlwaysOnTop = topmostCheck.Checked
+		updated.Opacity, _ = strconv.Atoi(opacityEntry.Text)
+		if updated.Opacity > 0 {
+			updated.Opacity = max(minOpacity, min(updated.Opacity, fullOpacity))
+		}
+		if updated.Opacity == fullOpacity {
+			updated.Opacity = 0
+		}
+		updated.ExStyleFlags = 0
+		if layeredCheck.Checked {
+			updated.ExStyleFlags |= WS_EX_LAYERED
+		}
+		if transparentCheck.Checked {
+			updated.ExStyleFlags |= WS_EX_TRANSPARENT
+		}
+		updated.RestoreExStyle = restoreExStyleCheck.Checked
+		updated.MoveTimeoutMilliseconds, _ = strconv.Atoi(timeoutEntry.Text)
+		updated.MoveTimeoutMilliseconds = max(updated.MoveTimeoutMilliseconds, 0)
+		updated.Strategies = strategies
+		updated.StableForMilliseconds, _ = strconv.Atoi(stableEntry.Text)
+		updated.StableForMilliseconds = max(updated.StableForMilliseconds, 0)
+		updated.Priority, _ = strconv.Atoi(priorityEntry.Text)
+		updated.Order, _ = strconv.Atoi(orderEntry.Text)
+		updated.Order = max(updated.Order, 0)
+		updated.State = states[stateSelect.Selected]
+		updated.MaximizeMonitor = maximizeDevices[maximizeSelect.Selected]
+		updated.Schedule = nil
+		if scheduleCheck.Checked {
+			// No day checked means every day
+			schedule := RuleSchedule{From: strings.TrimSpace(fromEntry.Text), Until: strings.TrimSpace(untilEntry.Text)}
+			for _, object := range dayChecks {
+				if check := object.(*widget.Check); check.Checked {
+					schedule.Days = append(schedule.Days, check.Text)
+				}
+			}
+			if err := schedule.validate(); err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			updated.Schedule = &schedule
+		}
+		updated.VirtualDesktop = desktopPolicies[desktopSelect.Selected]
+		if updated.VirtualDesktop == virtualDesktopMove && updated.VirtualDesktopID == "" {
+			// Store the desktop the window is on now
+			desktopID, err := captureDesktopID(identifier, pos.TitlePattern)
+			if err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			updated.VirtualDesktopID = desktopID
+		}
+		newIdentifier := reduceIdentifier(identifier, IdentifierFields{
+			Title:      titleCheck.Checked,
+			ClassName:  classCheck.Checked,
+			Executable: exeCheck.Checked,
+			Style:      styleCheck.Checked,
+			ExStyle:    exStyleCheck.Checked,
+		})
+		updated.TitlePattern = titleCheck.Checked && patternCheck.Checked
+		if titleCheck.Checked && (titleEntry.Text != parts[0] || updated.TitlePattern) {
+			if err := titleEntry.Validate(); err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			newIdentifier = withTitle(newIdentifier, titleEntry.Text)
+		}
+		switch {
+		case !iconCheck.Checked:
+			newIdentifier = withIconHash(newIdentifier, "")
+		case iconHash == "":
+			// Capture the icon from an open window matching the rule
+			hash, err := captureIconHash(newIdentifier, updated.TitlePattern)
+			if err != nil {
+				dialog.ShowError(err, wm.mainWindow)
+				return
+			}
+			newIdentifier = withIconHash(newIdentifier, hash)
+		}
+		if err := wm.storage.ReplacePosition(identifier, newIdentifier, updated); err != nil {
+			log(true, "Failed to save edited position:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		if newIdentifier != identifier {
+			if err := wm.rewriteGroupMembers(func(member string) string {
+				if member == identifier {
+					return newIdentifier
+				}
+				return member
+			}); err != nil {
+				dialog.ShowError(fmt.Errorf("the rule was saved, but its groups could not be updated: %v", err), wm.mainWindow)
+			}
+		}
+		log(debug, "Saved edited position for:", redactIdentifier(newIdentifier))
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
+
+// rectFromEntries parses the rectangle entered in the rule editor.
+// The size must be positive.
+func rectFromEntries(xEntry, yEntry, widthEntry, heightEntry *widget.Entry) (WindowPosition, error) {
+	var values [4]int
+	for i, entry := range []*widget.Entry{xEntry, yEntry, widthEntry, heightEntry} {
+		value, err := strconv.Atoi(strings.TrimSpace(entry.Text))
+		if err != nil {
+			return WindowPosition{}, fmt.Errorf("not a number: %s", entry.Text)
+		}
+		values[i] = value
+	}
+	if values[2] <= 0 || values[3] <= 0 {
+		return WindowPosition{}, fmt.Errorf("width and height must be positive")
+	}
+	return WindowPosition{X: values[0], Y: values[1], Width: values[2], Height: values[3]}, nil
+}
+
+// newIntEntry creates an entry that only accepts integer values.
+func newIntEntry(value int) *widget.Entry {
+	entry := widget.NewEntry()
+	entry.SetText(strconv.Itoa(value))
+	entry.Validator = func(text string) error {
+		if _, err := strconv.Atoi(text); err != nil {
+			return fmt.Errorf("not a number: %s", text)
+		}
+		return nil
+	}
+	return entry
+}
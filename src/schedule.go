@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+/*
+	Rule schedules:
+	- A rule can be limited to days of the week and a time range, e.g. a "focus" layout Mon-Fri 09:00-12:00.
+	- Inactive rules are left out before matching, so a lower priority rule can apply instead.
+	  This holds for the repositioning passes, the window events, the profile preview and snapping
+	  the foreground window. Applying a single rule or a group applies it, as the user chose it explicitly.
+	- From and until must differ. An all-day schedule leaves both empty.
+	- Times are wall clock times in the local time zone, so a schedule follows DST changes.
+	- A range that ends before it starts runs overnight, e.g. 22:00-06:00. The days refer to the day
+	  the range starts, so Fri 22:00-06:00 is also active on Saturday morning.
+*/
+
+// scheduleDays are the names of the days in a schedule, indexed by time.Weekday
+var scheduleDays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+// scheduleClockLayout is the format of the schedule times
+const scheduleClockLayout = "15:04"
+
+// RuleSchedule limits when a rule is active
+type RuleSchedule struct {
+	Days  []string `json:"days,omitempty"`  // Days the range starts on ("Mon".."Sun"), empty for every day
+	From  string   `json:"from,omitempty"`  // Start time "HH:MM", empty for the start of the day
+	Until string   `json:"until,omitempty"` // End time "HH:MM" (exclusive), empty for the end of the day
+}
+
+// parseScheduleClock returns the minutes since midnight of a schedule time.
+func parseScheduleClock(clock string, fallback int) (int, error) {
+	if clock == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(scheduleClockLayout, clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', use HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validate checks the days and times of the schedule.
+func (s RuleSchedule) validate() error {
+	for _, day := range s.Days {
+		if !slices.Contains(scheduleDays, day) {
+			return fmt.Errorf("invalid day '%s'", day)
+		}
+	}
+	from, err := parseScheduleClock(s.From, 0)
+	if err != nil {
+		return err
+	}
+	until, err := parseScheduleClock(s.Until, 24*60)
+	if err != nil {
+		return err
+	}
+	if from == until {
+		return fmt.Errorf("the start and end time are the same, leave both empty for the whole day")
+	}
+	return nil
+}
+
+// activeOn checks if the schedule includes a day. No days means every day.
+func (s RuleSchedule) activeOn(day time.Weekday) bool {
+	return len(s.Days) == 0 || slices.Contains(s.Days, scheduleDays[day])
+}
+
+// activeAt checks if the schedule is active at the given time, in its local wall clock time.
+func (s RuleSchedule) activeAt(t time.Time) bool {
+	from, err := parseScheduleClock(s.From, 0)
+	if err != nil {
+		return false
+	}
+	until, err := parseScheduleClock(s.Until, 24*60)
+	if err != nil {
+		return false
+	}
+	local := t.Local()
+	minute := local.Hour()*60 + local.Minute()
+	if from <= until {
+		return s.activeOn(local.Weekday()) && minute >= from && minute < until
+	}
+	// Overnight: the evening part belongs to today, the morning part to yesterday
+	if minute >= from {
+		return s.activeOn(local.Weekday())
+	}
+	return minute < until && s.activeOn((local.Weekday()+6)%7)
+}
+
+// String returns the schedule in a short form like "Mon Tue 09:00-12:00".
+func (s RuleSchedule) String() string {
+	days := "Every day"
+	if len(s.Days) > 0 {
+		days = strings.Join(s.Days, " ")
+	}
+	from, until := s.From, s.Until
+	if from == "" {
+		from = "00:00"
+	}
+	if until == "" {
+		until = "24:00"
+	}
+	return days + " " + from + "-" + until
+}
+
+// activeRules returns the rules whose schedule is active at the given time.
+func activeRules(positions map[string]WindowPosition, now time.Time) map[string]WindowPosition {
+	active := make(map[string]WindowPosition, len(positions))
+	for identifier, pos := range positions {
+		if pos.Schedule == nil || pos.Schedule.activeAt(now) {
+			active[identifier] = pos
+		}
+	}
+	return active
+}
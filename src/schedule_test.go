@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRuleScheduleActiveAt checks day ranges, open ends and overnight ranges.
+// 2024-01-01 is a Monday, January avoids DST changes.
+func TestRuleScheduleActiveAt(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.Local)
+	}
+	weekdayMornings := RuleSchedule{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, From: "09:00", Until: "12:00"}
+	fridayNight := RuleSchedule{Days: []string{"Fri"}, From: "22:00", Until: "06:00"}
+	tests := []struct {
+		name     string
+		schedule RuleSchedule
+		time     time.Time
+		want     bool
+	}{
+		{"empty schedule", RuleSchedule{}, at(7, 3, 0), true},
+		{"range start is inclusive", weekdayMornings, at(1, 9, 0), true},
+		{"inside the range", weekdayMornings, at(1, 11, 59), true},
+		{"range end is exclusive", weekdayMornings, at(1, 12, 0), false},
+		{"before the range", weekdayMornings, at(1, 8, 59), false},
+		{"day not in the schedule", weekdayMornings, at(6, 10, 0), false},
+		{"only from", RuleSchedule{From: "18:00"}, at(2, 18, 0), true},
+		{"before from", RuleSchedule{From: "18:00"}, at(2, 17, 59), false},
+		{"only until", RuleSchedule{Until: "06:00"}, at(2, 5, 59), true},
+		{"after until", RuleSchedule{Until: "06:00"}, at(2, 6, 0), false},
+		{"overnight evening", fridayNight, at(5, 23, 0), true},
+		{"overnight start", fridayNight, at(5, 22, 0), true},
+		{"overnight morning of the next day", fridayNight, at(6, 5, 59), true},
+		{"overnight end is exclusive", fridayNight, at(6, 6, 0), false},
+		{"overnight evening of the next day", fridayNight, at(6, 23, 0), false},
+		{"overnight morning of the start day", fridayNight, at(5, 5, 0), false},
+		{"overnight evening of another day", fridayNight, at(4, 23, 0), false},
+		{"overnight morning across the week", RuleSchedule{Days: []string{"Sun"}, From: "22:00", Until: "06:00"}, at(1, 1, 0), true},
+		{"invalid time", RuleSchedule{From: "25:00"}, at(1, 10, 0), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.schedule.activeAt(test.time); got != test.want {
+				t.Errorf("%v.activeAt(%v) = %v, want %v", test.schedule, test.time.Format("Mon 15:04"), got, test.want)
+			}
+		})
+	}
+}
+
+// TestRuleScheduleValidate checks that unusable schedules are rejected.
+func TestRuleScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule RuleSchedule
+		valid    bool
+	}{
+		{"empty", RuleSchedule{}, true},
+		{"range", RuleSchedule{Days: []string{"Mon"}, From: "09:00", Until: "12:00"}, true},
+		{"overnight", RuleSchedule{From: "22:00", Until: "06:00"}, true},
+		{"invalid day", RuleSchedule{Days: []string{"Monday"}}, false},
+		{"invalid time", RuleSchedule{From: "9am"}, false},
+		{"equal times", RuleSchedule{From: "09:00", Until: "09:00"}, false},
+		{"empty from equals until", RuleSchedule{Until: "00:00"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.schedule.validate(); (err == nil) != test.valid {
+				t.Errorf("validate() = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
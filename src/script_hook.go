@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+/*
+	Script hook:
+	- Rules with UseScript set get their target position from an external script or executable.
+	- The program receives a JSON object {"window": WindowInfo, "savedPosition": WindowPosition} on stdin
+	  and must print the target rectangle {"x":..,"y":..,"width":..,"height":..} as JSON on stdout.
+	- The program is killed after the configured timeout, and its output is validated before use.
+	  Its output is collected in a buffer, and the pipes are closed scriptWaitDelay after it exited or
+	  was killed, so a child process that inherited stdout cannot block the pass.
+	- The monitoring service runs the script once per window and keeps its target until the window
+	  moves away from it, see scriptedTarget. Snapping the foreground window always runs the script.
+*/
+
+// maxScriptOutput limits how much output of the script is kept
+const maxScriptOutput = 64 * 1024
+
+// scriptWaitDelay is how long the output pipes are kept open after the script exited or was killed
+const scriptWaitDelay = time.Second
+
+// limitedBuffer keeps the first max bytes written to it and discards the rest,
+// so a script printing endlessly neither blocks nor exhausts the memory
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+// Write stores what fits into the buffer and reports everything as written.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// scriptTarget is the target the script hook computed for a window
+type scriptTarget struct {
+	Identifier string         // Rule the script ran for
+	Rect       RECT           // Window rectangle when the script ran or after the window was moved
+	Target     WindowPosition // Position computed by the script
+}
+
+// scriptInput is the JSON document passed to the script on stdin
+type scriptInput struct {
+	Window        WindowInfo     `json:"window"`
+	SavedPosition WindowPosition `json:"savedPosition"`
+}
+
+// runPositionScript runs the script hook for a window and returns the position it computed.
+func runPositionScript(ctx context.Context, path string, timeout time.Duration, window WindowInfo, saved WindowPosition) (*WindowPosition, error) {
+	debug := false
+	if path == "" {
+		return nil, fmt.Errorf("no script configured")
+	}
+
+	input, err := json.Marshal(scriptInput{Window: window, SavedPosition: saved})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode script input: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	cmd.Stdin = bytes.NewReader(input)
+	stdout := &limitedBuffer{max: maxScriptOutput}
+	stderr := &limitedBuffer{max: maxScriptOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = scriptWaitDelay
+
+	log(debug, "Running position script:", path)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start script: %v", err)
+	}
+	waitErr := cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("script timed out after %v", timeout)
+	}
+	if waitErr != nil && !errors.Is(waitErr, exec.ErrWaitDelay) {
+		return nil, fmt.Errorf("script failed: %v: %s", waitErr, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var pos WindowPosition
+	if err := json.Unmarshal(stdout.Bytes(), &pos); err != nil {
+		return nil, fmt.Errorf("invalid script output: %v", err)
+	}
+	if err := validateScriptPosition(pos); err != nil {
+		return nil, err
+	}
+	log(debug, "Script computed position:", pos.X, pos.Y, pos.Width, pos.Height)
+	return &pos, nil
+}
+
+// scriptedTarget returns the target the script hook computes for a window of a rule. The script only
+// runs for windows it did not run for yet and for windows that moved since, otherwise the previous
+// target is returned.
+func (wm *WindowManager) scriptedTarget(identifier string, window WindowInfo, pos WindowPosition, settings Settings) (WindowPosition, error) {
+	wm.scriptTargetsMutex.Lock()
+	cached, ok := wm.scriptTargets[window.Handle]
+	wm.scriptTargetsMutex.Unlock()
+	if ok && cached.Identifier == identifier &&
+		(withinPositionTolerance(window.WindowRect, cached.Rect) || withinPositionTolerance(window.WindowRect, cached.Target.Rect())) {
+		return cached.Target, nil
+	}
+	timeout := time.Duration(settings.ScriptTimeoutSeconds) * time.Second
+	scripted, err := runPositionScript(wm.ctx, settings.ScriptPath, timeout, window, pos)
+	if err != nil {
+		return WindowPosition{}, err
+	}
+	wm.scriptTargetsMutex.Lock()
+	defer wm.scriptTargetsMutex.Unlock()
+	if wm.scriptTargets == nil {
+		wm.scriptTargets = make(map[syscall.Handle]scriptTarget)
+	}
+	wm.scriptTargets[window.Handle] = scriptTarget{Identifier: identifier, Rect: window.WindowRect, Target: *scripted}
+	return *scripted, nil
+}
+
+// scriptedWindowMoved records where a window with a scripted target ended up after it was moved,
+// which may differ from the target after clamping, so the script does not run again for it.
+func (wm *WindowManager) scriptedWindowMoved(hwnd syscall.Handle, rect RECT) {
+	wm.scriptTargetsMutex.Lock()
+	defer wm.scriptTargetsMutex.Unlock()
+	if cached, ok := wm.scriptTargets[hwnd]; ok {
+		cached.Rect = rect
+		wm.scriptTargets[hwnd] = cached
+	}
+}
+
+// pruneScriptTargets forgets the targets of closed windows, whose handles Windows reuses.
+func (wm *WindowManager) pruneScriptTargets() {
+	wm.scriptTargetsMutex.Lock()
+	defer wm.scriptTargetsMutex.Unlock()
+	for hwnd := range wm.scriptTargets {
+		if !isValidWindow(hwnd) {
+			delete(wm.scriptTargets, hwnd)
+		}
+	}
+}
+
+// validateScriptPosition rejects positions that are unusable or outside of the virtual screen.
+func validateScriptPosition(pos WindowPosition) error {
+	const maxSize = 32767 // Largest window size Windows accepts
+	if pos.Width <= 0 || pos.Height <= 0 || pos.Width > maxSize || pos.Height > maxSize {
+		return fmt.Errorf("script returned an invalid size: %dx%d", pos.Width, pos.Height)
+	}
+	if !isRectOnScreen(pos.Rect(), getVirtualScreenRect()) {
+		return fmt.Errorf("script returned a position outside of the screen: %d,%d", pos.X, pos.Y)
+	}
+	return nil
+}
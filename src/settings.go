@@ -0,0 +1,247 @@
+package main
+
+import (
+	"maps"
+)
+
+// Settings holds the user configurable settings of the application.
+// They are stored next to the saved positions by the PositionStorage.
+type Settings struct {
+	// Position slots: pressing a slot key moves the foreground window to the slot,
+	// pressing it together with the save modifier stores the foreground window's position.
+	SlotsEnabled     bool                      `json:"slotsEnabled"`
+	SlotKeys         []string                  `json:"slotKeys"`
+	SlotSaveModifier string                    `json:"slotSaveModifier"`
+	Slots            map[string]WindowPosition `json:"slots,omitempty"`
+
+	// Hotkey that moves the foreground window to the saved position matching it, empty to disable
+	SnapHotkey string `json:"snapHotkey,omitempty"`
+
+	// Hotkey that saves the position of the foreground window as a rule, empty to disable
+	SaveHotkey string `json:"saveHotkey,omitempty"`
+
+	// Hotkey that centers the foreground window on its monitor, empty to disable, see tiling.go
+	CenterHotkey string `json:"centerHotkey,omitempty"`
+
+	// Hotkey that puts the windows of the last arrangement back, empty to disable, see undo.go
+	UndoHotkey string `json:"undoHotkey,omitempty"`
+
+	// Hotkeys that tile the foreground window, by layout ID (see tileLayouts)
+	TileHotkeys map[string]string `json:"tileHotkeys,omitempty"`
+
+	// Order of the visible windows list, see sortWindows
+	WindowListSort       string `json:"windowListSort,omitempty"`
+	WindowListDescending bool   `json:"windowListDescending,omitempty"`
+
+	// Window attributes that compose the identifier of newly saved positions
+	IdentifierFields IdentifierFields `json:"identifierFields"`
+
+	// Script hook: an external program that computes positions for rules using it
+	ScriptPath           string `json:"scriptPath,omitempty"`
+	ScriptTimeoutSeconds int    `json:"scriptTimeoutSeconds"`
+
+	// Accept commands of other programs on the control pipe
+	ControlPipeEnabled bool `json:"controlPipeEnabled"`
+
+	// Groups of saved positions that are applied and moved together
+	Groups []WindowGroup `json:"groups,omitempty"`
+
+	// Policy for saved positions that overlap during a repositioning pass
+	ConflictPolicy string `json:"conflictPolicy"`
+
+	// Time budget for moving a single window, 0 means no limit. Rules can override it.
+	MoveTimeoutMilliseconds int `json:"moveTimeoutMilliseconds"`
+
+	// Rules that only apply to new windows apply during this period after a window was first seen
+	NewWindowGraceSeconds int `json:"newWindowGraceSeconds"`
+
+	// Ask before moving windows of elevated processes, in addition to rules asking for it
+	ConfirmElevatedMoves bool `json:"confirmElevatedMoves"`
+
+	// Move windows of elevated processes through an elevated helper process, started via UAC on demand
+	UseElevatedHelper bool `json:"useElevatedHelper"`
+
+	// Try the move strategy that historically succeeded most often for an executable first,
+	// for rules that did not record a successful strategy yet
+	LearnStrategyOrder bool `json:"learnStrategyOrder"`
+
+	// Order in which the move strategies are tried, unlisted strategies follow in the default order.
+	// Empty keeps the default order. Rules with their own strategies use their order instead.
+	StrategyOrder []string `json:"strategyOrder,omitempty"`
+
+	// Apply windows from the bottom of the Z-order to the top to keep the stacking order
+	RestoreBackToFront bool `json:"restoreBackToFront"`
+
+	// Default rule: windows that appear in this session and match no rule are centered at this size once
+	DefaultRuleEnabled bool `json:"defaultRuleEnabled"`
+	DefaultRuleWidth   int  `json:"defaultRuleWidth"`
+	DefaultRuleHeight  int  `json:"defaultRuleHeight"`
+
+	// Interval of the monitoring service that keeps windows in their saved positions, 0 disables it.
+	// Startup and manual repositioning are not affected.
+	MonitorIntervalSeconds int `json:"monitorIntervalSeconds"`
+
+	// The monitoring service leaves a moved window alone for this time (0 = never),
+	// unless it drifted more than the tolerance, see reposition_cooldown.go
+	RepositionCooldownSeconds int `json:"repositionCooldownSeconds"`
+	DriftTolerancePixels      int `json:"driftTolerancePixels"`
+
+	// A window whose position and size differ from the target by at most this many pixels
+	// is considered in place and not moved, so frame adjustments don't cause a move every cycle
+	PositionTolerancePixels int `json:"positionTolerancePixels"`
+
+	// Save the visible frame of windows instead of the window rectangle with its invisible borders,
+	// so windows placed flush with a screen edge leave no gap, see frame_bounds.go
+	SaveVisibleFrame bool `json:"saveVisibleFrame"`
+
+	// When windows are positioned automatically: off, only at startup, once per window and session
+	// or continuously by the monitoring service, see reposition_mode.go
+	RepositionMode string `json:"repositionMode,omitempty"`
+
+	// Reposition windows as soon as they appear or move, using window event hooks instead of the interval
+	WatchWindowEvents bool `json:"watchWindowEvents"`
+
+	// Show notifications for important events, like applied profiles and windows that cannot be moved, see notify.go
+	ShowNotifications bool `json:"showNotifications"`
+
+	// Relaunch as administrator at start and start with Windows by an elevated scheduled task, see elevated_startup.go
+	AlwaysRunElevated bool `json:"alwaysRunElevated,omitempty"`
+
+	// Skip the monitoring cycles while a fullscreen window is in the foreground, so games are not disturbed
+	PauseForFullscreen bool `json:"pauseForFullscreen"`
+
+	// List tool windows (floating palettes, no taskbar button), which Alt+Tab leaves out.
+	// Saved rules apply to them either way, see owned_windows.go
+	IncludeToolWindows bool `json:"includeToolWindows,omitempty"`
+
+	// List windows with an owner (popups, dialogs, palettes), which Alt+Tab leaves out.
+	// Saved rules apply to them either way
+	IncludeOwnedWindows bool `json:"includeOwnedWindows,omitempty"`
+
+	// Windows of these executables (file names) and classes are never listed or positioned, see exclusions.go
+	ExcludedExecutables []string `json:"excludedExecutables,omitempty"`
+	ExcludedClasses     []string `json:"excludedClasses,omitempty"`
+
+	// Regular expressions by executable (file name) that normalize window titles before matching,
+	// see title_normalize.go
+	TitleNormalizations map[string]string `json:"titleNormalizations,omitempty"`
+
+	// Startup gate: the startup repositioning waits until these executables have windows,
+	// or until the timeout expires
+	WaitForApps               []string `json:"waitForApps,omitempty"`
+	WaitForAppsTimeoutSeconds int      `json:"waitForAppsTimeoutSeconds"`
+
+	// Keep the log in memory only, so window titles and paths are never written to disk
+	LogMemoryOnly bool `json:"logMemoryOnly"`
+
+	// Minimum level of the logged messages: debug, info, warn or error
+	LogLevel string `json:"logLevel"`
+
+	// Rotate the log file when it exceeds this size (0 = never) and keep this many rotated files
+	LogMaxSizeMB int `json:"logMaxSizeMB"`
+	LogBackups   int `json:"logBackups"`
+
+	// Replace window titles in the log by a hash, so logs can be shared without leaking document names
+	RedactTitles bool `json:"redactTitles"`
+
+	// Pruning: rules that did not match a window for PruneDays are offered for removal on startup
+	PruneEnabled bool `json:"pruneEnabled"`
+	PruneDays    int  `json:"pruneDays"`
+
+	// Offer to merge saved positions that match the same windows on startup
+	MergeDuplicates bool `json:"mergeDuplicates"`
+
+	// Profiles switched to when the power source changes, empty to keep the active profile
+	BatteryProfile string `json:"batteryProfile,omitempty"`
+	ACProfile      string `json:"acProfile,omitempty"`
+
+	// Show the windows a profile would move before applying it from the menu or the tray
+	PreviewProfiles bool `json:"previewProfiles"`
+}
+
+// defaultSettings returns the settings used when no settings file exists.
+// Settings missing in an existing file keep these defaults.
+func defaultSettings() Settings {
+	return Settings{
+		SlotsEnabled:     false,
+		SlotKeys:         []string{"F1", "F2", "F3", "F4"},
+		SlotSaveModifier: "Ctrl",
+		Slots:            make(map[string]WindowPosition),
+		IdentifierFields: allIdentifierFields(),
+
+		ScriptTimeoutSeconds: 5,
+
+		ConflictPolicy:         conflictOverlap,
+		NewWindowGraceSeconds:  30,
+		MonitorIntervalSeconds: 10,
+
+		RepositionCooldownSeconds: 60,
+		DriftTolerancePixels:      10,
+		PositionTolerancePixels:   4,
+		RepositionMode:            repositionContinuous,
+		WatchWindowEvents:         true,
+		PauseForFullscreen:        true,
+		ShowNotifications:         true,
+
+		DefaultRuleEnabled: false,
+		DefaultRuleWidth:   1280,
+		DefaultRuleHeight:  800,
+
+		WaitForAppsTimeoutSeconds: 60,
+
+		LogLevel:     "info",
+		LogMaxSizeMB: 5,
+		LogBackups:   3,
+
+		PruneEnabled: false,
+		PruneDays:    90,
+
+		MergeDuplicates: true,
+
+		PreviewProfiles: true,
+	}
+}
+
+// clone returns a deep copy of the settings, so callers can modify it freely.
+func (s Settings) clone() Settings {
+	s.SlotKeys = append([]string(nil), s.SlotKeys...)
+	s.WaitForApps = append([]string(nil), s.WaitForApps...)
+	s.ExcludedExecutables = append([]string(nil), s.ExcludedExecutables...)
+	s.ExcludedClasses = append([]string(nil), s.ExcludedClasses...)
+	s.Slots = maps.Clone(s.Slots)
+	s.TileHotkeys = maps.Clone(s.TileHotkeys)
+	s.TitleNormalizations = maps.Clone(s.TitleNormalizations)
+	groups := make([]WindowGroup, len(s.Groups))
+	for i, group := range s.Groups {
+		groups[i] = WindowGroup{Name: group.Name, Members: append([]string(nil), group.Members...)}
+	}
+	s.Groups = groups
+	if s.Slots == nil {
+		s.Slots = make(map[string]WindowPosition)
+	}
+	return s
+}
+
+// getSettings returns a copy of the current settings.
+// It locks the mutex to ensure thread-safe access to the settings.
+func (wm *WindowManager) getSettings() Settings {
+	wm.settingsMutex.RLock()
+	defer wm.settingsMutex.RUnlock()
+	return wm.settings.clone()
+}
+
+// updateSettings applies a modification to the settings and persists them.
+// It locks the mutex to ensure thread-safe access to the settings.
+func (wm *WindowManager) updateSettings(modify func(s *Settings)) error {
+	wm.settingsMutex.Lock()
+	defer wm.settingsMutex.Unlock()
+	updated := wm.settings.clone()
+	modify(&updated)
+	if err := wm.storage.SaveSettings(updated); err != nil {
+		logError("Failed to save settings:", err)
+		recordProblem(problemError, "", "Failed to save settings:", err)
+		return err
+	}
+	wm.settings = updated
+	return nil
+}
@@ -0,0 +1,755 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// settingsTab is a tab of the settings window.
+// apply copies the values of the tab's widgets into the settings when the user saves.
+type settingsTab struct {
+	title   string
+	content fyne.CanvasObject
+	apply   func(s *Settings) error
+}
+
+// showSettingsWindow opens the settings window, or brings it to the front if it is already open.
+func (wm *WindowManager) showSettingsWindow() {
+	debug := true
+	if wm.settingsWindow != nil {
+		wm.settingsWindow.Show()
+		wm.settingsWindow.RequestFocus()
+		return
+	}
+	log(debug, "Opening settings window.")
+
+	window := wm.app.NewWindow(strProductName + " Settings")
+	settings := wm.getSettings()
+	tabs := []settingsTab{
+		wm.positioningSettingsTab(settings, window),
+		wm.matchingSettingsTab(settings),
+		wm.hotkeySettingsTab(settings),
+		wm.scriptSettingsTab(settings),
+		wm.pruneSettingsTab(settings, window),
+		wm.powerSettingsTab(settings),
+		wm.loggingSettingsTab(settings),
+		wm.backupSettingsTab(window),
+	}
+
+	appTabs := container.NewAppTabs()
+	for _, tab := range tabs {
+		appTabs.Append(container.NewTabItem(tab.title, container.NewVScroll(tab.content)))
+	}
+
+	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), safeCallback(func() {
+		previous := wm.getSettings()
+		updated := previous.clone()
+		for _, tab := range tabs {
+			if err := tab.apply(&updated); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+		}
+		if err := wm.updateSettings(func(s *Settings) { *s = updated }); err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save settings: %v", err), window)
+			return
+		}
+		log(debug, "Settings saved.")
+		wm.applySettings(window, previous)
+	}))
+	closeBtn := widget.NewButtonWithIcon("Close", theme.CancelIcon(), func() {
+		window.Close()
+	})
+
+	window.SetContent(container.NewBorder(nil,
+		container.New(layout.NewGridLayout(2), saveBtn, closeBtn),
+		nil, nil, appTabs))
+	window.Resize(fyne.NewSize(500, 400))
+	window.SetOnClosed(func() {
+		wm.settingsWindow = nil
+	})
+	wm.settingsWindow = window
+	window.Show()
+}
+
+// applySettings activates changed settings at runtime.
+// Problems are reported in a dialog on the given window.
+func (wm *WindowManager) applySettings(window fyne.Window, previous Settings) {
+	settings := wm.getSettings()
+	logRedactTitles.Store(settings.RedactTitles)
+	setLogLevel(parseLogLevel(settings.LogLevel))
+	setTitleNormalizations(settings.TitleNormalizations)
+	setPositionTolerance(settings.PositionTolerancePixels)
+	setLogRotation(int64(settings.LogMaxSizeMB)*1024*1024, settings.LogBackups)
+	wm.setMonitorInterval(time.Duration(settings.MonitorIntervalSeconds) * time.Second)
+	if settings.RepositionMode != previous.RepositionMode {
+		wm.resetMonitoring()
+		wm.updateSystemTray()
+	}
+	if err := wm.reloadHotkeys(); err != nil {
+		dialog.ShowError(err, window)
+	}
+	if settings.ControlPipeEnabled != previous.ControlPipeEnabled {
+		if err := wm.reloadControlPipe(); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+	if settings.WatchWindowEvents != previous.WatchWindowEvents {
+		if err := wm.reloadWindowEvents(); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+	if settings.AlwaysRunElevated != previous.AlwaysRunElevated {
+		wm.moveStartupRegistration(window, settings.AlwaysRunElevated)
+	}
+	if settings.LogMemoryOnly != previous.LogMemoryOnly {
+		if err := configureLogging(settings.LogMemoryOnly); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+	if !slices.Equal(settings.ExcludedExecutables, previous.ExcludedExecutables) ||
+		!slices.Equal(settings.ExcludedClasses, previous.ExcludedClasses) ||
+		!maps.Equal(settings.TitleNormalizations, previous.TitleNormalizations) ||
+		settings.IncludeToolWindows != previous.IncludeToolWindows ||
+		settings.IncludeOwnedWindows != previous.IncludeOwnedWindows {
+		wm.refreshWindowList()
+	}
+	if settings.IdentifierFields != previous.IdentifierFields {
+		wm.offerIdentifierMigration(window, settings.IdentifierFields)
+	}
+}
+
+// offerIdentifierMigration asks the user whether the existing saved positions should be
+// rewritten to the new identifier composition, and rewrites them if confirmed.
+func (wm *WindowManager) offerIdentifierMigration(window fyne.Window, fields IdentifierFields) {
+	message := "The identifier composition changed.\n" +
+		"Also remove the deselected attributes from the existing saved positions?\n" +
+		"Attributes that are removed cannot be restored later."
+	dialog.ShowConfirm("Migrate saved positions", message, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		rewrite := func(identifier string) string {
+			return reduceIdentifier(identifier, fields)
+		}
+		changed, err := wm.storage.RewriteIdentifiers(rewrite)
+		if err != nil {
+			log(true, "Failed to migrate identifiers:", err)
+			dialog.ShowError(err, window)
+			return
+		}
+		if err := wm.rewriteGroupMembers(rewrite); err != nil {
+			dialog.ShowError(fmt.Errorf("the identifiers were migrated, but the groups could not be updated: %v", err), window)
+		}
+		log(true, "Migrated", changed, "identifiers to the new composition.")
+		wm.setupMainWindowContent() // Refresh the UI
+	}, window)
+}
+
+// matchingSettingsTab creates the settings tab for the window matching.
+func (wm *WindowManager) matchingSettingsTab(settings Settings) settingsTab {
+	titleCheck := widget.NewCheck("Title", nil)
+	titleCheck.SetChecked(settings.IdentifierFields.Title)
+	classCheck := widget.NewCheck("Class name", nil)
+	classCheck.SetChecked(settings.IdentifierFields.ClassName)
+	exeCheck := widget.NewCheck("Executable", nil)
+	exeCheck.SetChecked(settings.IdentifierFields.Executable)
+	styleCheck := widget.NewCheck("Style", nil)
+	styleCheck.SetChecked(settings.IdentifierFields.Style)
+	exStyleCheck := widget.NewCheck("Extended style", nil)
+	exStyleCheck.SetChecked(settings.IdentifierFields.ExStyle)
+
+	excludedExeEntry := widget.NewEntry()
+	excludedExeEntry.SetPlaceHolder("code.exe, idea64.exe")
+	excludedExeEntry.SetText(strings.Join(settings.ExcludedExecutables, ", "))
+	excludedClassEntry := widget.NewEntry()
+	excludedClassEntry.SetText(strings.Join(settings.ExcludedClasses, ", "))
+	toolWindowsCheck := widget.NewCheck("Include tool windows (floating palettes without a taskbar button)", nil)
+	toolWindowsCheck.SetChecked(settings.IncludeToolWindows)
+	ownedWindowsCheck := widget.NewCheck("Include owned windows (popups, dialogs and palettes of other windows)", nil)
+	ownedWindowsCheck.SetChecked(settings.IncludeOwnedWindows)
+	normalizationEntry := widget.NewMultiLineEntry()
+	normalizationEntry.SetPlaceHolder("firefox.exe = — Mozilla Firefox$")
+	normalizationEntry.SetText(formatTitleNormalizations(settings.TitleNormalizations))
+	normalizationEntry.SetMinRowsVisible(3)
+
+	help := widget.NewLabel("Attributes used to recognize a window when saving its position.\n" +
+		"Style bits often change with application updates, deselect them if saved positions stop matching.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title: "Matching",
+		content: container.NewVBox(
+			widget.NewLabel("Match windows on:"),
+			container.NewGridWithColumns(2, titleCheck, classCheck, exeCheck, styleCheck, exStyleCheck),
+			help,
+			widget.NewForm(
+				widget.NewFormItem("Ignored apps", excludedExeEntry),
+				widget.NewFormItem("Ignored classes", excludedClassEntry),
+			),
+			widget.NewLabel("Windows of ignored apps and classes are never listed or positioned."),
+			toolWindowsCheck,
+			ownedWindowsCheck,
+			widget.NewLabel("Title normalization, one \"app.exe = pattern\" per line:"),
+			normalizationEntry,
+			widget.NewLabel("Matches of the pattern are removed from the app's titles, with a capture group only the group is kept."),
+		),
+		apply: func(s *Settings) error {
+			fields := IdentifierFields{
+				Title:      titleCheck.Checked,
+				ClassName:  classCheck.Checked,
+				Executable: exeCheck.Checked,
+				Style:      styleCheck.Checked,
+				ExStyle:    exStyleCheck.Checked,
+			}
+			if fields == (IdentifierFields{}) {
+				return fmt.Errorf("at least one attribute must be used for matching")
+			}
+			s.IdentifierFields = fields
+			s.ExcludedExecutables = commaList(excludedExeEntry.Text)
+			s.ExcludedClasses = commaList(excludedClassEntry.Text)
+			s.IncludeToolWindows = toolWindowsCheck.Checked
+			s.IncludeOwnedWindows = ownedWindowsCheck.Checked
+			normalizations, err := parseTitleNormalizations(normalizationEntry.Text)
+			if err != nil {
+				return err
+			}
+			s.TitleNormalizations = normalizations
+			return nil
+		},
+	}
+}
+
+// hotkeySettingsTab creates the settings tab for the hotkeys.
+func (wm *WindowManager) hotkeySettingsTab(settings Settings) settingsTab {
+	slotsCheck := widget.NewCheck("Enable position slots", nil)
+	slotsCheck.SetChecked(settings.SlotsEnabled)
+	slotKeysEntry := widget.NewEntry()
+	slotKeysEntry.SetText(strings.Join(settings.SlotKeys, ", "))
+	slotModifierSelect := widget.NewSelect([]string{"Ctrl", "Alt", "Shift", "Ctrl+Alt", "Ctrl+Shift"}, nil)
+	slotModifierSelect.SetSelected(settings.SlotSaveModifier)
+	snapEntry := widget.NewEntry()
+	snapEntry.SetPlaceHolder("Ctrl+Alt+R")
+	snapEntry.SetText(settings.SnapHotkey)
+	saveEntry := widget.NewEntry()
+	saveEntry.SetPlaceHolder("Ctrl+Alt+S")
+	saveEntry.SetText(settings.SaveHotkey)
+	centerEntry := widget.NewEntry()
+	centerEntry.SetPlaceHolder("Ctrl+Alt+C")
+	centerEntry.SetText(settings.CenterHotkey)
+	undoEntry := widget.NewEntry()
+	undoEntry.SetPlaceHolder("Ctrl+Alt+Z")
+	undoEntry.SetText(settings.UndoHotkey)
+
+	form := widget.NewForm(
+		widget.NewFormItem("", slotsCheck),
+		widget.NewFormItem("Slot keys", slotKeysEntry),
+		widget.NewFormItem("Save modifier", slotModifierSelect),
+		widget.NewFormItem("Snap to saved position", snapEntry),
+		widget.NewFormItem("Save position", saveEntry),
+		widget.NewFormItem("Center on monitor", centerEntry),
+		widget.NewFormItem("Undo last arrangement", undoEntry),
+	)
+	tileEntries := make(map[string]*widget.Entry, len(tileLayouts))
+	for _, layout := range tileLayouts {
+		entry := widget.NewEntry()
+		entry.SetText(settings.TileHotkeys[layout.ID])
+		tileEntries[layout.ID] = entry
+		form.Append(layout.Name, entry)
+	}
+	help := widget.NewLabel("Press a slot key to move the foreground window to the slot.\n" +
+		"Press it with the save modifier to store the foreground window's position in the slot.\n" +
+		"The snap hotkey moves the foreground window to its saved position, the save hotkey saves it. " +
+		"The center hotkey centers it on its monitor. The undo hotkey puts the windows moved last back where they were. " +
+		"The tiling hotkeys move it to a half or quarter of the monitor it is on. " +
+		"Leave them empty to disable them. Hotkeys taken by another app are listed under Problems in the log window.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title:   "Hotkeys",
+		content: container.NewVBox(form, help),
+		apply: func(s *Settings) error {
+			var keys []string
+			for _, key := range strings.Split(slotKeysEntry.Text, ",") {
+				key = strings.TrimSpace(key)
+				if key == "" {
+					continue
+				}
+				if _, _, err := parseHotkey(key); err != nil {
+					return fmt.Errorf("invalid slot key: %v", err)
+				}
+				keys = append(keys, key)
+			}
+			snap := strings.TrimSpace(snapEntry.Text)
+			if snap != "" {
+				if _, _, err := parseHotkey(snap); err != nil {
+					return fmt.Errorf("invalid snap hotkey: %v", err)
+				}
+			}
+			save := strings.TrimSpace(saveEntry.Text)
+			if save != "" {
+				if _, _, err := parseHotkey(save); err != nil {
+					return fmt.Errorf("invalid save hotkey: %v", err)
+				}
+			}
+			center := strings.TrimSpace(centerEntry.Text)
+			if center != "" {
+				if _, _, err := parseHotkey(center); err != nil {
+					return fmt.Errorf("invalid center hotkey: %v", err)
+				}
+			}
+			undo := strings.TrimSpace(undoEntry.Text)
+			if undo != "" {
+				if _, _, err := parseHotkey(undo); err != nil {
+					return fmt.Errorf("invalid undo hotkey: %v", err)
+				}
+			}
+			tile := make(map[string]string)
+			for _, layout := range tileLayouts {
+				combo := strings.TrimSpace(tileEntries[layout.ID].Text)
+				if combo == "" {
+					continue
+				}
+				if _, _, err := parseHotkey(combo); err != nil {
+					return fmt.Errorf("invalid hotkey for %s: %v", layout.Name, err)
+				}
+				tile[layout.ID] = combo
+			}
+			s.SnapHotkey = snap
+			s.SaveHotkey = save
+			s.CenterHotkey = center
+			s.UndoHotkey = undo
+			s.TileHotkeys = tile
+			s.SlotsEnabled = slotsCheck.Checked
+			s.SlotKeys = keys
+			s.SlotSaveModifier = slotModifierSelect.Selected
+			return nil
+		},
+	}
+}
+
+// scriptSettingsTab creates the settings tab for the script hook.
+func (wm *WindowManager) scriptSettingsTab(settings Settings) settingsTab {
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder(`C:\Scripts\position.exe`)
+	pathEntry.SetText(settings.ScriptPath)
+	timeoutEntry := newIntEntry(settings.ScriptTimeoutSeconds)
+	controlCheck := widget.NewCheck("Accept commands from other programs on "+controlPipeName, nil)
+	controlCheck.SetChecked(settings.ControlPipeEnabled)
+
+	help := widget.NewLabel("Rules with \"Compute the position with the script hook\" run this program.\n" +
+		"It receives the window info as JSON on stdin and must print the target rectangle " +
+		"{\"x\":0,\"y\":0,\"width\":800,\"height\":600} as JSON on stdout.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title: "Script",
+		content: container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("Script path", pathEntry),
+				widget.NewFormItem("Timeout (seconds)", timeoutEntry),
+			),
+			help,
+			controlCheck,
+		),
+		apply: func(s *Settings) error {
+			timeout, err := strconv.Atoi(timeoutEntry.Text)
+			if err != nil || timeout < 1 {
+				return fmt.Errorf("the script timeout must be at least 1 second")
+			}
+			s.ScriptPath = strings.TrimSpace(pathEntry.Text)
+			s.ScriptTimeoutSeconds = timeout
+			s.ControlPipeEnabled = controlCheck.Checked
+			return nil
+		},
+	}
+}
+
+// positioningSettingsTab creates the settings tab for the repositioning behavior.
+func (wm *WindowManager) positioningSettingsTab(settings Settings, window fyne.Window) settingsTab {
+	policies := map[string]string{
+		"Let windows overlap":           conflictOverlap,
+		"Offset the overlapping window": conflictOffset,
+		"Skip the overlapping window":   conflictSkip,
+	}
+	var options []string
+	selected := ""
+	for label, policy := range policies {
+		options = append(options, label)
+		if policy == settings.ConflictPolicy {
+			selected = label
+		}
+	}
+	sort.Strings(options)
+	conflictSelect := widget.NewSelect(options, nil)
+	conflictSelect.SetSelected(selected)
+
+	modes := make(map[string]string)
+	var modeLabels []string
+	for _, m := range repositionModes {
+		modes[m.label] = m.mode
+		modeLabels = append(modeLabels, m.label)
+	}
+	modeRadio := widget.NewRadioGroup(modeLabels, nil)
+	modeRadio.Horizontal = true
+	modeRadio.Required = true
+	modeRadio.SetSelected(repositionModeLabel(settings.RepositionMode))
+
+	backToFrontCheck := widget.NewCheck("Restore windows back to front (keeps the stacking order)", nil)
+	backToFrontCheck.SetChecked(settings.RestoreBackToFront)
+	fullscreenCheck := widget.NewCheck("Pause while a fullscreen app (game, video) is in the foreground", nil)
+	fullscreenCheck.SetChecked(settings.PauseForFullscreen)
+	notificationsCheck := widget.NewCheck("Show notifications (profile applied, window cannot be moved, startup)", nil)
+	notificationsCheck.SetChecked(settings.ShowNotifications)
+
+	intervalEntry := newIntEntry(settings.MonitorIntervalSeconds)
+	eventsCheck := widget.NewCheck("Position windows as soon as they appear or move (checks at the interval otherwise)", nil)
+	eventsCheck.SetChecked(settings.WatchWindowEvents)
+	cooldownEntry := newIntEntry(settings.RepositionCooldownSeconds)
+	toleranceEntry := newIntEntry(settings.DriftTolerancePixels)
+	positionToleranceEntry := newIntEntry(settings.PositionTolerancePixels)
+	visibleFrameCheck := widget.NewCheck("Save the visible frame without the invisible borders (no gap at screen edges)", nil)
+	visibleFrameCheck.SetChecked(settings.SaveVisibleFrame)
+	moveTimeoutEntry := newIntEntry(settings.MoveTimeoutMilliseconds)
+	graceEntry := newIntEntry(settings.NewWindowGraceSeconds)
+	confirmElevatedCheck := widget.NewCheck("Ask before moving windows of elevated (administrator) processes", nil)
+	confirmElevatedCheck.SetChecked(settings.ConfirmElevatedMoves)
+	helperCheck := widget.NewCheck("Move elevated windows through an elevated helper (asks for UAC once)", nil)
+	helperCheck.SetChecked(settings.UseElevatedHelper)
+	alwaysElevatedCheck := widget.NewCheck("Always run elevated (asks for UAC at start, starts with Windows by a scheduled task)", nil)
+	alwaysElevatedCheck.SetChecked(settings.AlwaysRunElevated)
+	startupElevatedCheck := widget.NewCheck("Start with Windows (elevated, by a scheduled task with the highest privileges)", nil)
+	startupElevatedCheck.SetChecked(IsStartupElevatedEnabled())
+	startupElevatedCheck.OnChanged = func(checked bool) {
+		// Changing the task may wait for a UAC prompt
+		go func() {
+			defer panicHandler()
+			if err := setStartupElevated(checked); err != nil {
+				logWarn("Failed to change the elevated startup task:", err)
+				fyne.Do(func() {
+					dialog.ShowError(err, window)
+				})
+			}
+		}()
+	}
+	learnCheck := widget.NewCheck("Try the historically best move strategy of the app first, unless the rule remembers one", nil)
+	learnCheck.SetChecked(settings.LearnStrategyOrder)
+	strategyOrderEntry := widget.NewEntry()
+	strategyOrderEntry.SetPlaceHolder(strings.Join(orderedStrategyNames(nil)[:3], ", "))
+	strategyOrderEntry.SetText(strings.Join(settings.StrategyOrder, ", "))
+	statsBtn := widget.NewButton("Strategy statistics…", safeCallback(func() {
+		wm.showStrategyStatsDialog(window)
+	}))
+
+	defaultRuleCheck := widget.NewCheck("Center new windows without a rule at a default size", nil)
+	defaultRuleCheck.SetChecked(settings.DefaultRuleEnabled)
+	defaultWidthEntry := newIntEntry(settings.DefaultRuleWidth)
+	defaultHeightEntry := newIntEntry(settings.DefaultRuleHeight)
+
+	waitForEntry := widget.NewEntry()
+	waitForEntry.SetPlaceHolder("outlook.exe, teams.exe")
+	waitForEntry.SetText(strings.Join(settings.WaitForApps, ", "))
+	waitTimeoutEntry := newIntEntry(settings.WaitForAppsTimeoutSeconds)
+	waitHelp := widget.NewLabel("On startup, the saved positions are applied once these apps have a window, " +
+		"or when the timeout expires. Leave empty to apply them after 2 seconds.")
+	waitHelp.Wrapping = fyne.TextWrapWord
+
+	help := widget.NewLabel("Conflicts happen when two saved positions overlap. They are written to the log once per session.\n" +
+		"Rules that only apply to new windows are applied during the new window period after a window appeared. " +
+		"Windows are checked at the check interval, so keep the period longer than that. " +
+		"With a check interval of 0, windows are only positioned on startup and on request. " +
+		"A moved window is left alone during the cooldown unless it drifts further than the tolerance, " +
+		"so apps that adjust their own position don't jump back and forth. " +
+		"A window within the position tolerance of its target counts as in place and is not moved. " +
+		"The default size is applied once to windows that appear during this period. " +
+		"Move strategies missing from the strategy order are tried after the listed ones, rules that allow only some strategies try them in their own order.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title: "Positioning",
+		content: container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("Overlapping positions", conflictSelect),
+				widget.NewFormItem("Position windows", modeRadio),
+				widget.NewFormItem("Check interval (seconds, 0 = off)", intervalEntry),
+				widget.NewFormItem("Cooldown after a move (seconds)", cooldownEntry),
+				widget.NewFormItem("Drift tolerance (pixels)", toleranceEntry),
+				widget.NewFormItem("Position tolerance (pixels)", positionToleranceEntry),
+				widget.NewFormItem("Move timeout (ms, 0 = none)", moveTimeoutEntry),
+				widget.NewFormItem("New window period (seconds)", graceEntry),
+			),
+			help,
+			eventsCheck,
+			visibleFrameCheck,
+			backToFrontCheck,
+			fullscreenCheck,
+			notificationsCheck,
+			confirmElevatedCheck,
+			helperCheck,
+			alwaysElevatedCheck,
+			startupElevatedCheck,
+			container.NewBorder(nil, nil, nil, statsBtn, learnCheck),
+			widget.NewForm(
+				widget.NewFormItem("Move strategy order", strategyOrderEntry),
+			),
+			defaultRuleCheck,
+			widget.NewForm(
+				widget.NewFormItem("Default width", defaultWidthEntry),
+				widget.NewFormItem("Default height", defaultHeightEntry),
+			),
+			widget.NewForm(
+				widget.NewFormItem("Wait for apps", waitForEntry),
+				widget.NewFormItem("Wait timeout (seconds)", waitTimeoutEntry),
+			),
+			waitHelp,
+		),
+		apply: func(s *Settings) error {
+			timeout, err := strconv.Atoi(waitTimeoutEntry.Text)
+			if err != nil || timeout < 1 {
+				return fmt.Errorf("the wait timeout must be at least 1 second")
+			}
+			interval, err := strconv.Atoi(intervalEntry.Text)
+			if err != nil || interval < 0 {
+				return fmt.Errorf("the check interval must be 0 or more seconds")
+			}
+			cooldown, err := strconv.Atoi(cooldownEntry.Text)
+			if err != nil || cooldown < 0 {
+				return fmt.Errorf("the cooldown must be 0 or more seconds")
+			}
+			tolerance, err := strconv.Atoi(toleranceEntry.Text)
+			if err != nil || tolerance < 0 {
+				return fmt.Errorf("the drift tolerance must be 0 or more pixels")
+			}
+			positionTolerance, err := strconv.Atoi(positionToleranceEntry.Text)
+			if err != nil || positionTolerance < 0 {
+				return fmt.Errorf("the position tolerance must be 0 or more pixels")
+			}
+			moveTimeout, err := strconv.Atoi(moveTimeoutEntry.Text)
+			if err != nil || moveTimeout < 0 {
+				return fmt.Errorf("the move timeout must be 0 or more milliseconds")
+			}
+			grace, err := strconv.Atoi(graceEntry.Text)
+			if err != nil || grace < 1 {
+				return fmt.Errorf("the new window period must be at least 1 second")
+			}
+			var order []string
+			for _, name := range strings.Split(strategyOrderEntry.Text, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					continue
+				}
+				if _, ok := findMoveStrategy(name); !ok {
+					return fmt.Errorf("unknown move strategy: %s (known: %s)", name, strings.Join(orderedStrategyNames(nil), ", "))
+				}
+				order = append(order, name)
+			}
+			defaultWidth, err := strconv.Atoi(defaultWidthEntry.Text)
+			if err != nil || defaultWidth < 100 {
+				return fmt.Errorf("the default width must be at least 100 pixels")
+			}
+			defaultHeight, err := strconv.Atoi(defaultHeightEntry.Text)
+			if err != nil || defaultHeight < 100 {
+				return fmt.Errorf("the default height must be at least 100 pixels")
+			}
+			var apps []string
+			for _, app := range strings.Split(waitForEntry.Text, ",") {
+				if app = strings.TrimSpace(app); app != "" {
+					apps = append(apps, app)
+				}
+			}
+			if policy, ok := policies[conflictSelect.Selected]; ok {
+				s.ConflictPolicy = policy
+			}
+			if mode, ok := modes[modeRadio.Selected]; ok {
+				s.RepositionMode = mode
+			}
+			s.RestoreBackToFront = backToFrontCheck.Checked
+			s.PauseForFullscreen = fullscreenCheck.Checked
+			s.ShowNotifications = notificationsCheck.Checked
+			s.MonitorIntervalSeconds = interval
+			s.RepositionCooldownSeconds = cooldown
+			s.DriftTolerancePixels = tolerance
+			s.PositionTolerancePixels = positionTolerance
+			s.SaveVisibleFrame = visibleFrameCheck.Checked
+			s.WatchWindowEvents = eventsCheck.Checked
+			s.MoveTimeoutMilliseconds = moveTimeout
+			s.NewWindowGraceSeconds = grace
+			s.ConfirmElevatedMoves = confirmElevatedCheck.Checked
+			s.UseElevatedHelper = helperCheck.Checked
+			s.AlwaysRunElevated = alwaysElevatedCheck.Checked
+			s.LearnStrategyOrder = learnCheck.Checked
+			s.StrategyOrder = order
+			s.DefaultRuleEnabled = defaultRuleCheck.Checked
+			s.DefaultRuleWidth = defaultWidth
+			s.DefaultRuleHeight = defaultHeight
+			s.WaitForApps = apps
+			s.WaitForAppsTimeoutSeconds = timeout
+			return nil
+		},
+	}
+}
+
+// pruneSettingsTab creates the settings tab for pruning unused rules.
+func (wm *WindowManager) pruneSettingsTab(settings Settings, window fyne.Window) settingsTab {
+	pruneCheck := widget.NewCheck("Offer to prune unused rules on startup", nil)
+	pruneCheck.SetChecked(settings.PruneEnabled)
+	daysEntry := newIntEntry(settings.PruneDays)
+	mergeCheck := widget.NewCheck("Offer to merge rules that match the same windows on startup", nil)
+	mergeCheck.SetChecked(settings.MergeDuplicates)
+	mergeBtn := widget.NewButtonWithIcon("Merge duplicates now…", theme.ContentCopyIcon(), safeCallback(func() {
+		wm.showMergeDuplicatesDialog(window, false)
+	}))
+
+	parseDays := func() (int, error) {
+		days, err := strconv.Atoi(daysEntry.Text)
+		if err != nil || days < 1 {
+			return 0, fmt.Errorf("the retention period must be at least 1 day")
+		}
+		return days, nil
+	}
+	previewBtn := widget.NewButtonWithIcon("Prune now…", theme.DeleteIcon(), safeCallback(func() {
+		days, err := parseDays()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		wm.showPruneDialog(window, days)
+	}))
+
+	help := widget.NewLabel("Rules that did not match a window for the retention period or whose executable no longer exists " +
+		"are listed for confirmation before they are deleted. Rules saved by older versions are kept until they match a window once.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title: "Cleanup",
+		content: container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("", pruneCheck),
+				widget.NewFormItem("Retention (days)", daysEntry),
+			),
+			help,
+			previewBtn,
+			widget.NewSeparator(),
+			mergeCheck,
+			mergeBtn,
+		),
+		apply: func(s *Settings) error {
+			days, err := parseDays()
+			if err != nil {
+				return err
+			}
+			s.PruneEnabled = pruneCheck.Checked
+			s.PruneDays = days
+			s.MergeDuplicates = mergeCheck.Checked
+			return nil
+		},
+	}
+}
+
+// powerSettingsTab creates the settings tab for the power profiles.
+func (wm *WindowManager) powerSettingsTab(settings Settings) settingsTab {
+	const keepProfile = "(keep active profile)"
+	options := append([]string{keepProfile}, wm.storage.ProfileNames()...)
+	newSelect := func(profile string) *widget.Select {
+		selectWidget := widget.NewSelect(options, nil)
+		if profile == "" {
+			profile = keepProfile
+		}
+		selectWidget.SetSelected(profile)
+		return selectWidget
+	}
+	batterySelect := newSelect(settings.BatteryProfile)
+	acSelect := newSelect(settings.ACProfile)
+	selected := func(selectWidget *widget.Select) string {
+		if selectWidget.Selected == keepProfile {
+			return ""
+		}
+		return selectWidget.Selected
+	}
+
+	help := widget.NewLabel("The profile is switched and applied when the power source changes. " +
+		"It is not switched on startup.")
+	help.Wrapping = fyne.TextWrapWord
+	previewCheck := widget.NewCheck("Preview the windows a profile moves before applying it from the menu or the tray", nil)
+	previewCheck.SetChecked(settings.PreviewProfiles)
+
+	return settingsTab{
+		title: "Power",
+		content: container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem("On battery", batterySelect),
+				widget.NewFormItem("On AC power", acSelect),
+			),
+			help,
+			previewCheck,
+		),
+		apply: func(s *Settings) error {
+			s.BatteryProfile = selected(batterySelect)
+			s.ACProfile = selected(acSelect)
+			s.PreviewProfiles = previewCheck.Checked
+			return nil
+		},
+	}
+}
+
+// loggingSettingsTab creates the settings tab for logging.
+func (wm *WindowManager) loggingSettingsTab(settings Settings) settingsTab {
+	memoryOnlyCheck := widget.NewCheck("Keep the log in memory only (never write the log file)", nil)
+	memoryOnlyCheck.SetChecked(settings.LogMemoryOnly)
+	redactCheck := widget.NewCheck("Redact window titles (replace them by a hash)", nil)
+	redactCheck.SetChecked(settings.RedactTitles)
+	levelSelect := widget.NewSelect(logLevelNames, nil)
+	levelSelect.SetSelected(logLevelNames[parseLogLevel(settings.LogLevel)])
+	maxSizeEntry := newIntEntry(settings.LogMaxSizeMB)
+	backupsEntry := newIntEntry(settings.LogBackups)
+	showLogBtn := widget.NewButtonWithIcon("Show log", theme.FileTextIcon(), safeCallback(func() {
+		wm.showLogWindow()
+	}))
+
+	help := widget.NewLabel("Debug also logs the details of every check, warn and error only log failures.\n" +
+		"The log contains window titles and executable paths. " +
+		"In memory-only mode, the last messages are only available in the log window and are lost when the application exits. " +
+		"Enabling it deletes the existing log file.\n" +
+		"Redacted titles keep the class name and executable, and the same title always gets the same hash, " +
+		"so a log can be shared for support without revealing document names.")
+	help.Wrapping = fyne.TextWrapWord
+
+	return settingsTab{
+		title: "Logging",
+		content: container.NewVBox(memoryOnlyCheck, redactCheck,
+			widget.NewForm(
+				widget.NewFormItem("Minimum level", levelSelect),
+				widget.NewFormItem("Rotate at (MB, 0 = never)", maxSizeEntry),
+				widget.NewFormItem("Rotated files to keep", backupsEntry),
+			),
+			help, showLogBtn),
+		apply: func(s *Settings) error {
+			maxSize, err := strconv.Atoi(maxSizeEntry.Text)
+			if err != nil || maxSize < 0 {
+				return fmt.Errorf("the log size must be 0 or more MB")
+			}
+			backups, err := strconv.Atoi(backupsEntry.Text)
+			if err != nil || backups < 0 || backups > 20 {
+				return fmt.Errorf("between 0 and 20 rotated log files can be kept")
+			}
+			s.LogLevel = levelSelect.Selected
+			s.LogMaxSizeMB = maxSize
+			s.LogBackups = backups
+			s.LogMemoryOnly = memoryOnlyCheck.Checked
+			s.RedactTitles = redactCheck.Checked
+			return nil
+		},
+	}
+}
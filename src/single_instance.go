@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+/*
+	Single instance:
+	- The application holds a named mutex while it runs. A second start finds the mutex, asks the
+	  running instance to show its main window and exits, so two trays never write the positions
+	  file at the same time.
+	- The request is a registered window message posted to the system event window of the running
+	  instance, see system_events.go. The second process is the foreground process when the user
+	  starts it, so it allows the running instance to take the foreground.
+	- If the message cannot be posted, the request is sent as {"action":"show"} on the control pipe,
+	  if the running instance has it enabled, see control_pipe.go.
+	- The mutex is local to the session, other users run their own instance. The command line mode
+	  and the move helper do not claim it, they run next to the tray.
+	- An elevated instance does not receive the message of a normal one (UIPI), the control pipe
+	  is tried then.
+*/
+
+// singleInstanceMutexName is the name of the mutex held by the running instance
+var singleInstanceMutexName = `Local\` + strAppId
+
+// singleInstanceMutex is held until the process exits, the system releases it
+var singleInstanceMutex windows.Handle
+
+// claimSingleInstance creates the named mutex and reports whether no other instance holds it.
+// If the mutex cannot be created, the application starts anyway.
+func claimSingleInstance() bool {
+	name, err := windows.UTF16PtrFromString(singleInstanceMutexName)
+	if err != nil {
+		return true
+	}
+	handle, err := windows.CreateMutex(nil, false, name)
+	if err == windows.ERROR_ALREADY_EXISTS {
+		windows.CloseHandle(handle)
+		return false
+	}
+	if err != nil {
+		logWarn("Failed to create the single instance mutex:", err)
+		return true
+	}
+	singleInstanceMutex = handle
+	return true
+}
+
+// showInstanceMessage returns the window message that asks the running instance to show
+// its main window, 0 if it cannot be registered.
+func showInstanceMessage() uint32 {
+	name, err := syscall.UTF16PtrFromString(strProductName + ".ShowMainWindow")
+	if err != nil {
+		return 0
+	}
+	message, _, _ := procRegisterWindowMessage.Call(uintptr(unsafe.Pointer(name)))
+	return uint32(message)
+}
+
+// activateRunningInstance asks the running instance to show its main window,
+// with the window message or else on the control pipe.
+func activateRunningInstance() {
+	debug := true
+	err := postShowInstanceMessage()
+	if err == nil {
+		return
+	}
+	log(debug, "Failed to post the show message, trying the control pipe:", err)
+	if err := sendControlShow(); err != nil {
+		log(debug, "The running instance cannot be activated:", err)
+	}
+}
+
+// postShowInstanceMessage posts the show message to the system event window of the running instance.
+func postShowInstanceMessage() error {
+	className, err := syscall.UTF16PtrFromString(systemEventClassName)
+	if err != nil {
+		return err
+	}
+	hwnd, _, _ := procFindWindow.Call(uintptr(unsafe.Pointer(className)), 0)
+	if hwnd == 0 {
+		return fmt.Errorf("the system event window of the running instance was not found")
+	}
+	message := showInstanceMessage()
+	if message == 0 {
+		return fmt.Errorf("the show message cannot be registered")
+	}
+	var processID uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&processID)))
+	procAllowSetForegroundWindow.Call(uintptr(processID))
+	if ret, _, err := procPostMessage.Call(hwnd, uintptr(message), 0, 0); ret == 0 {
+		return fmt.Errorf("PostMessage failed: %v", err)
+	}
+	return nil
+}
+
+// sendControlShow sends the show request on the control pipe and waits for the answer.
+func sendControlShow() error {
+	name, err := windows.UTF16PtrFromString(controlPipeName)
+	if err != nil {
+		return err
+	}
+	pipe, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open the control pipe: %v", err)
+	}
+	file := os.NewFile(uintptr(pipe), controlPipeName)
+	defer file.Close()
+
+	request, err := json.Marshal(controlRequest{Action: controlShow})
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(request, '\n')); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	var response controlResponse
+	if err := json.Unmarshal(line, &response); err != nil {
+		return err
+	}
+	if !response.OK {
+		return fmt.Errorf("%s", response.Error)
+	}
+	return nil
+}
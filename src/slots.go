@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+/*
+	Position slots:
+	- A slot is a scratch-pad position bound to a hotkey (F1..F4 by default), separate from the saved rules.
+	- Pressing the slot key together with the save modifier stores the foreground window's position in the slot.
+	- Pressing the slot key alone moves the foreground window to the stored position.
+	- The snap hotkey moves the foreground window to the saved rule matching it.
+	- The save hotkey saves the foreground window's position as a rule, like "Save" in the window list.
+*/
+
+// saveForegroundWindowToSlot stores the position of the foreground window in the given slot.
+func (wm *WindowManager) saveForegroundWindowToSlot(slot string) {
+	debug := true
+	hwnd := getForegroundWindow()
+	pos, err := getWindowPosition(hwnd)
+	if err != nil {
+		log(true, "Failed to get foreground window position for slot", slot+":", err)
+		messageBeep()
+		return
+	}
+	err = wm.updateSettings(func(s *Settings) {
+		s.Slots[slot] = *pos
+	})
+	if err != nil {
+		messageBeep()
+		return
+	}
+	log(debug, fmt.Sprintf("Saved slot %s: %d,%d %dx%d", slot, pos.X, pos.Y, pos.Width, pos.Height))
+}
+
+// moveForegroundWindowToSlot moves the foreground window to the position stored in the given slot.
+func (wm *WindowManager) moveForegroundWindowToSlot(slot string) {
+	debug := true
+	pos, ok := wm.getSettings().Slots[slot]
+	if !ok {
+		log(debug, "Slot", slot, "is empty.")
+		messageBeep()
+		return
+	}
+	hwnd := getForegroundWindow()
+	if err := MoveWindowAccurate(hwnd, pos.X, pos.Y, pos.Width, pos.Height); err != nil {
+		log(true, "Failed to move foreground window to slot", slot+":", err)
+		messageBeep()
+		return
+	}
+	log(debug, "Moved foreground window to slot", slot)
+}
+
+// snapForegroundWindow moves the foreground window to the saved position matching it.
+// Rules outside of their schedule are left out. If several rules match, the one with the highest priority is used. Like a repositioning pass it
+// restores the saved window state and maximizes on the rule's monitor. Beeps if none matches.
+func (wm *WindowManager) snapForegroundWindow() {
+	debug := true
+	window := getWindowInfo(getForegroundWindow())
+	settings := wm.getSettings()
+	identifier, pos, ok := findSavedPosition(window, activeRules(wm.storage.GetAllPositions(), time.Now()))
+	if !ok {
+		log(debug, "No saved position matches the foreground window:", redactTitle(window.Title))
+		messageBeep()
+		return
+	}
+
+	target := pos.PhysicalFor(window.Handle)
+	if pos.UseScript {
+		timeout := time.Duration(settings.ScriptTimeoutSeconds) * time.Second
+		scripted, err := runPositionScript(wm.ctx, settings.ScriptPath, timeout, window, pos)
+		if err != nil {
+			log(true, "Position script failed for", redactIdentifier(identifier)+":", err)
+			messageBeep()
+			return
+		}
+		target = *scripted
+	}
+	if monitors, err := getCachedMonitors(); err == nil {
+		if target, err = pos.onMaximizeMonitor(target, monitors); err != nil {
+			log(debug, "Not snapping foreground window to", redactIdentifier(identifier)+":", err)
+			messageBeep()
+			return
+		}
+		target = clampToMonitors(target, monitors)
+	}
+	opts := pos.moveOptions(settings)
+	if err := wm.moveToSavedState(window, pos, target, opts); err != nil {
+		logError("Failed to snap foreground window to", redactIdentifier(identifier)+":", err)
+		recordProblem(problemError, redactIdentifier(identifier), "Failed to snap foreground window:", err)
+		messageBeep()
+		return
+	}
+	log(debug, "Snapped foreground window to", redactIdentifier(identifier))
+}
+
+// saveForegroundWindow saves the position of the foreground window as a rule.
+// Beeps if the foreground window cannot be saved, e.g. the desktop or the taskbar.
+func (wm *WindowManager) saveForegroundWindow() {
+	debug := true
+	hwnd := getForegroundWindow()
+	if hwnd == 0 || !isValidWindow(hwnd) {
+		log(debug, "No foreground window to save.")
+		messageBeep()
+		return
+	}
+	window := getWindowInfo(hwnd)
+	if isShellWindowClass(window.ClassName) {
+		log(debug, "Not saving shell window:", window.ClassName)
+		messageBeep()
+		return
+	}
+	fyne.Do(func() {
+		wm.saveWindowPosition(window)
+	})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Staging:
+	- A snapshot captures the positions of all visible windows into a staging list,
+	  which is stored separately from the profiles and never applied.
+	- The user reviews the staged positions and promotes the good ones into a profile,
+	  or discards them. Promoted positions are removed from the staging list.
+	- A newer snapshot of the same window replaces the staged position.
+*/
+
+// snapshotToStaging captures the positions of all visible windows into the staging list.
+func (wm *WindowManager) snapshotToStaging() {
+	debug := true
+	windows, err := EnumerateWindows()
+	if err != nil {
+		log(true, "Failed to enumerate windows:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	staged, err := wm.storage.LoadStaging()
+	if err != nil {
+		log(true, "Failed to load staged positions:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+
+	ownExecutable, _ := os.Executable()
+	fields := wm.getSettings().IdentifierFields
+	count := 0
+	for zOrder, window := range windows {
+		if strings.EqualFold(window.Executable, ownExecutable) {
+			continue // Do not stage the windows of this application
+		}
+		pos, err := getWindowPosition(window.Handle)
+		if err != nil {
+			log(debug, "Skipping window without position:", redactTitle(window.Title), err)
+			continue
+		}
+		pos.ExStyleFlags = window.ExStyle & clickThroughExStyles
+		pos.Order = zOrder + 1 // The enumeration is in Z-order
+		staged[buildIdentifier(window, fields)] = *pos
+		count++
+	}
+	if err := wm.storage.SaveStaging(staged); err != nil {
+		log(true, "Failed to save staged positions:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	log(debug, "Staged", count, "window positions.")
+	dialog.ShowConfirm("Snapshot taken",
+		fmt.Sprintf("Staged %d window positions. Review them now?", count),
+		func(review bool) {
+			defer panicHandler()
+			if review {
+				wm.showStagingDialog()
+			}
+		}, wm.mainWindow)
+}
+
+// showStagingDialog lists the staged positions, so they can be promoted into a profile or discarded.
+func (wm *WindowManager) showStagingDialog() {
+	debug := true
+	staged, err := wm.storage.LoadStaging()
+	if err != nil {
+		log(true, "Failed to load staged positions:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	if len(staged) == 0 {
+		dialog.ShowInformation("Staging", "No positions are staged. Use \"Snapshot windows to staging\" first.", wm.mainWindow)
+		return
+	}
+
+	var identifiers []string
+	for identifier := range staged {
+		identifiers = append(identifiers, identifier)
+	}
+	slices.Sort(identifiers)
+	checks := container.NewVBox()
+	selected := make(map[string]bool)
+	for _, identifier := range identifiers {
+		pos := staged[identifier]
+		label := fmt.Sprintf("%s (%d,%d %dx%d)", redactIdentifier(identifier), pos.X, pos.Y, pos.Width, pos.Height)
+		checks.Add(widget.NewCheck(label, func(checked bool) {
+			selected[identifier] = checked
+		}))
+	}
+	selection := func() []string {
+		var result []string
+		for _, identifier := range identifiers {
+			if selected[identifier] {
+				result = append(result, identifier)
+			}
+		}
+		return result
+	}
+	selectAll := widget.NewCheck("Select all", func(checked bool) {
+		for _, object := range checks.Objects {
+			object.(*widget.Check).SetChecked(checked)
+		}
+	})
+
+	profileEntry := widget.NewSelectEntry(wm.storage.ProfileNames())
+	profileEntry.SetText(wm.storage.ActiveProfile())
+
+	var staging dialog.Dialog
+	// removeStaged removes promoted or discarded positions from the staging list and closes the dialog.
+	removeStaged := func(removed []string) error {
+		for _, identifier := range removed {
+			delete(staged, identifier)
+		}
+		if err := wm.storage.SaveStaging(staged); err != nil {
+			return err
+		}
+		staging.Hide()
+		return nil
+	}
+	promoteBtn := widget.NewButtonWithIcon("Promote selected", theme.ConfirmIcon(), safeCallback(func() {
+		promoted := selection()
+		profile := strings.TrimSpace(profileEntry.Text)
+		if len(promoted) == 0 || profile == "" {
+			return
+		}
+		positions := make(map[string]WindowPosition, len(promoted))
+		for _, identifier := range promoted {
+			positions[identifier] = staged[identifier]
+		}
+		if err := wm.storage.SaveProfilePositions(profile, positions); err != nil {
+			log(true, "Failed to promote staged positions:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Promoted", len(promoted), "staged positions to profile:", profile)
+		wm.setupMainWindowContent() // Refresh the UI
+		if err := removeStaged(promoted); err != nil {
+			log(true, "Failed to save staged positions:", err)
+			dialog.ShowError(err, wm.mainWindow)
+		}
+	}))
+	discardBtn := widget.NewButtonWithIcon("Discard selected", theme.DeleteIcon(), safeCallback(func() {
+		discarded := selection()
+		if len(discarded) == 0 {
+			return
+		}
+		if err := removeStaged(discarded); err != nil {
+			log(true, "Failed to save staged positions:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Discarded", len(discarded), "staged positions.")
+	}))
+
+	scroll := container.NewVScroll(checks)
+	scroll.SetMinSize(fyne.NewSize(600, 300))
+	content := container.NewBorder(
+		selectAll,
+		container.NewVBox(
+			widget.NewForm(widget.NewFormItem("Promote to profile", profileEntry)),
+			container.NewGridWithColumns(2, promoteBtn, discardBtn),
+		),
+		nil, nil, scroll)
+	staging = dialog.NewCustom("Staged positions", "Close", content, wm.mainWindow)
+	staging.Show()
+}
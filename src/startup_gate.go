@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// waitForStartupApps delays the startup repositioning until the configured apps are running.
+// An app counts as running once a window of its executable is found. Without configured apps,
+// it waits a fixed delay to give the other apps time to load.
+func (wm *WindowManager) waitForStartupApps(ctx context.Context) {
+	debug := true
+	const defaultDelay = 2 * time.Second
+	const pollInterval = 500 * time.Millisecond
+
+	settings := wm.getSettings()
+	if len(settings.WaitForApps) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(defaultDelay):
+		}
+		return
+	}
+
+	log(debug, "Waiting for apps before repositioning:", strings.Join(settings.WaitForApps, ", "))
+	timeout := time.After(time.Duration(settings.WaitForAppsTimeoutSeconds) * time.Second)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		missing := missingApps(settings.WaitForApps)
+		if len(missing) == 0 {
+			log(debug, "All apps are running.")
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			log(true, "Timed out waiting for apps:", strings.Join(missing, ", "))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// missingApps returns the apps that have no window yet.
+// Apps are executable names like "outlook.exe" and are compared case-insensitively.
+func missingApps(apps []string) []string {
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return apps
+	}
+	running := make(map[string]bool)
+	for _, window := range windows {
+		running[strings.ToLower(filepath.Base(window.Executable))] = true
+	}
+	var missing []string
+	for _, app := range apps {
+		if !running[strings.ToLower(filepath.Base(app))] {
+			missing = append(missing, app)
+		}
+	}
+	return missing
+}
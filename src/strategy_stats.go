@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// strategyOutcome counts how often a move strategy succeeded and failed
+type strategyOutcome struct {
+	Successes int `json:"successes"`
+	Failures  int `json:"failures"`
+}
+
+// StrategyStats holds the recorded move strategy outcomes,
+// keyed by the lower case executable name and then by the strategy name.
+type StrategyStats map[string]map[string]strategyOutcome
+
+// strategyStatsKey returns the key of an executable in the statistics.
+func strategyStatsKey(executable string) string {
+	return strings.ToLower(filepath.Base(executable))
+}
+
+// best returns the strategy with the most successes for an executable.
+// Ties are broken by the fewest failures, then by name, so the result is stable.
+func (stats StrategyStats) best(executable string) (string, strategyOutcome, bool) {
+	var bestName string
+	var bestOutcome strategyOutcome
+	for name, outcome := range stats[strategyStatsKey(executable)] {
+		if outcome.Successes == 0 {
+			continue
+		}
+		better := bestName == "" ||
+			outcome.Successes > bestOutcome.Successes ||
+			outcome.Successes == bestOutcome.Successes && outcome.Failures < bestOutcome.Failures ||
+			outcome.Successes == bestOutcome.Successes && outcome.Failures == bestOutcome.Failures && name < bestName
+		if better {
+			bestName, bestOutcome = name, outcome
+		}
+	}
+	return bestName, bestOutcome, bestName != ""
+}
+
+// recordStrategyOutcome counts the outcome of a strategy attempt for an executable.
+// The statistics are written by saveStrategyStats.
+func (wm *WindowManager) recordStrategyOutcome(executable, strategy string, succeeded bool) {
+	wm.strategyStatsMutex.Lock()
+	defer wm.strategyStatsMutex.Unlock()
+	if wm.strategyStats == nil {
+		wm.strategyStats = make(StrategyStats)
+	}
+	key := strategyStatsKey(executable)
+	if wm.strategyStats[key] == nil {
+		wm.strategyStats[key] = make(map[string]strategyOutcome)
+	}
+	outcome := wm.strategyStats[key][strategy]
+	if succeeded {
+		outcome.Successes++
+	} else {
+		outcome.Failures++
+	}
+	wm.strategyStats[key][strategy] = outcome
+	wm.strategyStatsDirty = true
+}
+
+// saveStrategyStats writes the statistics if they changed since the last write.
+func (wm *WindowManager) saveStrategyStats() {
+	wm.strategyStatsMutex.Lock()
+	defer wm.strategyStatsMutex.Unlock()
+	if !wm.strategyStatsDirty {
+		return
+	}
+	if err := wm.storage.SaveStrategyStats(wm.strategyStats); err != nil {
+		log(true, "Failed to save strategy statistics:", err)
+		return
+	}
+	wm.strategyStatsDirty = false
+}
+
+// preferredStrategy returns the strategy that historically succeeded most often for an executable,
+// or an empty string if nothing was recorded yet.
+func (wm *WindowManager) preferredStrategy(executable string) string {
+	wm.strategyStatsMutex.Lock()
+	defer wm.strategyStatsMutex.Unlock()
+	name, _, _ := wm.strategyStats.best(executable)
+	return name
+}
+
+// rememberSuccessfulMethod records the strategy that last moved the windows of a rule,
+// so it is tried first the next time. It is only written when it changes.
+func (wm *WindowManager) rememberSuccessfulMethod(identifier, strategy string) {
+	err := wm.storage.UpdatePositions([]string{identifier}, func(_ string, pos *WindowPosition) {
+		pos.SuccessfulMethod = strategy
+	})
+	if err != nil {
+		log(true, "Failed to remember the move strategy:", err)
+	}
+}
+
+// resetStrategyStats forgets all recorded outcomes.
+func (wm *WindowManager) resetStrategyStats() {
+	wm.strategyStatsMutex.Lock()
+	wm.strategyStats = make(StrategyStats)
+	wm.strategyStatsDirty = true
+	wm.strategyStatsMutex.Unlock()
+	wm.saveStrategyStats()
+}
+
+// showStrategyStatsDialog shows, per executable, which move strategy succeeds most often.
+func (wm *WindowManager) showStrategyStatsDialog(window fyne.Window) {
+	wm.strategyStatsMutex.Lock()
+	var lines []string
+	for executable, outcomes := range wm.strategyStats {
+		name, best, ok := wm.strategyStats.best(executable)
+		attempts := 0
+		for _, outcome := range outcomes {
+			attempts += outcome.Successes + outcome.Failures
+		}
+		if ok {
+			lines = append(lines, fmt.Sprintf("%s: %s (%d successes, %d failures, %d attempts in total)",
+				executable, name, best.Successes, best.Failures, attempts))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: no successful strategy (%d attempts)", executable, attempts))
+		}
+	}
+	wm.strategyStatsMutex.Unlock()
+	sort.Strings(lines)
+
+	if len(lines) == 0 {
+		dialog.ShowInformation("Strategy statistics", "No moves were recorded yet.", window)
+		return
+	}
+	text := widget.NewLabel(strings.Join(lines, "\n"))
+	scroll := container.NewVScroll(text)
+	scroll.SetMinSize(fyne.NewSize(500, 300))
+	var d dialog.Dialog
+	resetBtn := widget.NewButton("Reset statistics", safeCallback(func() {
+		wm.resetStrategyStats()
+		log(true, "Strategy statistics reset.")
+		d.Hide()
+	}))
+	d = dialog.NewCustom("Strategy statistics", "Close", container.NewBorder(nil, resetBtn, nil, nil, scroll), window)
+	d.Show()
+}
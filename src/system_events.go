@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+)
+
+// WNDCLASSEX contains the window class information used by RegisterClassExW
+type WNDCLASSEX struct {
+	CbSize        uint32
+	Style         uint32
+	LpfnWndProc   uintptr
+	CbClsExtra    int32
+	CbWndExtra    int32
+	HInstance     syscall.Handle
+	HIcon         syscall.Handle
+	HCursor       syscall.Handle
+	HbrBackground syscall.Handle
+	LpszMenuName  *uint16
+	LpszClassName *uint16
+	HIconSm       syscall.Handle
+}
+
+// systemEventHandler handles a window message received by the system event window
+type systemEventHandler func(wParam, lParam uintptr)
+
+// SystemEventWindow is a hidden window that receives system broadcasts like
+// WM_SETTINGCHANGE and WM_DISPLAYCHANGE. A message-only window would be simpler,
+// but message-only windows do not receive broadcast messages, so a hidden top-level
+// window is used instead. It runs its own message loop on a locked OS thread.
+type SystemEventWindow struct {
+	mutex    sync.Mutex
+	handlers map[uint32][]systemEventHandler
+	threadID uintptr       // Thread running the message loop, 0 if not running
+	done     chan struct{} // Closed when the message loop exits
+}
+
+// systemEvents is the system event window of the application. The window procedure
+// is a global callback, so there is only one instance.
+var systemEvents = &SystemEventWindow{handlers: make(map[uint32][]systemEventHandler)}
+
+// systemEventClassName is the window class of the system event window, a second instance
+// finds the running instance by it
+var systemEventClassName = strProductName + "SystemEvents"
+
+// Global window procedure callback, created once
+var globalSystemEventProc uintptr
+
+func init() {
+	globalSystemEventProc = syscall.NewCallback(systemEventWndProc)
+}
+
+// systemEventWndProc is the window procedure of the system event window.
+// It runs the handlers registered for the message, then the default processing.
+func systemEventWndProc(hwnd syscall.Handle, message uint32, wParam, lParam uintptr) uintptr {
+	systemEvents.mutex.Lock()
+	handlers := systemEvents.handlers[message]
+	systemEvents.mutex.Unlock()
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log(true, "Panic in system event handler for message", message, ":", r)
+				}
+			}()
+			handler(wParam, lParam)
+		}()
+	}
+	ret, _, _ := procDefWindowProc.Call(uintptr(hwnd), uintptr(message), wParam, lParam)
+	return ret
+}
+
+// Handle registers a handler for a window message. Handlers run on the message loop thread,
+// so they must not block; UI work has to be passed to fyne.Do.
+func (se *SystemEventWindow) Handle(message uint32, handler systemEventHandler) {
+	se.mutex.Lock()
+	defer se.mutex.Unlock()
+	se.handlers[message] = append(se.handlers[message], handler)
+}
+
+// Start creates the window and runs the message loop until ctx is cancelled.
+func (se *SystemEventWindow) Start(ctx context.Context) error {
+	ready := make(chan error)
+	done := make(chan struct{})
+	go se.messageLoop(ready, done)
+	if err := <-ready; err != nil {
+		return err
+	}
+	se.mutex.Lock()
+	se.done = done
+	se.mutex.Unlock()
+
+	go func() {
+		defer panicHandler()
+		<-ctx.Done()
+		se.Stop()
+	}()
+	return nil
+}
+
+// Stop destroys the window and ends the message loop.
+func (se *SystemEventWindow) Stop() {
+	se.mutex.Lock()
+	threadID, done := se.threadID, se.done
+	se.mutex.Unlock()
+	if threadID == 0 || done == nil {
+		return
+	}
+	procPostThreadMessage.Call(threadID, WM_QUIT, 0, 0)
+	<-done
+}
+
+// messageLoop creates the hidden window on a locked OS thread and dispatches its messages
+// until WM_QUIT is received. The creation result is reported on the ready channel.
+func (se *SystemEventWindow) messageLoop(ready chan<- error, done chan struct{}) {
+	debug := true
+	defer panicHandler()
+	defer close(done)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	hInstance, _, _ := procGetModuleHandle.Call(0)
+	className, _ := syscall.UTF16PtrFromString(systemEventClassName)
+	wc := WNDCLASSEX{
+		LpfnWndProc:   globalSystemEventProc,
+		HInstance:     syscall.Handle(hInstance),
+		LpszClassName: className,
+	}
+	wc.CbSize = uint32(unsafe.Sizeof(wc))
+	if ret, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc))); ret == 0 {
+		ready <- fmt.Errorf("RegisterClassEx failed: %v", err)
+		return
+	}
+	// No WS_VISIBLE style, so the window is never shown
+	hwnd, _, err := procCreateWindowEx.Call(0, uintptr(unsafe.Pointer(className)), uintptr(unsafe.Pointer(className)),
+		0, 0, 0, 0, 0, 0, 0, hInstance, 0)
+	if hwnd == 0 {
+		ready <- fmt.Errorf("CreateWindowEx failed: %v", err)
+		return
+	}
+	defer procDestroyWindow.Call(hwnd)
+
+	threadID, _, _ := procGetCurrentThreadId.Call()
+	se.mutex.Lock()
+	se.threadID = threadID
+	se.mutex.Unlock()
+	defer func() {
+		se.mutex.Lock()
+		se.threadID = 0
+		se.mutex.Unlock()
+	}()
+	log(debug, "System event window created.")
+	ready <- nil
+
+	var msg MSG
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(ret) <= 0 { // 0 is WM_QUIT, -1 is an error
+			log(debug, "System event window stopped.")
+			return
+		}
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+}
+
+// registerSystemEventHandlers connects the system events to the window manager.
+func (wm *WindowManager) registerSystemEventHandlers() {
+	debug := true
+	// Moving the taskbar or toggling auto-hide changes the work areas
+	systemEvents.Handle(WM_SETTINGCHANGE, func(wParam, lParam uintptr) {
+		if wParam == SPI_SETWORKAREA {
+			log(debug, "Work area changed, invalidating the monitor cache.")
+			invalidateMonitorCache()
+		}
+	})
+	systemEvents.Handle(WM_DISPLAYCHANGE, func(wParam, lParam uintptr) {
+		log(debug, "Display configuration changed, invalidating the monitor cache.")
+		invalidateMonitorCache()
+	})
+	// A second instance asks to show the main window, see single_instance.go
+	if message := showInstanceMessage(); message != 0 {
+		systemEvents.Handle(message, func(wParam, lParam uintptr) {
+			log(debug, "Another instance was started, showing the main window.")
+			fyne.Do(func() {
+				wm.mainWindow.Show()
+				wm.mainWindow.RequestFocus()
+			})
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"fyne.io/fyne/v2"
+)
+
+/*
+	Tiling:
+	- Moves the foreground window to a half or a quarter of the work area of the monitor it is on,
+	  or maximizes it there. The work area excludes the taskbar and docked toolbars.
+	- Centering keeps the size of the window and centers it on the work area of the monitor
+	  containing its center, or of the nearest monitor if it is off-screen.
+	- Available in the "Tile window" tray submenu and as optional hotkeys, centering also in the window list.
+	- Opening the tray menu makes the taskbar the foreground window, so the tray actions tile the
+	  topmost window of another application instead.
+*/
+
+// tileLayout is a predefined place of a window on the work area
+type tileLayout struct {
+	ID   string // Key in the TileHotkeys setting
+	Name string // Name in the menu and the settings
+}
+
+// tileLayouts are the available layouts in menu order
+var tileLayouts = []tileLayout{
+	{"left", "Left half"},
+	{"right", "Right half"},
+	{"top-left", "Top left quarter"},
+	{"top-right", "Top right quarter"},
+	{"bottom-left", "Bottom left quarter"},
+	{"bottom-right", "Bottom right quarter"},
+	{"maximize", "Maximize"},
+}
+
+// tileRect returns the position of a layout on a work area.
+// Odd sizes are split so the halves cover the whole work area without a gap.
+func tileRect(layout string, area RECT) (WindowPosition, error) {
+	left, top := int(area.Left), int(area.Top)
+	width, height := int(area.Right-area.Left), int(area.Bottom-area.Top)
+	halfWidth, halfHeight := width/2, height/2
+
+	switch layout {
+	case "left":
+		return WindowPosition{X: left, Y: top, Width: halfWidth, Height: height}, nil
+	case "right":
+		return WindowPosition{X: left + halfWidth, Y: top, Width: width - halfWidth, Height: height}, nil
+	case "top-left":
+		return WindowPosition{X: left, Y: top, Width: halfWidth, Height: halfHeight}, nil
+	case "top-right":
+		return WindowPosition{X: left + halfWidth, Y: top, Width: width - halfWidth, Height: halfHeight}, nil
+	case "bottom-left":
+		return WindowPosition{X: left, Y: top + halfHeight, Width: halfWidth, Height: height - halfHeight}, nil
+	case "bottom-right":
+		return WindowPosition{X: left + halfWidth, Y: top + halfHeight, Width: width - halfWidth, Height: height - halfHeight}, nil
+	case "maximize":
+		return WindowPosition{X: left, Y: top, Width: width, Height: height}, nil
+	}
+	return WindowPosition{}, fmt.Errorf("unknown layout '%s'", layout)
+}
+
+// tileWindow moves a window to a layout on the work area of the monitor containing most of it.
+// Maximized and minimized windows are restored first, as a move does not change their state.
+func tileWindow(hwnd syscall.Handle, layout string) error {
+	if hwnd == 0 || !isValidWindow(hwnd) {
+		return fmt.Errorf("no window to tile")
+	}
+	current, err := getWindowPosition(hwnd)
+	if err != nil {
+		return err
+	}
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		return err
+	}
+	if len(monitors) == 0 {
+		return fmt.Errorf("no monitor found")
+	}
+	index := monitorForRect(current.Rect(), monitors)
+	if index < 0 {
+		index = nearestMonitor(current.Rect(), monitors)
+	}
+	target, err := tileRect(layout, monitors[index].WorkArea)
+	if err != nil {
+		return err
+	}
+
+	if isWindowMaximized(hwnd) || isWindowMinimized(hwnd) {
+		procShowWindow.Call(uintptr(hwnd), SW_RESTORE)
+	}
+	if layout == "maximize" {
+		// A real maximize, so the window gets its maximized frame and can be restored
+		if err := MoveWindowAccurate(hwnd, target.X, target.Y, target.Width, target.Height); err != nil {
+			return err
+		}
+		procShowWindow.Call(uintptr(hwnd), SW_MAXIMIZE)
+		return nil
+	}
+	return MoveWindowAccurate(hwnd, target.X, target.Y, target.Width, target.Height)
+}
+
+// centerWindowOnMonitor centers a window at its current size on the work area of the monitor
+// containing its center. Maximized and minimized windows are restored first.
+func centerWindowOnMonitor(hwnd syscall.Handle) error {
+	if hwnd == 0 || !isValidWindow(hwnd) {
+		return fmt.Errorf("no window to center")
+	}
+	if isWindowMaximized(hwnd) || isWindowMinimized(hwnd) {
+		procShowWindow.Call(uintptr(hwnd), SW_RESTORE)
+	}
+	current, err := getWindowPosition(hwnd)
+	if err != nil {
+		return err
+	}
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		return err
+	}
+	if len(monitors) == 0 {
+		return fmt.Errorf("no monitor found")
+	}
+	area := monitors[nearestMonitor(current.Rect(), monitors)].WorkArea
+	target := centerOnWorkArea(current.Width, current.Height, area)
+	return MoveWindowAccurate(hwnd, target.X, target.Y, target.Width, target.Height)
+}
+
+// tileTargetWindow returns the window a tray action applies to: the foreground window, unless
+// it belongs to this application or the shell, then the topmost window of another application.
+func tileTargetWindow() syscall.Handle {
+	own := uint32(os.Getpid())
+	if window := getWindowInfo(getForegroundWindow()); window.ProcessID != own && !isShellWindowClass(window.ClassName) {
+		return window.Handle
+	}
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return 0
+	}
+	for _, window := range windows { // In z-order, topmost first
+		if window.ProcessID != own && !isShellWindowClass(window.ClassName) && !isWindowMinimized(window.Handle) {
+			return window.Handle
+		}
+	}
+	return 0
+}
+
+// tileForegroundWindow moves the foreground window to a layout. Beeps if that is not possible.
+func (wm *WindowManager) tileForegroundWindow(layout string) {
+	wm.tileWindowTo(getForegroundWindow(), layout)
+}
+
+// tileWindowTo moves a window to a layout. Beeps if that is not possible.
+func (wm *WindowManager) tileWindowTo(hwnd syscall.Handle, layout string) {
+	debug := true
+	if className := getClassName(hwnd); isShellWindowClass(className) {
+		log(debug, "Not tiling shell window:", className)
+		messageBeep()
+		return
+	}
+	if err := tileWindow(hwnd, layout); err != nil {
+		logWarn("Failed to tile window to", layout+":", err)
+		messageBeep()
+		return
+	}
+	log(debug, "Tiled window", hwnd, "to", layout)
+}
+
+// centerWindow centers a window on its monitor. Beeps if that is not possible.
+func (wm *WindowManager) centerWindow(hwnd syscall.Handle) {
+	debug := true
+	if className := getClassName(hwnd); isShellWindowClass(className) {
+		log(debug, "Not centering shell window:", className)
+		messageBeep()
+		return
+	}
+	if err := centerWindowOnMonitor(hwnd); err != nil {
+		logWarn("Failed to center window:", err)
+		messageBeep()
+		return
+	}
+	log(debug, "Centered window", hwnd)
+}
+
+// tileTrayItem creates the tray submenu with the tiling layouts.
+func (wm *WindowManager) tileTrayItem() *fyne.MenuItem {
+	var items []*fyne.MenuItem
+	for _, layout := range tileLayouts {
+		items = append(items, fyne.NewMenuItem(layout.Name, safeCallback(func() {
+			hwnd := tileTargetWindow()
+			go func() {
+				defer panicHandler()
+				wm.tileWindowTo(hwnd, layout.ID)
+			}()
+		})))
+	}
+	items = append(items, fyne.NewMenuItemSeparator(), fyne.NewMenuItem("Center on monitor", safeCallback(func() {
+		hwnd := tileTargetWindow()
+		go func() {
+			defer panicHandler()
+			wm.centerWindow(hwnd)
+		}()
+	})))
+	tile := fyne.NewMenuItem("Tile window", nil)
+	tile.ChildMenu = fyne.NewMenu("", items...)
+	return tile
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+const identifierAliasPrefix = "alias:" // Title component of a window whose title carries an alias tag
+
+// titleAliasPattern finds the alias tag in a window title, e.g. "~/src - bash [wp:build]"
+var titleAliasPattern = regexp.MustCompile(`\[wp:([^\[\]|]+)\]`)
+
+// titleAlias returns the alias tagged in a window title.
+func titleAlias(title string) (string, bool) {
+	match := titleAliasPattern.FindStringSubmatch(title)
+	if match == nil {
+		return "", false
+	}
+	alias := strings.TrimSpace(match[1])
+	return alias, alias != ""
+}
+
+// titleAliasTag returns the tag for an alias, to be added to a window title.
+func titleAliasTag(alias string) string {
+	return "[wp:" + alias + "]"
+}
+
+// tagWindowTitle adds an alias tag to the title of a window, replacing an existing tag.
+// Many apps reset their title when it changes, so the tag is most useful in titles
+// the user controls, like terminal prompts.
+func tagWindowTitle(hwnd syscall.Handle, alias string) error {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || strings.ContainsAny(alias, "[]|") {
+		return fmt.Errorf("an alias must not be empty or contain [, ] or |")
+	}
+	title := strings.TrimSpace(titleAliasPattern.ReplaceAllString(getWindowText(hwnd), ""))
+	title = strings.TrimSpace(title + " " + titleAliasTag(alias))
+	titlePtr, err := syscall.UTF16PtrFromString(title)
+	if err != nil {
+		return err
+	}
+	ret, _, err := procSetWindowText.Call(uintptr(hwnd), uintptr(unsafe.Pointer(titlePtr)))
+	if ret == 0 {
+		return fmt.Errorf("SetWindowText failed: %v", err)
+	}
+	return nil
+}
+
+// showTagTitleDialog asks for an alias and tags the title of a window with it.
+// Positions saved afterwards match the window by its alias.
+func (wm *WindowManager) showTagTitleDialog(window WindowInfo) {
+	aliasEntry := widget.NewEntry()
+	if alias, ok := titleAlias(window.Title); ok {
+		aliasEntry.SetText(alias)
+	}
+	aliasEntry.SetPlaceHolder("build-terminal")
+	help := widget.NewLabel("Adds " + titleAliasTag("alias") + " to the window title. Positions saved while the title " +
+		"carries the tag match the window by its alias, however the rest of the title changes. " +
+		"Apps that set their own title may remove the tag; then add it yourself, e.g. to a terminal prompt.")
+	help.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("Alias", aliasEntry),
+		widget.NewFormItem("", help),
+	}
+	form := dialog.NewForm("Tag window title", "Tag", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		if err := tagWindowTitle(window.Handle, aliasEntry.Text); err != nil {
+			log(true, "Failed to tag window title:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(true, "Tagged window title with alias:", aliasEntry.Text)
+		wm.refreshWindowList()
+	}, wm.mainWindow)
+	form.Resize(fyne.NewSize(450, 250))
+	form.Show()
+}
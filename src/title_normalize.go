@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+/*
+	Title normalization:
+	- Apps like browsers put the page or document into the window title, e.g.
+	  "Dashboard — Mozilla Firefox". A normalization pattern per executable (file name, ignoring case)
+	  removes the changing or decorating part before the title is used for an identifier.
+	- The pattern is a regular expression. Without a capture group every match is removed,
+	  e.g. " — Mozilla Firefox$". With a capture group the title is replaced by the first group,
+	  so the stable part of a title can be kept, e.g. "^(\S+) - ".
+	- The same normalization is applied when a position is saved and when windows are matched,
+	  so rules follow windows whose titles change while they are open. A title that would become
+	  empty is kept as it is.
+	- The raw title is stored with a saved position when it differs, and identifiers created before
+	  a pattern existed still match the raw title.
+	- Patterns are edited in the Matching settings, or for one app from the window list.
+*/
+
+// titleNormalizers holds the compiled normalization patterns by lower-case executable name
+var titleNormalizers atomic.Pointer[map[string]*regexp.Regexp]
+
+// setTitleNormalizations compiles the normalization patterns of the settings.
+// Invalid patterns are logged, recorded as a problem and skipped.
+func setTitleNormalizations(patterns map[string]string) {
+	compiled := make(map[string]*regexp.Regexp, len(patterns))
+	for executable, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logError("Skipping title normalization for", executable, err)
+			recordProblem(problemError, executable, "Invalid title normalization pattern:", err)
+			continue
+		}
+		compiled[strings.ToLower(executable)] = re
+	}
+	titleNormalizers.Store(&compiled)
+}
+
+// normalizeTitle returns the title with the normalization pattern of the executable applied.
+func normalizeTitle(title, executable string) string {
+	normalizers := titleNormalizers.Load()
+	if normalizers == nil || title == "" {
+		return title
+	}
+	re, ok := (*normalizers)[strings.ToLower(filepath.Base(executable))]
+	if !ok {
+		return title
+	}
+	return applyTitleNormalization(re, title)
+}
+
+// applyTitleNormalization keeps the first capture group of a pattern, or removes its matches
+// if it has no group. The title is kept if nothing would remain.
+func applyTitleNormalization(re *regexp.Regexp, title string) string {
+	var normalized string
+	if re.NumSubexp() > 0 {
+		match := re.FindStringSubmatch(title)
+		if match == nil {
+			return title
+		}
+		normalized = match[1]
+	} else {
+		normalized = re.ReplaceAllString(title, "")
+	}
+	if normalized = strings.TrimSpace(normalized); normalized == "" {
+		return title
+	}
+	return normalized
+}
+
+// formatTitleNormalizations returns the patterns as lines "executable = pattern", sorted by executable.
+func formatTitleNormalizations(patterns map[string]string) string {
+	executables := make([]string, 0, len(patterns))
+	for executable := range patterns {
+		executables = append(executables, executable)
+	}
+	sort.Strings(executables)
+	lines := make([]string, len(executables))
+	for i, executable := range executables {
+		lines[i] = executable + " = " + patterns[executable]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseTitleNormalizations parses lines "executable = pattern" and checks the patterns.
+func parseTitleNormalizations(text string) (map[string]string, error) {
+	patterns := make(map[string]string)
+	for number, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		executable, pattern, ok := strings.Cut(line, "=")
+		executable, pattern = strings.TrimSpace(executable), strings.TrimSpace(pattern)
+		if !ok || executable == "" || pattern == "" {
+			return nil, fmt.Errorf("title normalization line %d must look like \"app.exe = pattern\"", number+1)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("title normalization for %s: %v", executable, err)
+		}
+		patterns[executable] = pattern
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return patterns, nil
+}
+
+// showTitleNormalizationDialog edits the normalization pattern of the app of a window,
+// previewing the normalized title while typing.
+func (wm *WindowManager) showTitleNormalizationDialog(window WindowInfo) {
+	executable := filepath.Base(window.Executable)
+	if executable == "" || executable == "." {
+		dialog.ShowError(fmt.Errorf("the window has no executable to normalize titles for"), wm.mainWindow)
+		return
+	}
+	var existing string
+	for name, pattern := range wm.getSettings().TitleNormalizations {
+		if strings.EqualFold(name, executable) {
+			executable, existing = name, pattern
+		}
+	}
+
+	patternEntry := widget.NewEntry()
+	patternEntry.SetPlaceHolder(` — Mozilla Firefox$`)
+	preview := widget.NewLabel("")
+	preview.Wrapping = fyne.TextWrapWord
+	updatePreview := func(pattern string) {
+		if pattern == "" {
+			preview.SetText(window.Title)
+			return
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			preview.SetText("Invalid pattern: " + err.Error())
+			return
+		}
+		preview.SetText(applyTitleNormalization(re, window.Title))
+	}
+	patternEntry.OnChanged = updatePreview
+	patternEntry.SetText(existing)
+	updatePreview(existing)
+
+	help := widget.NewLabel("A regular expression. Its matches are removed from the titles of this app, " +
+		"or with a capture group only the first group is kept. Leave it empty to use the titles as they are. " +
+		"Positions saved afterwards match on the normalized title.")
+	help.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("App", widget.NewLabel(executable)),
+		widget.NewFormItem("Title", widget.NewLabel(window.Title)),
+		widget.NewFormItem("Pattern", patternEntry),
+		widget.NewFormItem("Normalized", preview),
+		widget.NewFormItem("", help),
+	}
+	form := dialog.NewForm("Normalize titles", "Save", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		pattern := strings.TrimSpace(patternEntry.Text)
+		if _, err := regexp.Compile(pattern); err != nil {
+			dialog.ShowError(fmt.Errorf("invalid pattern: %v", err), wm.mainWindow)
+			return
+		}
+		err := wm.updateSettings(func(s *Settings) {
+			if pattern == "" {
+				delete(s.TitleNormalizations, executable)
+				return
+			}
+			if s.TitleNormalizations == nil {
+				s.TitleNormalizations = make(map[string]string)
+			}
+			s.TitleNormalizations[executable] = pattern
+		})
+		if err != nil {
+			log(true, "Failed to save title normalization:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		setTitleNormalizations(wm.getSettings().TitleNormalizations)
+		log(true, "Title normalization for", executable, "set to", pattern)
+		wm.refreshWindowList()
+	}, wm.mainWindow)
+	form.Resize(fyne.NewSize(520, 0))
+	form.Show()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+/*
+	Title patterns:
+	- The title component of an identifier can be a pattern, so a rule keeps matching when the title
+	  contains a changing document name, e.g. "* - Notepad".
+	- Patterns are explicit: only rules with TitlePattern set, which the rule editor sets on request,
+	  treat their title as a pattern. Other titles match exactly, also if they contain "*", "?" or
+	  slashes, like "*Untitled - Notepad" or "Save changes?". The command line and the control pipe
+	  look up windows by pattern only when asked to.
+	- In a glob, "*" matches any text and "?" a single character. A title consisting of "*" only
+	  is the wildcard of the identifier composition.
+	- A title enclosed in slashes, e.g. "/^Report \d+ - Excel$/", is a regular expression. It matches
+	  anywhere in the title unless it is anchored.
+	- Patterns are compiled once and cached. An invalid regular expression is logged and recorded as
+	  a problem once, and its rule does not match any window.
+*/
+
+// titlePatterns caches the compiled title patterns, nil for invalid patterns
+var titlePatterns sync.Map
+
+// isRegexTitle reports whether a title component is a regular expression.
+func isRegexTitle(title string) bool {
+	return len(title) >= 2 && strings.HasPrefix(title, "/") && strings.HasSuffix(title, "/")
+}
+
+// compileTitlePattern compiles a glob or a regular expression title component.
+func compileTitlePattern(pattern string) (*regexp.Regexp, error) {
+	if isRegexTitle(pattern) {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern %s: %v", pattern, err)
+		}
+		return re, nil
+	}
+	var expr strings.Builder
+	expr.WriteString("(?s)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// cachedTitlePattern returns the compiled title pattern, or nil if it is invalid.
+func cachedTitlePattern(pattern string) *regexp.Regexp {
+	if cached, ok := titlePatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := compileTitlePattern(pattern)
+	if err != nil {
+		logError("Skipping rule with", err)
+		recordProblem(problemError, redactTitle(pattern), "Invalid title pattern:", err)
+	}
+	titlePatterns.Store(pattern, re)
+	return re
+}
+
+// titleMatches checks if a window title matches the title component of an identifier.
+// The component is only used as a pattern if pattern is set.
+func titleMatches(component, title string, pattern bool) bool {
+	if component == identifierWildcard || component == title {
+		return true
+	}
+	if !pattern {
+		return false
+	}
+	re := cachedTitlePattern(component)
+	return re != nil && re.MatchString(title)
+}
+
+// findWindowByTitle returns the first window whose title matches a title, or a title pattern if pattern is set.
+func findWindowByTitle(title string, pattern bool, windows []WindowInfo) (WindowInfo, bool) {
+	for _, window := range windows {
+		if titleMatches(title, window.Title, pattern) {
+			return window, true
+		}
+	}
+	return WindowInfo{}, false
+}
+
+// withTitle returns the identifier with the title component replaced, keeping an icon hash.
+func withTitle(identifier, title string) string {
+	parts, ok := splitIdentifier(identifier)
+	if !ok {
+		return identifier
+	}
+	parts[0] = title
+	_, hash := splitIconHash(identifier)
+	return withIconHash(joinIdentifier(parts), hash)
+}
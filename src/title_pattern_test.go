@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+// TestTitleMatches checks exact titles, globs and regular expressions as title components.
+func TestTitleMatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		component string
+		title     string
+		pattern   bool
+		want      bool
+	}{
+		{"exact title", "Save changes?", "Save changes?", false, true},
+		{"glob characters without pattern", "*Untitled - Notepad", "notes.txt - Notepad", false, false},
+		{"question mark without pattern", "Save changes?", "Save changesX", false, false},
+		{"slashes without pattern", "/tmp/", "/tmp/x", false, false},
+		{"identifier wildcard", identifierWildcard, "Anything", false, true},
+		{"glob star", "* - Notepad", "notes.txt - Notepad", true, true},
+		{"glob star needs the suffix", "* - Notepad", "Notepad", true, false},
+		{"glob question mark", "?ord", "Word", true, true},
+		{"glob question mark is one character", "?ord", "Woord", true, false},
+		{"glob quotes regexp characters", "a.b (1)*", "a.b (1) - Viewer", true, true},
+		{"glob dot is literal", "a.b (1)*", "axb (1) - Viewer", true, false},
+		{"glob star spans lines", "First*", "First\nSecond", true, true},
+		{"anchored regexp", `/^Report \d+ - Excel$/`, "Report 12 - Excel", true, true},
+		{"anchored regexp mismatch", `/^Report \d+ - Excel$/`, "Report x - Excel", true, false},
+		{"unanchored regexp", "/Excel/", "Book1 - Excel", true, true},
+		{"invalid regexp", "/[/", "[", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := titleMatches(test.component, test.title, test.pattern); got != test.want {
+				t.Errorf("titleMatches(%q, %q, %v) = %v, want %v", test.component, test.title, test.pattern, got, test.want)
+			}
+		})
+	}
+}
+
+// TestFindWindowByTitle checks that a title only finds other windows if it is a pattern.
+func TestFindWindowByTitle(t *testing.T) {
+	windows := []WindowInfo{
+		{Handle: 1, Title: "notes.txt - Notepad"},
+		{Handle: 2, Title: "* - Notepad"},
+	}
+	if window, ok := findWindowByTitle("* - Notepad", false, windows); !ok || window.Handle != 2 {
+		t.Errorf("exact title found %v, %v, want window 2", window.Handle, ok)
+	}
+	if window, ok := findWindowByTitle("* - Notepad", true, windows); !ok || window.Handle != 1 {
+		t.Errorf("title pattern found %v, %v, want window 1", window.Handle, ok)
+	}
+	if _, ok := findWindowByTitle("Calculator", true, windows); ok {
+		t.Error("found a window for a title that matches none")
+	}
+}
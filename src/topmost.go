@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+/*
+	Always on top:
+	- A rule with AlwaysOnTop keeps its window topmost. Every repositioning pass reasserts it,
+	  as other applications and some move strategies remove the topmost state.
+	- The pin button in the window list toggles the topmost state of a window right away.
+	  If a saved position matches the window, the state is stored in it, so it persists.
+*/
+
+// setTopmost makes a window topmost or removes the topmost state, without moving or activating it.
+func setTopmost(hwnd syscall.Handle, topmost bool) error {
+	insertAfter := HWND_NOTOPMOST
+	if topmost {
+		insertAfter = HWND_TOPMOST
+	}
+	ret, _, err := procSetWindowPos.Call(uintptr(hwnd), insertAfter, 0, 0, 0, 0, SWP_NOMOVE|SWP_NOSIZE|SWP_NOACTIVATE)
+	if ret == 0 {
+		return fmt.Errorf("SetWindowPos failed: %v", err)
+	}
+	return nil
+}
+
+// isTopmost checks if a window is topmost.
+func isTopmost(hwnd syscall.Handle) bool {
+	exStyle, err := getWindowLong(hwnd, GWL_EXSTYLE)
+	return err == nil && uint32(exStyle)&WS_EX_TOPMOST != 0
+}
+
+// toggleTopmost toggles the topmost state of a window and stores it in the saved position matching the window.
+func (wm *WindowManager) toggleTopmost(window WindowInfo) error {
+	debug := true
+	if !isValidWindow(window.Handle) {
+		return fmt.Errorf("window no longer exists: %s", window.Title)
+	}
+	topmost := !isTopmost(window.Handle)
+	if err := setTopmost(window.Handle, topmost); err != nil {
+		return err
+	}
+	log(debug, "Set topmost", topmost, "for:", redactTitle(window.Title))
+
+	identifier, pos, ok := findSavedPosition(window, wm.storage.GetAllPositions())
+	if !ok {
+		return nil
+	}
+	pos.AlwaysOnTop = topmost
+	if err := wm.storage.SavePosition(identifier, pos); err != nil {
+		return fmt.Errorf("failed to save the topmost state: %v", err)
+	}
+	log(debug, "Stored topmost", topmost, "in:", redactIdentifier(identifier))
+	return nil
+}
+
+// reassertTopmost makes the window of a match topmost again if its rule asks for it.
+func (wm *WindowManager) reassertTopmost(match repositionMatch) {
+	if !match.Position.AlwaysOnTop || !isValidWindow(match.Window.Handle) || isTopmost(match.Window.Handle) {
+		return
+	}
+	if err := setTopmost(match.Window.Handle, true); err != nil {
+		logError("Failed to keep window topmost", redactIdentifier(match.Identifier)+":", err)
+		recordProblem(problemError, redactIdentifier(match.Identifier), "Failed to keep window topmost:", err)
+		return
+	}
+	log(true, "Made window topmost again:", redactIdentifier(match.Identifier))
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+/*
+	Undo:
+	- Before a window is moved, its placement (normal rectangle and state) is captured in an
+	  in-memory history. "Undo last arrangement" in the tray or the undo hotkey puts the windows
+	  of the last arrangement back.
+	- An arrangement is a repositioning pass, applying a group or rescuing windows, which call
+	  beginArrangement. Single moves, e.g. by a tiling hotkey, start a new arrangement when the
+	  previous capture is older than undoArrangementGap. Only the first capture of a window per
+	  arrangement is kept, so a window moved twice returns to where it was before.
+	- The history is bounded to undoLimit moves. Entries of closed windows are dropped.
+	- Undone windows are recorded in the repositioning cooldown, so the monitoring service does
+	  not move them right back, see reposition_cooldown.go.
+*/
+
+const (
+	undoLimit          = 50              // Moves kept in the history
+	undoArrangementGap = 5 * time.Second // Moves further apart than this belong to different arrangements
+)
+
+// undoEntry is the placement of a window before it was moved
+type undoEntry struct {
+	Handle      syscall.Handle
+	Placement   WINDOWPLACEMENT
+	Arrangement int
+}
+
+// undoHistory holds the placements of moved windows, newest last
+type undoHistory struct {
+	mutex       sync.Mutex
+	entries     []undoEntry
+	arrangement int       // Arrangement of the last capture
+	startNew    bool      // The next capture starts a new arrangement
+	lastCapture time.Time // Time of the last capture
+}
+
+// moveHistory is the undo history of the application. Moves are captured in moveWindowWithOptions,
+// which has no window manager, so there is only one instance.
+var moveHistory = &undoHistory{}
+
+// beginArrangement starts a new arrangement with the next captured move.
+func (h *undoHistory) beginArrangement() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.startNew = true
+}
+
+// capture records the placement of a window before it is moved, once per arrangement.
+func (h *undoHistory) capture(hwnd syscall.Handle) {
+	var placement WINDOWPLACEMENT
+	placement.Length = uint32(unsafe.Sizeof(placement))
+	if ret, _, _ := procGetWindowPlacement.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&placement))); ret == 0 {
+		return
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	now := time.Now()
+	if h.startNew || now.Sub(h.lastCapture) > undoArrangementGap {
+		h.arrangement++
+		h.startNew = false
+	}
+	h.lastCapture = now
+	for _, entry := range h.entries {
+		if entry.Arrangement == h.arrangement && entry.Handle == hwnd {
+			return
+		}
+	}
+	h.entries = append(h.entries, undoEntry{Handle: hwnd, Placement: placement, Arrangement: h.arrangement})
+	if len(h.entries) > undoLimit {
+		h.entries = h.entries[len(h.entries)-undoLimit:]
+	}
+}
+
+// popArrangement removes and returns the entries of the last arrangement that still has open windows.
+func (h *undoHistory) popArrangement() []undoEntry {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	kept := h.entries[:0]
+	for _, entry := range h.entries {
+		if isValidWindow(entry.Handle) {
+			kept = append(kept, entry)
+		}
+	}
+	h.entries = kept
+	if len(h.entries) == 0 {
+		return nil
+	}
+	last := h.entries[len(h.entries)-1].Arrangement
+	split := len(h.entries)
+	for split > 0 && h.entries[split-1].Arrangement == last {
+		split--
+	}
+	popped := append([]undoEntry(nil), h.entries[split:]...)
+	h.entries = h.entries[:split]
+	h.startNew = true // Moves after an undo do not join the undone arrangement
+	return popped
+}
+
+// restorePlacement puts a window back to a captured placement without activating it.
+func restorePlacement(entry undoEntry) error {
+	placement := entry.Placement
+	switch placement.ShowCmd {
+	case SW_SHOWMINIMIZED:
+		placement.ShowCmd = SW_SHOWMINNOACTIVE
+	case SW_SHOWMAXIMIZED:
+	default:
+		placement.ShowCmd = SW_SHOWNOACTIVATE
+	}
+	ret, _, err := procSetWindowPlacement.Call(uintptr(entry.Handle), uintptr(unsafe.Pointer(&placement)))
+	if ret == 0 {
+		return fmt.Errorf("SetWindowPlacement failed: %v", err)
+	}
+	return nil
+}
+
+// undoLastArrangement puts the windows of the last arrangement back and returns the number of
+// restored windows. It returns false if there is nothing to undo.
+func (wm *WindowManager) undoLastArrangement() (int, bool) {
+	debug := true
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
+
+	entries := moveHistory.popArrangement()
+	if len(entries) == 0 {
+		log(debug, "Nothing to undo.")
+		return 0, false
+	}
+	positions := wm.storage.GetAllPositions()
+	now := time.Now()
+	restored := 0
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		window := getWindowInfo(entry.Handle)
+		if err := restorePlacement(entry); err != nil {
+			logWarn("Failed to undo the move of", redactTitle(window.Title)+":", err)
+			continue
+		}
+		restored++
+		if identifier, _, ok := findSavedPosition(window, positions); ok {
+			if current, err := getWindowPosition(entry.Handle); err == nil {
+				wm.cooldown.record(identifier, entry.Handle, current.Rect(), now)
+			}
+		}
+	}
+	log(debug, "Undid the last arrangement,", restored, "of", len(entries), "windows restored.")
+	return restored, true
+}
+
+// runUndoLastArrangement undoes the last arrangement in the background and reports the result.
+func (wm *WindowManager) runUndoLastArrangement() {
+	go func() {
+		defer panicHandler()
+		restored, ok := wm.undoLastArrangement()
+		if !ok {
+			messageBeep()
+			return
+		}
+		message := fmt.Sprintf("Restored %d windows to where they were before the last arrangement.", restored)
+		wm.notify(message)
+	}()
+}
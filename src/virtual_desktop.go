@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+/*
+	Virtual desktops:
+	- Uses the documented IVirtualDesktopManager COM interface, available since Windows 10.
+	- A rule chooses what happens if its window is on another virtual desktop than the current one:
+	  leave the desktop alone and position the window anyway (the default), only position it while
+	  it is on the current desktop, move it to the desktop stored in the rule, or bring it to the
+	  current desktop.
+	- Saving a position stores the desktop the window is on, so the rule can be switched to moving
+	  the window to its desktop later.
+	- Windows only allows MoveWindowToDesktop for windows of the calling process, so moving the
+	  windows of other apps fails with E_ACCESSDENIED on most systems. The failure is logged and
+	  the window is positioned on its current desktop.
+	- If the interface is not available, the rules behave like the default.
+*/
+
+// Virtual desktop policies of a rule
+const (
+	virtualDesktopLeave   = ""        // Position the window on whatever desktop it is
+	virtualDesktopCurrent = "current" // Only position the window while it is on the current desktop
+	virtualDesktopMove    = "move"    // Move the window to the rule's desktop, then position it
+	virtualDesktopBring   = "bring"   // Move the window to the current desktop, then position it
+)
+
+// nullDesktopID is returned by GetWindowDesktopId for windows that are on no desktop, like the taskbar
+const nullDesktopID = "{00000000-0000-0000-0000-000000000000}"
+
+// CLSID_VirtualDesktopManager and IID_IVirtualDesktopManager
+var (
+	clsidVirtualDesktopManager = windows.GUID{Data1: 0xAA509086, Data2: 0x5CA9, Data3: 0x4C25, Data4: [8]byte{0x8F, 0x95, 0x58, 0x9D, 0x3C, 0x07, 0xB4, 0x8A}}
+	iidIVirtualDesktopManager  = windows.GUID{Data1: 0xA5CD92FF, Data2: 0x29BE, Data3: 0x454C, Data4: [8]byte{0x8D, 0x04, 0xD8, 0x28, 0x79, 0xFB, 0x3C, 0x1B}}
+)
+
+// IVirtualDesktopManager interface definition
+type IVirtualDesktopManager struct {
+	vtbl *IVirtualDesktopManagerVtbl
+}
+type IVirtualDesktopManagerVtbl struct {
+	QueryInterface                  uintptr // Retrieves a pointer to another interface of the object
+	AddRef                          uintptr // Increments the reference count
+	Release                         uintptr // Decrements the reference count
+	IsWindowOnCurrentVirtualDesktop uintptr // Checks if a window is on the current virtual desktop
+	GetWindowDesktopId              uintptr // Retrieves the ID of the virtual desktop of a window
+	MoveWindowToDesktop             uintptr // Moves a window of the calling process to a virtual desktop
+}
+
+// withVirtualDesktopManager creates the virtual desktop manager, calls the function with it and releases it.
+// COM is initialized for the call, so the goroutine is locked to its thread meanwhile.
+func withVirtualDesktopManager(function func(manager *IVirtualDesktopManager) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	procCoInitialize.Call(uintptr(0))
+	defer procCoUninitialize.Call()
+
+	var manager *IVirtualDesktopManager
+	hr, _, _ := procCoCreateInstance.Call(
+		uintptr(unsafe.Pointer(&clsidVirtualDesktopManager)),
+		0,
+		CLSCTX_ALL,
+		uintptr(unsafe.Pointer(&iidIVirtualDesktopManager)),
+		uintptr(unsafe.Pointer(&manager)),
+	)
+	if hr != 0 || manager == nil {
+		return fmt.Errorf("virtual desktops are not available: 0x%08X", uint32(hr))
+	}
+	defer syscall.SyscallN(manager.vtbl.Release, uintptr(unsafe.Pointer(manager)))
+	return function(manager)
+}
+
+// isWindowOnCurrentDesktop checks if a window is on the current virtual desktop.
+func isWindowOnCurrentDesktop(hwnd syscall.Handle) (bool, error) {
+	var onCurrent int32
+	err := withVirtualDesktopManager(func(manager *IVirtualDesktopManager) error {
+		hr, _, _ := syscall.SyscallN(manager.vtbl.IsWindowOnCurrentVirtualDesktop,
+			uintptr(unsafe.Pointer(manager)), uintptr(hwnd), uintptr(unsafe.Pointer(&onCurrent)))
+		if hr != 0 {
+			return fmt.Errorf("IsWindowOnCurrentVirtualDesktop failed: 0x%08X", uint32(hr))
+		}
+		return nil
+	})
+	return onCurrent != 0, err
+}
+
+// getWindowDesktopID returns the ID of the virtual desktop a window is on.
+func getWindowDesktopID(hwnd syscall.Handle) (string, error) {
+	var id windows.GUID
+	err := withVirtualDesktopManager(func(manager *IVirtualDesktopManager) error {
+		hr, _, _ := syscall.SyscallN(manager.vtbl.GetWindowDesktopId,
+			uintptr(unsafe.Pointer(manager)), uintptr(hwnd), uintptr(unsafe.Pointer(&id)))
+		if hr != 0 {
+			return fmt.Errorf("GetWindowDesktopId failed: 0x%08X", uint32(hr))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// moveWindowToDesktop moves a window to the virtual desktop with the given ID.
+func moveWindowToDesktop(hwnd syscall.Handle, desktopID string) error {
+	id, err := windows.GUIDFromString(desktopID)
+	if err != nil {
+		return fmt.Errorf("invalid virtual desktop ID '%s': %v", desktopID, err)
+	}
+	return withVirtualDesktopManager(func(manager *IVirtualDesktopManager) error {
+		hr, _, _ := syscall.SyscallN(manager.vtbl.MoveWindowToDesktop,
+			uintptr(unsafe.Pointer(manager)), uintptr(hwnd), uintptr(unsafe.Pointer(&id)))
+		if hr != 0 {
+			return fmt.Errorf("MoveWindowToDesktop failed: 0x%08X", uint32(hr))
+		}
+		return nil
+	})
+}
+
+// currentDesktopID returns the ID of the current virtual desktop. The documented interface cannot
+// query it directly, so it is the desktop of the topmost window that is on the current desktop.
+func currentDesktopID() (string, error) {
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return "", err
+	}
+	for _, window := range windows { // In z-order, topmost first
+		if onCurrent, err := isWindowOnCurrentDesktop(window.Handle); err != nil {
+			return "", err
+		} else if !onCurrent {
+			continue
+		}
+		if id, err := getWindowDesktopID(window.Handle); err == nil && id != nullDesktopID {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no window on the current virtual desktop")
+}
+
+// windowDesktopID returns the virtual desktop ID of a window to store in its rule,
+// or an empty string if virtual desktops are not available or the window is on no desktop.
+func windowDesktopID(hwnd syscall.Handle) string {
+	debug := false
+	id, err := getWindowDesktopID(hwnd)
+	if err != nil || id == nullDesktopID {
+		log(debug, "No virtual desktop stored for window:", hwnd, err)
+		return ""
+	}
+	return id
+}
+
+// captureDesktopID returns the virtual desktop ID of an open window matching the identifier.
+func captureDesktopID(identifier string, titlePattern bool) (string, error) {
+	windows, err := EnumerateWindows()
+	if err != nil {
+		return "", err
+	}
+	window, ok := findWindowForIdentifier(identifier, titlePattern, windows)
+	if !ok {
+		return "", fmt.Errorf("open the window on its virtual desktop to store the desktop")
+	}
+	return getWindowDesktopID(window.Handle)
+}
+
+// applyVirtualDesktopPolicy applies the virtual desktop policy of a rule before its window is positioned.
+// It returns false if the window should not be positioned now.
+func applyVirtualDesktopPolicy(hwnd syscall.Handle, identifier string, pos WindowPosition) bool {
+	debug := false
+	switch pos.VirtualDesktop {
+	case virtualDesktopCurrent:
+		onCurrent, err := isWindowOnCurrentDesktop(hwnd)
+		if err != nil {
+			log(debug, "Cannot check the virtual desktop, positioning anyway:", err)
+			return true
+		}
+		if !onCurrent {
+			log(debug, "Window is on another virtual desktop, skipping:", redactIdentifier(identifier))
+		}
+		return onCurrent
+	case virtualDesktopMove:
+		if pos.VirtualDesktopID == "" {
+			return true
+		}
+		current, err := getWindowDesktopID(hwnd)
+		if err != nil || current == pos.VirtualDesktopID {
+			return true
+		}
+		if err := moveWindowToDesktop(hwnd, pos.VirtualDesktopID); err != nil {
+			logWarn("Failed to move window to its virtual desktop", redactIdentifier(identifier)+":", err)
+			recordProblem(problemWarning, redactIdentifier(identifier), "Failed to move window to its virtual desktop:", err)
+		}
+	case virtualDesktopBring:
+		onCurrent, err := isWindowOnCurrentDesktop(hwnd)
+		if err != nil || onCurrent {
+			return true
+		}
+		current, err := currentDesktopID()
+		if err == nil {
+			err = moveWindowToDesktop(hwnd, current)
+		}
+		if err != nil {
+			logWarn("Failed to bring window to the current virtual desktop", redactIdentifier(identifier)+":", err)
+			recordProblem(problemWarning, redactIdentifier(identifier), "Failed to bring window to the current virtual desktop:", err)
+			return true
+		}
+		log(debug, "Brought window to the current virtual desktop:", redactIdentifier(identifier))
+	}
+	return true
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+/*
+	Window events:
+	- SetWinEventHook reports windows that are shown, moved or brought to the foreground, so a new
+	  window is positioned right away instead of at the next monitoring cycle.
+	- The hook is out of context: the callback runs on the thread that installed the hook, which
+	  needs a message loop. Events of the own process are skipped.
+	- Events come in bursts (dragging a window reports every mouse move), so the windows are
+	  collected until no event arrived for winEventDebounce. A repositioning pass is then requested
+	  from the monitoring service if one of them matches a saved position.
+	- Our own moves also report location changes. The following pass finds the windows in place,
+	  so it does not move anything and the events stop.
+	- While the hooks run, the monitoring service still checks the windows every winEventFallbackInterval,
+	  so changes that no window event announces, like a rule schedule becoming active, are applied.
+*/
+
+const (
+	winEventDebounce         = 300 * time.Millisecond // Quiet time after the last event before the windows are checked
+	winEventFallbackInterval = time.Minute            // Slowest interval of the monitoring ticker while the hooks run
+)
+
+// winEventHandler receives the windows that reported events since the last call
+type winEventHandler func(handles []syscall.Handle)
+
+// WinEventWatcher installs the window event hooks and runs their message loop on a locked OS thread.
+type WinEventWatcher struct {
+	mutex    sync.Mutex
+	threadID uintptr       // Thread running the message loop, 0 if not running
+	done     chan struct{} // Closed when the message loop exits
+	handler  winEventHandler
+	pending  map[syscall.Handle]bool // Windows that reported events since the last call of the handler
+	timer    *time.Timer             // Debounce timer, calls the handler
+}
+
+// winEvents is the window event watcher of the application. The hook procedure
+// is a global callback, so there is only one instance.
+var winEvents = &WinEventWatcher{}
+
+// Global hook procedure callback, created once
+var globalWinEventProc uintptr
+
+func init() {
+	globalWinEventProc = syscall.NewCallback(winEventProc)
+}
+
+// winEventProc is the WINEVENTPROC of the hooks. It only records the window, the handler
+// runs later on its own goroutine so the message loop is never blocked.
+func winEventProc(hook, event, hwnd, idObject, idChild, eventThread, eventTime uintptr) uintptr {
+	// Events of the window itself, not of its scroll bars, carets or the cursor
+	if hwnd == 0 || int32(idObject) != OBJID_WINDOW || int32(idChild) != CHILDID_SELF {
+		return 0
+	}
+	winEvents.record(syscall.Handle(hwnd))
+	return 0
+}
+
+// record adds a window to the pending windows and restarts the debounce timer.
+func (we *WinEventWatcher) record(hwnd syscall.Handle) {
+	we.mutex.Lock()
+	defer we.mutex.Unlock()
+	if we.pending == nil {
+		we.pending = make(map[syscall.Handle]bool)
+	}
+	we.pending[hwnd] = true
+	if we.timer != nil {
+		we.timer.Stop()
+	}
+	we.timer = time.AfterFunc(winEventDebounce, we.flush)
+}
+
+// flush passes the pending windows to the handler.
+func (we *WinEventWatcher) flush() {
+	defer panicHandler()
+	we.mutex.Lock()
+	handler := we.handler
+	handles := make([]syscall.Handle, 0, len(we.pending))
+	for hwnd := range we.pending {
+		handles = append(handles, hwnd)
+	}
+	we.pending = nil
+	we.timer = nil
+	we.mutex.Unlock()
+	if handler != nil && len(handles) > 0 {
+		handler(handles)
+	}
+}
+
+// Running reports whether the hooks are installed.
+func (we *WinEventWatcher) Running() bool {
+	we.mutex.Lock()
+	defer we.mutex.Unlock()
+	return we.threadID != 0
+}
+
+// Start installs the hooks and runs the message loop until ctx is cancelled or Stop is called.
+func (we *WinEventWatcher) Start(ctx context.Context, handler winEventHandler) error {
+	we.Stop()
+	we.mutex.Lock()
+	we.handler = handler
+	we.mutex.Unlock()
+
+	ready := make(chan error)
+	done := make(chan struct{})
+	go we.messageLoop(ready, done)
+	if err := <-ready; err != nil {
+		return err
+	}
+	we.mutex.Lock()
+	we.done = done
+	we.mutex.Unlock()
+
+	go func() {
+		defer panicHandler()
+		select {
+		case <-ctx.Done():
+			we.Stop()
+		case <-done:
+		}
+	}()
+	return nil
+}
+
+// Stop removes the hooks, ends the message loop and drops pending events.
+func (we *WinEventWatcher) Stop() {
+	we.mutex.Lock()
+	threadID, done := we.threadID, we.done
+	if we.timer != nil {
+		we.timer.Stop()
+		we.timer = nil
+	}
+	we.pending = nil
+	we.mutex.Unlock()
+	if threadID == 0 || done == nil {
+		return
+	}
+	procPostThreadMessage.Call(threadID, WM_QUIT, 0, 0)
+	<-done
+}
+
+// messageLoop installs the hooks on a locked OS thread and pumps its messages, which delivers
+// the events, until WM_QUIT is received. The installation result is reported on the ready channel.
+func (we *WinEventWatcher) messageLoop(ready chan<- error, done chan struct{}) {
+	debug := true
+	defer panicHandler()
+	defer close(done)
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// Make sure the thread has a message queue before anyone posts to it
+	var msg MSG
+	procPeekMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, WM_USER, WM_USER, PM_NOREMOVE)
+
+	var hooks []uintptr
+	defer func() {
+		for _, hook := range hooks {
+			procUnhookWinEvent.Call(hook)
+		}
+		if len(hooks) > 0 {
+			log(debug, "Window event hooks removed.")
+		}
+	}()
+	// Separate hooks, the event ranges in between contain many events that are not needed
+	for _, event := range []uintptr{EVENT_SYSTEM_FOREGROUND, EVENT_OBJECT_SHOW, EVENT_OBJECT_LOCATIONCHANGE} {
+		hook, _, err := procSetWinEventHook.Call(event, event, 0, globalWinEventProc, 0, 0,
+			WINEVENT_OUTOFCONTEXT|WINEVENT_SKIPOWNPROCESS)
+		if hook == 0 {
+			ready <- fmt.Errorf("SetWinEventHook failed for event 0x%04X: %v", event, err)
+			return
+		}
+		hooks = append(hooks, hook)
+	}
+
+	threadID, _, _ := procGetCurrentThreadId.Call()
+	we.mutex.Lock()
+	we.threadID = threadID
+	we.mutex.Unlock()
+	defer func() {
+		we.mutex.Lock()
+		we.threadID = 0
+		we.mutex.Unlock()
+	}()
+	log(debug, "Window event hooks installed.")
+	ready <- nil
+
+	for {
+		ret, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+		if int32(ret) <= 0 { // WM_QUIT or error
+			return
+		}
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+}
+
+// reloadWindowEvents installs or removes the window event hooks according to the settings.
+// The monitoring service only polls at its interval while the hooks are not installed.
+func (wm *WindowManager) reloadWindowEvents() error {
+	defer wm.resetMonitoring()
+	if !wm.getSettings().WatchWindowEvents {
+		winEvents.Stop()
+		return nil
+	}
+	return winEvents.Start(wm.ctx, wm.onWindowEvents)
+}
+
+// onWindowEvents requests a repositioning pass if a window that reported an event
+// matches a saved position, or may get the default rule.
+func (wm *WindowManager) onWindowEvents(handles []syscall.Handle) {
+	debug := false
+	settings := wm.getSettings()
+	positions := activeRules(wm.storage.GetAllPositions(), time.Now())
+	for _, hwnd := range handles {
+		if !isValidWindow(hwnd) {
+			continue
+		}
+		window := getWindowInfo(hwnd)
+		if _, _, ok := findSavedPosition(window, positions); ok || settings.DefaultRuleEnabled {
+			log(debug, "Window event from", redactTitle(window.Title)+", requesting a repositioning pass.")
+			select {
+			case wm.windowEvent <- struct{}{}:
+			default: // A pass is already pending
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+)
+
+/*
+	Window list output:
+	- The window list is written as one pretty-printed JSON array by default.
+	- The JSON lines format writes one compact WindowInfo per line, so consumers can process
+	  the windows of a busy desktop line by line. The lines go through a bufio.Writer, which
+	  writes them in chunks and is flushed at the end, so a consumer may see several lines at once.
+	- Used by the window list export, and meant for a command line or HTTP window list.
+*/
+
+// Window list formats
+const (
+	windowListJSON  = "json"  // Pretty-printed JSON array
+	windowListJSONL = "jsonl" // One compact JSON object per line
+)
+
+// writeWindowList writes the windows in the given format.
+func writeWindowList(w io.Writer, windows []WindowInfo, format string) error {
+	switch format {
+	case windowListJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(windows)
+	case windowListJSONL:
+		buffered := bufio.NewWriter(w)
+		encoder := json.NewEncoder(buffered) // Encode terminates each value with a newline
+		for _, window := range windows {
+			if err := encoder.Encode(window); err != nil {
+				return err
+			}
+		}
+		return buffered.Flush()
+	}
+	return fmt.Errorf("unknown window list format '%s'", format)
+}
+
+// exportWindowList writes the current windows to a file chosen by the user.
+// Files ending in .jsonl are written as JSON lines.
+func (wm *WindowManager) exportWindowList() {
+	debug := true
+	windows, err := EnumerateWindows()
+	if err != nil {
+		log(true, "Failed to enumerate windows:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		defer panicHandler()
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+		format := windowListJSON
+		if strings.EqualFold(writer.URI().Extension(), ".jsonl") {
+			format = windowListJSONL
+		}
+		if err := writeWindowList(writer, windows, format); err != nil {
+			log(true, "Failed to export window list:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Exported", len(windows), "windows as", format, "to", writer.URI().Path())
+	}, wm.mainWindow)
+	save.SetFileName("windows.json")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".json", ".jsonl"}))
+	save.Show()
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -15,38 +20,127 @@ import (
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"fyne.io/systray"
 )
 
 // WindowManager manages the main application window and the list of windows
 // It provides functionality to enumerate, save, and apply window positions.
 type WindowManager struct {
+	ctx            context.Context // Application context, cancelled on shutdown
 	app            fyne.App
+	desk           desktop.App // System tray support, nil if not available
 	mainWindow     fyne.Window
 	storage        *PositionStorage
 	windowList     *widget.List
 	windows        []WindowInfo
 	windowsMutex   sync.RWMutex // Mutex to protect access to the windows slice
+	windowFilter   string       // Filter of the window list, only used on the Fyne main thread
+	listed         []WindowInfo // Filtered and sorted windows shown in the window list, see updateListedWindows
 	operationMutex sync.Mutex   // Mutex to protect operations that modify the window list
+	snoozeUntil    time.Time    // Positioning is suspended until this time
+	snoozeCancel   context.CancelFunc
+	snoozeMutex    sync.Mutex // Mutex to protect the snooze state
+	settings       Settings
+	settingsMutex  sync.RWMutex // Mutex to protect access to the settings
+	settingsWindow fyne.Window  // Settings window, nil if not open
+	logWindow      fyne.Window  // Log window, nil if not open
+	hotkeys        *HotkeyManager
+
+	reportedConflicts map[string]bool // Conflicts already logged, protected by operationMutex
+	pendingMatches    map[string]int  // Matched windows not yet added to the MatchCount of the rules, protected by operationMutex
+
+	scriptTargets      map[syscall.Handle]scriptTarget // Targets computed by the script hook, see scriptedTarget
+	scriptTargetsMutex sync.Mutex                      // Mutex to protect the script targets
+
+	storageErr error // No writable directory was found for the settings and positions, see NewPositionStorage
+
+	strategyStats      StrategyStats // Recorded move strategy outcomes per executable
+	strategyStatsDirty bool          // The statistics changed since they were saved
+	strategyStatsMutex sync.Mutex    // Mutex to protect the strategy statistics
+
+	moveDecisions      map[syscall.Handle]bool // User decisions about moving elevated windows
+	moveDecisionsMutex sync.Mutex              // Mutex to protect the move decisions
+
+	tracker  *windowTracker      // First-seen times of the windows, updated by each repositioning pass
+	cooldown *repositionCooldown // Windows moved recently, left alone by the monitoring service
+
+	power   powerWatcher   // Switches profiles when the power source changes
+	helper  elevatedHelper // Moves windows of elevated processes, started on demand
+	control controlServer  // Accepts commands of other programs on the control pipe
+
+	monitorInterval      time.Duration // Interval of the monitoring service, 0 disables the periodic repositioning
+	monitorIntervalMutex sync.Mutex    // Mutex to protect the monitoring interval
+	monitorReset         chan struct{} // Signals the monitoring service to restart its ticker
+	windowEvent          chan struct{} // Requests a monitoring cycle after window events, see win_events.go
+
+	selected   map[syscall.Handle]bool // Windows checked in the window list, only used on the Fyne main thread
+	listCursor syscall.Handle          // Window selected in the window list, only used on the Fyne main thread
+
+	savedList       *widget.List    // List of the saved positions, only used on the Fyne main thread
+	liveIdentifiers map[string]bool // Saved positions matching an open window at the last refresh, only used on the Fyne main thread
+
+	positioned *positionedSet // Identifiers positioned in this session, used by the "once" repositioning mode
+
+	fullscreenPaused bool // The last monitoring cycle was skipped for a fullscreen window, only used by the monitoring service
 }
 
 // NewWindowManager initializes the WindowManager with the given application
-func NewWindowManager(app fyne.App) *WindowManager {
+// The context is used to stop background work like the snooze timer on shutdown.
+func NewWindowManager(ctx context.Context, app fyne.App) *WindowManager {
 	wm := &WindowManager{
+		ctx:     ctx,
 		app:     app,
-		storage: NewPositionStorage(),
+		hotkeys: NewHotkeyManager(ctx),
+		tracker: newWindowTracker(),
+
+		cooldown: newRepositionCooldown(),
+
+		selected: make(map[syscall.Handle]bool),
+
+		positioned: newPositionedSet(),
+
+		monitorReset: make(chan struct{}, 1),
+		windowEvent:  make(chan struct{}, 1),
+	}
+
+	storage, err := NewPositionStorage()
+	if err != nil {
+		logError("Settings and positions cannot be saved:", err)
+		recordProblem(problemError, "", "Settings and positions cannot be saved:", err)
+		wm.storageErr = err
 	}
+	wm.storage = storage
+
+	settings, err := wm.storage.LoadSettings()
+	if err != nil {
+		log(true, "Failed to load settings, using defaults:", err)
+	}
+	wm.settings = settings
+	wm.monitorInterval = time.Duration(settings.MonitorIntervalSeconds) * time.Second
+	logRedactTitles.Store(settings.RedactTitles)
+	setLogLevel(parseLogLevel(settings.LogLevel))
+	setTitleNormalizations(settings.TitleNormalizations)
+	setPositionTolerance(settings.PositionTolerancePixels)
+
+	stats, err := wm.storage.LoadStrategyStats()
+	if err != nil {
+		log(true, "Failed to load strategy statistics:", err)
+	}
+	wm.strategyStats = stats
 
 	wm.createMainWindow()
 	return wm
 }
 
-// setWindows replaces the current list of windows with a new one.
+// setWindows replaces the current list of windows with a new one and updates the listed windows.
 // It locks the mutex to ensure thread-safe access to the windows slice.
+// Call it on the Fyne main thread, it reads the filter of the window list.
 func (wm *WindowManager) setWindows(ws []WindowInfo) {
 	wm.windowsMutex.Lock()
-	defer wm.windowsMutex.Unlock()
 	wm.windows = make([]WindowInfo, len(ws))
 	copy(wm.windows, ws)
+	wm.windowsMutex.Unlock()
+	wm.updateListedWindows()
 }
 
 // getWindows returns a copy of the current list of windows.
@@ -59,6 +153,149 @@ func (wm *WindowManager) getWindows() []WindowInfo {
 	return copyWin
 }
 
+// listedWindows returns the windows shown in the window list. The list callbacks run once per row,
+// so the slice is computed by updateListedWindows when the windows, the filter or the order change.
+// The slice is replaced, never modified, so callers must not modify it either.
+func (wm *WindowManager) listedWindows() []WindowInfo {
+	wm.windowsMutex.RLock()
+	defer wm.windowsMutex.RUnlock()
+	return wm.listed
+}
+
+// updateListedWindows computes the windows shown in the window list: the cached windows whose title,
+// class name or executable contain the filter, ignoring case, in the chosen order.
+// Call it on the Fyne main thread, it reads the filter of the window list.
+func (wm *WindowManager) updateListedWindows() {
+	windows := wm.getWindows()
+	filter := strings.ToLower(strings.TrimSpace(wm.windowFilter))
+	if filter != "" {
+		filtered := windows[:0]
+		for _, window := range windows {
+			if strings.Contains(strings.ToLower(window.Title), filter) ||
+				strings.Contains(strings.ToLower(window.ClassName), filter) ||
+				strings.Contains(strings.ToLower(window.Executable), filter) {
+				filtered = append(filtered, window)
+			}
+		}
+		windows = filtered
+	}
+	settings := wm.getSettings()
+	sortWindows(windows, settings.WindowListSort, settings.WindowListDescending)
+	wm.windowsMutex.Lock()
+	wm.listed = windows
+	wm.windowsMutex.Unlock()
+}
+
+// Orders of the window list
+const (
+	sortZOrder     = ""           // Enumeration order, which is the Z-order from top to bottom
+	sortTitle      = "title"      // By title, ignoring case
+	sortExecutable = "executable" // By executable name, then title
+	sortPID        = "pid"        // By process ID, then title
+)
+
+// sortWindows sorts windows by the given order. The Z-order is kept as is, also when descending.
+func sortWindows(windows []WindowInfo, order string, descending bool) {
+	title := func(w WindowInfo) string { return strings.ToLower(w.Title) }
+	var compare func(a, b WindowInfo) int
+	switch order {
+	case sortTitle:
+		compare = func(a, b WindowInfo) int { return strings.Compare(title(a), title(b)) }
+	case sortExecutable:
+		compare = func(a, b WindowInfo) int {
+			if c := strings.Compare(strings.ToLower(filepath.Base(a.Executable)), strings.ToLower(filepath.Base(b.Executable))); c != 0 {
+				return c
+			}
+			return strings.Compare(title(a), title(b))
+		}
+	case sortPID:
+		compare = func(a, b WindowInfo) int {
+			if c := cmp.Compare(a.ProcessID, b.ProcessID); c != 0 {
+				return c
+			}
+			return strings.Compare(title(a), title(b))
+		}
+	default:
+		return
+	}
+	slices.SortStableFunc(windows, func(a, b WindowInfo) int {
+		if descending {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
+
+// windowSortBar creates the buttons that choose the order of the window list.
+// Tapping the active order toggles between ascending and descending, the choice is saved.
+func (wm *WindowManager) windowSortBar() fyne.CanvasObject {
+	orders := []struct{ label, order string }{
+		{"Z-order", sortZOrder},
+		{"Title", sortTitle},
+		{"Executable", sortExecutable},
+		{"PID", sortPID},
+	}
+	buttons := make([]*widget.Button, len(orders))
+	update := func() {
+		settings := wm.getSettings()
+		for i, o := range orders {
+			buttons[i].Importance = widget.LowImportance
+			buttons[i].SetIcon(nil)
+			if o.order == settings.WindowListSort {
+				buttons[i].Importance = widget.MediumImportance
+				if o.order != sortZOrder {
+					buttons[i].SetIcon(theme.MoveDownIcon())
+					if settings.WindowListDescending {
+						buttons[i].SetIcon(theme.MoveUpIcon())
+					}
+				}
+			}
+			buttons[i].Refresh()
+		}
+	}
+	for i, o := range orders {
+		buttons[i] = widget.NewButton(o.label, safeCallback(func() {
+			err := wm.updateSettings(func(s *Settings) {
+				s.WindowListDescending = s.WindowListSort == o.order && !s.WindowListDescending
+				s.WindowListSort = o.order
+			})
+			if err != nil {
+				log(true, "Failed to save the window list order:", err)
+			}
+			update()
+			wm.updateListedWindows()
+			wm.windowList.Refresh()
+		}))
+	}
+	update()
+	objects := []fyne.CanvasObject{widget.NewLabel("Sort:")}
+	for _, button := range buttons {
+		objects = append(objects, button)
+	}
+	return container.NewHBox(objects...)
+}
+
+// filterEntry is an entry that is cleared by pressing Escape
+type filterEntry struct {
+	widget.Entry
+}
+
+// newFilterEntry creates a filter entry.
+func newFilterEntry() *filterEntry {
+	entry := &filterEntry{}
+	entry.ExtendBaseWidget(entry)
+	return entry
+}
+
+// TypedKey clears the entry on Escape and handles all other keys like an entry.
+func (e *filterEntry) TypedKey(key *fyne.KeyEvent) {
+	if key.Name == fyne.KeyEscape {
+		e.SetText("")
+		return
+	}
+	e.Entry.TypedKey(key)
+}
+
 // createMainWindow sets up the main application window
 // It includes a close intercept to hide the window instead of closing it.
 func (wm *WindowManager) createMainWindow() {
@@ -72,6 +309,7 @@ func (wm *WindowManager) createMainWindow() {
 		wm.mainWindow.Hide()
 	})
 	wm.setupMainWindowContent()
+	wm.setupListKeyboard()
 }
 
 // setupMainWindowContent sets up the content of the main window
@@ -86,6 +324,10 @@ func (wm *WindowManager) setupMainWindowContent() {
 	refreshBtn := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), safeCallback(func() {
 		wm.refreshWindowList()
 	}))
+	// Save selected button
+	saveSelectedBtn := widget.NewButtonWithIcon("Save Selected", theme.DocumentSaveIcon(), safeCallback(func() {
+		wm.saveSelectedWindows()
+	}))
 	// Exit button
 	exitBtn := widget.NewButtonWithIcon("Exit", theme.LogoutIcon(), safeCallback(func() {
 		wm.app.Quit()
@@ -94,59 +336,91 @@ func (wm *WindowManager) setupMainWindowContent() {
 	const listItemHeight = 40 // Vertical pixel per scroll item (approx)
 	wm.windowList = widget.NewList(
 		func() int {
-			return len(wm.getWindows())
+			return len(wm.listedWindows())
 		},
 		func() fyne.CanvasObject {
 			return container.NewHBox(
+				widget.NewCheck("", nil),                                    // Selection-Check
 				widget.NewButtonWithIcon("", theme.InfoIcon(), nil),         // Info-Button
 				widget.NewButtonWithIcon("", theme.SearchIcon(), nil),       // Magnify-Button
 				widget.NewButtonWithIcon("", theme.DocumentSaveIcon(), nil), // Save-Button
+				widget.NewButtonWithIcon("", theme.MoveUpIcon(), nil),       // Topmost-Button
+				widget.NewButtonWithIcon("", theme.ZoomFitIcon(), nil),      // Center-Button
+				widget.NewButtonWithIcon("", theme.VisibilityIcon(), nil),   // Opacity-Button
+				widget.NewButtonWithIcon("", theme.MoreVerticalIcon(), nil), // More-Button
 				widget.NewLabel("Window Title"),
 			)
 		},
 		func(id widget.ListItemID, obj fyne.CanvasObject) {
-			if id >= len(wm.windows) {
-				return
-			}
-			windows := wm.getWindows()
+			windows := wm.listedWindows()
 			if id >= len(windows) {
 				return
 			}
 			window := windows[id]
 			hbox := obj.(*fyne.Container)
-			infoBtn := hbox.Objects[0].(*widget.Button)
-			magnifyIcon := hbox.Objects[1].(*widget.Button)
-			saveBtn := hbox.Objects[2].(*widget.Button)
-			label := hbox.Objects[3].(*widget.Label)
+			selectCheck := hbox.Objects[0].(*widget.Check)
+			infoBtn := hbox.Objects[1].(*widget.Button)
+			magnifyIcon := hbox.Objects[2].(*widget.Button)
+			saveBtn := hbox.Objects[3].(*widget.Button)
+			topmostBtn := hbox.Objects[4].(*widget.Button)
+			centerBtn := hbox.Objects[5].(*widget.Button)
+			opacityBtn := hbox.Objects[6].(*widget.Button)
+			moreBtn := hbox.Objects[7].(*widget.Button)
+			label := hbox.Objects[8].(*widget.Label)
 
 			// Clear existing callbacks to prevent memory leaks
+			selectCheck.OnChanged = nil
 			infoBtn.OnTapped = nil
 			saveBtn.OnTapped = nil
+			topmostBtn.OnTapped = nil
+			centerBtn.OnTapped = nil
+			opacityBtn.OnTapped = nil
+			moreBtn.OnTapped = nil
 
 			// Set new callbacks
+			selectCheck.SetChecked(wm.selected[window.Handle])
+			selectCheck.OnChanged = func(checked bool) {
+				if checked {
+					wm.selected[window.Handle] = true
+				} else {
+					delete(wm.selected, window.Handle)
+				}
+			}
 			infoBtn.OnTapped = safeCallback(func() {
 				x := int(window.WindowRect.Left)
 				y := int(window.WindowRect.Top)
 				width := int(window.WindowRect.Right - window.WindowRect.Left)
 				height := int(window.WindowRect.Bottom - window.WindowRect.Top)
+				dpi := getDpiForWindow(window.Handle)
+				ruleText := "Saved rule: none"
+				if identifier, pos, ok := findSavedPosition(window, wm.storage.GetAllPositions()); ok {
+					lastMatched := "never"
+					if last := pos.lastMatched(); !last.IsZero() {
+						lastMatched = last.Format("2006-01-02 15:04")
+					}
+					ruleText = fmt.Sprintf("Saved rule: %s\nMatches   : %d\nLast match: %s", displayName(identifier, pos), pos.MatchCount, lastMatched)
+				}
 				infoText := fmt.Sprintf(
 					"Window    :\n'%s'\n\n"+
 						"Position  : %d,%d\n"+
 						"Size      : %dx%d\n"+
+						"DPI       : %d (%d%%)\n"+
 						"Process ID: %d\n"+
 						"Class Name: %s\n"+
 						"HWND      : 0x%08X\n"+
 						"Style     : 0x%08X\n"+
 						"ExStyle   : 0x%08X\n"+
-						"Executable:\n'%s'",
+						"Executable:\n'%s'\n\n%s",
 					window.Title,
 					x, y, width, height,
+					dpi, dpi*100/USER_DEFAULT_SCREEN_DPI,
 					window.ProcessID,
 					window.ClassName,
 					window.Handle,
 					window.Style,
 					window.ExStyle,
 					window.Executable,
+					ruleText,
 				)
 				entry := widget.NewMultiLineEntry()
 				entry.SetText(infoText)
@@ -154,33 +428,65 @@ func (wm *WindowManager) setupMainWindowContent() {
 				entry.Wrapping = fyne.TextWrapBreak
 				scroll := container.NewScroll(entry)
 				scroll.SetMinSize(fyne.NewSize(400, 300))
-				dialog.ShowCustom("Details for this window", "Close", scroll, wm.mainWindow)
+				tagBtn := widget.NewButtonWithIcon("Tag title…", theme.ContentAddIcon(), safeCallback(func() {
+					wm.showTagTitleDialog(window)
+				}))
+				content := container.NewBorder(nil, container.NewVBox(wm.strategyTestButtons(window), tagBtn), nil, nil, scroll)
+				dialog.ShowCustom("Details for this window", "Close", content, wm.mainWindow)
 			})
 			magnifyIcon.OnTapped = safeCallback(func() {
-				// Validate window handle before attempting to focus
-				if !isValidWindow(window.Handle) {
-					log(true, "Cannot focus window - handle is invalid:", window.Handle)
-					dialog.ShowError(fmt.Errorf("window no longer exists: %s", window.Title), wm.mainWindow)
-					return
-				}
-				err := focusWindow(window.Handle)
-				if err != nil {
-					log(true, "Failed to focus window:", err)
-					dialog.ShowError(fmt.Errorf("failed to focus window: %v", err), wm.mainWindow)
-				}
+				wm.focusListedWindow(window)
 			})
 			saveBtn.OnTapped = safeCallback(func() {
-				// Validate window handle before attempting to save position
-				if !isValidWindow(window.Handle) {
-					log(true, "Cannot save position - window handle is invalid:", window.Handle)
-					dialog.ShowError(fmt.Errorf("window no longer exists: %s", window.Title), wm.mainWindow)
+				wm.saveListedWindow(window)
+			})
+			if window.ExStyle&WS_EX_TOPMOST != 0 {
+				topmostBtn.Importance = widget.HighImportance
+			} else {
+				topmostBtn.Importance = widget.MediumImportance
+			}
+			topmostBtn.Refresh()
+			topmostBtn.OnTapped = safeCallback(func() {
+				if err := wm.toggleTopmost(window); err != nil {
+					log(true, "Failed to toggle topmost:", err)
+					dialog.ShowError(err, wm.mainWindow)
 					return
 				}
-				wm.saveWindowPosition(window)
+				wm.refreshWindowList() // Show the new state
+			})
+			centerBtn.OnTapped = safeCallback(func() {
+				// Strategies may sleep, so do not block the UI
+				go func() {
+					defer panicHandler()
+					if err := centerWindowOnMonitor(window.Handle); err != nil {
+						logWarn("Failed to center window:", err)
+						fyne.Do(func() {
+							dialog.ShowError(err, wm.mainWindow)
+						})
+					}
+				}()
+			})
+			opacityBtn.OnTapped = safeCallback(func() {
+				wm.showOpacitySlider(window, opacityBtn)
+			})
+			moreBtn.OnTapped = safeCallback(func() {
+				canvas := fyne.CurrentApp().Driver().CanvasForObject(moreBtn)
+				widget.ShowPopUpMenuAtRelativePosition(wm.windowRowMenu(window), canvas, fyne.NewPos(0, moreBtn.Size().Height), moreBtn)
 			})
 			label.SetText(fmt.Sprintf("%s [%s]", window.Title, window.ClassName))
 		},
 	)
+	wm.bindListCursor(wm.windowList)
+	// Filter of the window list, it survives rebuilding the content
+	filter := newFilterEntry()
+	filter.SetPlaceHolder("Filter by title, class or executable (Esc clears)")
+	filter.SetText(wm.windowFilter)
+	filter.OnChanged = func(text string) {
+		wm.windowFilter = text
+		wm.updateListedWindows()
+		wm.windowList.UnselectAll()
+		wm.windowList.Refresh()
+	}
 	scrollWindowList := container.NewScroll(wm.windowList)
 	scrollWindowList.SetMinSize(fyne.NewSize(0, 5*listItemHeight))
 	// Saved positions section
@@ -195,54 +501,90 @@ func (wm *WindowManager) setupMainWindowContent() {
 			dialog.ShowError(err, wm.mainWindow)
 		}
 	}))
+	groupsBtn := widget.NewButtonWithIcon("Groups", theme.ListIcon(), safeCallback(func() {
+		wm.showGroupsDialog()
+	}))
 	// Create a list for saved positions
-	savedList := wm.createSavedPositionsList()
-	scrollSavedList := container.NewScroll(savedList)
+	wm.savedList = wm.createSavedPositionsList()
+	scrollSavedList := container.NewScroll(wm.savedList)
 	scrollSavedList.SetMinSize(fyne.NewSize(0, 5*listItemHeight))
 	// Settings section
 	labSettings := widget.NewLabel("Settings")
 	labSettings.TextStyle = fyne.TextStyle{Bold: true}
-	startupCheck := widget.NewCheck("Start with Windows", func(checked bool) {
-		if checked {
-			if err := EnableStartup(); err != nil {
-				log(true, "Failed to enable startup:", err)
+	startupCheck := widget.NewCheck("Start with Windows", nil)
+	// Check current startup status, before the callback is set so it does not register again
+	startupCheck.SetChecked(startsWithWindows(wm.getSettings().AlwaysRunElevated))
+	startupCheck.OnChanged = func(checked bool) {
+		// Registering the elevated task may wait for a UAC prompt
+		go func() {
+			defer panicHandler()
+			if err := setStartup(checked, wm.getSettings().AlwaysRunElevated); err != nil {
+				log(true, "Failed to change startup:", err)
+				fyne.Do(func() {
+					dialog.ShowError(err, wm.mainWindow)
+				})
 			}
-		} else {
-			if err := DisableStartup(); err != nil {
-				log(true, "Failed to disable startup:", err)
-			}
-		}
-	})
-	// Check current startup status
-	startupCheck.SetChecked(IsStartupEnabled())
+		}()
+	}
+	settingsBtn := widget.NewButtonWithIcon("Settings", theme.SettingsIcon(), safeCallback(func() {
+		wm.showSettingsWindow()
+	}))
+	var workspaceBtn *widget.Button
+	workspaceBtn = widget.NewButtonWithIcon("Workspace", theme.StorageIcon(), safeCallback(func() {
+		canvas := fyne.CurrentApp().Driver().CanvasForObject(workspaceBtn)
+		widget.ShowPopUpMenuAtRelativePosition(wm.workspaceMenu(), canvas, fyne.NewPos(0, workspaceBtn.Size().Height), workspaceBtn)
+	}))
 	// Layout
 	content := container.NewVBox(
-		container.New(layout.NewGridLayout(4), labTitle, separator, refreshBtn, exitBtn),
+		container.New(layout.NewGridLayout(4), labTitle, saveSelectedBtn, refreshBtn, exitBtn),
 		separator,
 		//container.NewHBox(labTitle, separator, refreshBtn, separator, exitBtn),
 		separator,
+		container.NewBorder(nil, nil, nil, wm.windowSortBar(), filter),
 		scrollWindowList,
 		widget.NewSeparator(),
-		container.New(layout.NewGridLayout(4), savedLabel, separator, separator, configBtn),
+		container.New(layout.NewGridLayout(4), savedLabel, separator, groupsBtn, configBtn),
 		//container.NewHBox(savedLabel, separator, configBtn),
-		separator,
+		wm.profileBar(),
 		scrollSavedList,
 		separator,
-		labSettings,
-		startupCheck,
+		container.New(layout.NewGridLayout(4), labSettings, separator, workspaceBtn, settingsBtn),
+		wm.startupRow(startupCheck),
 	)
 	wm.mainWindow.SetContent(content)
 	wm.refreshWindowList()
+	wm.updateSystemTray() // The profiles may have changed
+}
+
+// strategyTestButtons creates a button per move strategy that tests the strategy against a window.
+// This helps to find out which technique works for a stubborn application.
+func (wm *WindowManager) strategyTestButtons(window WindowInfo) fyne.CanvasObject {
+	buttons := container.NewGridWithColumns(3)
+	for _, strategy := range moveStrategies {
+		buttons.Add(widget.NewButton(strategy.Name, safeCallback(func() {
+			if !isValidWindow(window.Handle) {
+				dialog.ShowError(fmt.Errorf("window no longer exists: %s", window.Title), wm.mainWindow)
+				return
+			}
+			// Strategies may sleep, so do not block the UI
+			go func() {
+				defer panicHandler()
+				result := testMoveStrategy(window.Handle, strategy)
+				fyne.Do(func() {
+					dialog.ShowInformation("Strategy test", result, wm.mainWindow)
+				})
+			}()
+		})))
+	}
+	label := widget.NewLabel("Test move strategies (moves the window by 20 pixels and back):")
+	return container.NewVBox(label, buttons)
 }
 
 // createSavedPositionsList creates a list of saved window positions
-// It allows users to apply or delete saved positions.
+// It allows users to apply, edit or delete saved positions.
 func (wm *WindowManager) createSavedPositionsList() *widget.List {
 	positions := wm.storage.GetAllPositions()
-	positionKeys := make([]string, 0, len(positions))
-	for key := range positions {
-		positionKeys = append(positionKeys, key)
-	}
+	positionKeys := sortedByDisplayName(positions)
 
 	return widget.NewList(
 		func() int {
@@ -250,7 +592,11 @@ func (wm *WindowManager) createSavedPositionsList() *widget.List {
 		},
 		func() fyne.CanvasObject {
 			return container.NewHBox(
-				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+				widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),         // Delete-Button
+				widget.NewButtonWithIcon("", theme.MediaPlayIcon(), nil),      // Apply-Button
+				widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil), // Edit-Button
+				widget.NewButtonWithIcon("", theme.MoreVerticalIcon(), nil),   // More-Button
+				widget.NewIcon(nil), // Open window indicator
 				widget.NewLabel("Position"),
 			)
 		},
@@ -262,17 +608,60 @@ func (wm *WindowManager) createSavedPositionsList() *widget.List {
 			key := positionKeys[id]
 			hbox := obj.(*fyne.Container)
 			deleteBtn := hbox.Objects[0].(*widget.Button)
-			label := hbox.Objects[1].(*widget.Label)
+			applyBtn := hbox.Objects[1].(*widget.Button)
+			editBtn := hbox.Objects[2].(*widget.Button)
+			moreBtn := hbox.Objects[3].(*widget.Button)
+			indicator := hbox.Objects[4].(*widget.Icon)
+			label := hbox.Objects[5].(*widget.Label)
 
-			label.SetText(key)
+			// Green if a window matches the saved position, grey for leftovers of closed applications
+			if wm.liveIdentifiers[key] {
+				indicator.SetResource(theme.NewSuccessThemedResource(theme.MediaRecordIcon()))
+			} else {
+				indicator.SetResource(theme.NewDisabledResource(theme.MediaRecordIcon()))
+			}
+			label.SetText(displayName(key, positions[key]))
 			deleteBtn.OnTapped = safeCallback(func() {
 				wm.storage.DeletePosition(key)
 				wm.setupMainWindowContent() // Refresh the UI
 			})
+			applyBtn.OnTapped = safeCallback(func() {
+				go func() {
+					defer panicHandler()
+					if err := wm.applySavedPosition(key); err != nil {
+						fyne.Do(func() {
+							dialog.ShowError(err, wm.mainWindow)
+						})
+					}
+				}()
+			})
+			editBtn.OnTapped = safeCallback(func() {
+				wm.showRuleEditor(key)
+			})
+			moreBtn.OnTapped = safeCallback(func() {
+				menu := wm.savedPositionMenu(key)
+				canvas := fyne.CurrentApp().Driver().CanvasForObject(moreBtn)
+				widget.ShowPopUpMenuAtRelativePosition(menu, canvas, fyne.NewPos(0, moreBtn.Size().Height), moreBtn)
+			})
 		},
 	)
 }
 
+// savedPositionMenu creates the menu with additional actions for a saved position.
+func (wm *WindowManager) savedPositionMenu(identifier string) *fyne.Menu {
+	return fyne.NewMenu("",
+		fyne.NewMenuItem("Rename…", safeCallback(func() {
+			wm.showRenameRuleDialog(identifier)
+		})),
+		fyne.NewMenuItem("Add to group…", safeCallback(func() {
+			wm.showAddToGroupDialog(identifier)
+		})),
+		fyne.NewMenuItem("Copy to profile…", safeCallback(func() {
+			wm.showCopyRuleToProfileDialog(identifier)
+		})),
+	)
+}
+
 // refreshWindowList fetches the current list of windows and updates the window list widget
 func (wm *WindowManager) refreshWindowList() {
 	debug := true
@@ -303,16 +692,32 @@ func (wm *WindowManager) refreshWindowList() {
 		return
 	}
 
-	// Filter out system windows and our own window
+	// Filter out system windows, excluded apps, windows Alt+Tab leaves out and our own window
 	var filteredWindows []WindowInfo
-	for _, window := range windows {
-		if window.Title != "" && window.Title != strAppTitle {
+	settings := wm.getSettings()
+	matchable := withoutExcluded(windows, settings)
+	for _, window := range matchable {
+		if window.Title != "" && window.Title != strAppTitle && listedLikeAltTab(window, settings) {
 			filteredWindows = append(filteredWindows, window)
 		}
 	}
 
+	// Mark the saved positions that match an open window, the same way repositioning matches them
+	positions := wm.storage.GetAllPositions()
+	wm.liveIdentifiers = make(map[string]bool)
+	for _, window := range matchable {
+		if identifier, _, ok := findSavedPosition(window, positions); ok {
+			wm.liveIdentifiers[identifier] = true
+		}
+	}
+	wm.recordMatches(positions, wm.liveIdentifiers, nil)
+	if wm.savedList != nil {
+		wm.savedList.Refresh()
+	}
+
 	wm.setWindows(filteredWindows)
 	wm.windowList.Refresh()
+	wm.restoreListCursor()
 
 	var msFinal runtime.MemStats
 	runtime.ReadMemStats(&msFinal)
@@ -331,128 +736,245 @@ func (wm *WindowManager) saveWindowPosition(window WindowInfo) {
 		return
 	}
 
-	identifier := fmt.Sprintf("%s|%s|%s|0x%08X|0x%08X", window.Title, window.ClassName, window.Executable, window.Style, window.ExStyle)
-	err = wm.storage.SavePosition(identifier, *pos)
+	captureWindowState(window.Handle, pos)
+
+	identifier, err := wm.storeWindowPosition(window, *pos)
 	if err != nil {
-		log(true, "Failed to save position:", err)
+		wm.showSaveError(err)
 		return
 	}
 
-	log(true, "Saved position for:", identifier)
+	log(true, "Saved position for:", redactIdentifier(identifier))
 	wm.setupMainWindowContent() // Refresh the UI
 }
 
-// repositionSavedWindows repositions all saved windows based on their stored positions
-// This is called on startup and periodically by the monitoring service.
-func (wm *WindowManager) repositionSavedWindows() {
-	debug := false
-	log(debug, "Repositioning saved windows.")
-
-	// Ensure we handle panics gracefully
-	defer panicHandler()
-
-	// Thread-safe operation to avoid concurrent modifications
-	wm.operationMutex.Lock()
-	defer wm.operationMutex.Unlock()
+// showSaveError reports a failed save in a dialog, also when the position was saved with a hotkey
+// while the main window is hidden, so the user does not lose a layout unnoticed.
+// It must be called on the Fyne main thread.
+func (wm *WindowManager) showSaveError(err error) {
+	logError("Failed to save position:", err)
+	recordProblem(problemError, "", "Failed to save position:", err)
+	wm.mainWindow.Show()
+	dialog.ShowError(fmt.Errorf("the position was not saved: %v", err), wm.mainWindow)
+}
 
-	// Get all saved positions and enumerate current windows
-	positions := wm.storage.GetAllPositions()
-	windows, err := EnumerateWindows()
+// saveSelectedWindows saves the current positions of the windows checked in the window list
+// into the active profile with a single write, and clears the selection.
+func (wm *WindowManager) saveSelectedWindows() {
+	debug := true
+	var windows []WindowInfo
+	for _, window := range wm.getWindows() {
+		if wm.selected[window.Handle] {
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) == 0 {
+		dialog.ShowInformation("Save selected", "Check the windows to save in the window list first.", wm.mainWindow)
+		return
+	}
+	saved, err := wm.storeSelectedPositions(windows)
 	if err != nil {
-		log(true, "-> Failed to enumerate windows:", err)
+		wm.showSaveError(err)
 		return
 	}
+	log(debug, "Saved", saved, "positions of", len(windows), "selected windows.")
+	wm.selected = make(map[syscall.Handle]bool)
+	wm.setupMainWindowContent() // Refresh the UI
+	if saved < len(windows) {
+		dialog.ShowInformation("Save selected",
+			fmt.Sprintf("Saved %d of %d windows. Closed windows are skipped, "+
+				"and windows with the same identifier share one position.", saved, len(windows)), wm.mainWindow)
+	}
+}
 
-	log(debug, "-> Found", len(windows), "windows to check for saved positions.")
-
-	errorCount := 0
-	maxErrors := 10 // Stop processing if too many errors occur
+// storeSelectedPositions captures the positions of several windows and saves them with a
+// single write, holding the operationMutex. It returns the number of saved positions.
+func (wm *WindowManager) storeSelectedPositions(windows []WindowInfo) (int, error) {
+	wm.operationMutex.Lock()
+	defer wm.operationMutex.Unlock()
 
+	settings := wm.getSettings()
+	positions := make(map[string]WindowPosition, len(windows))
 	for _, window := range windows {
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					errorCount++
-					log(true, "Panic in repositionSavedWindows for window", window.Handle, ":", r)
-					if errorCount >= maxErrors {
-						log(true, "Too many errors in repositionSavedWindows, stopping processing for this cycle")
-						return
-					}
-				}
-			}()
-
-			// Skip processing if too many errors have occurred
-			if errorCount >= maxErrors {
-				return
-			}
-
-			identifier := fmt.Sprintf("%s|%s|%s|0x%08X|0x%08X",
-				window.Title, window.ClassName, window.Executable, window.Style, window.ExStyle)
+		if !isValidWindow(window.Handle) {
+			log(true, "Skipping closed window:", redactTitle(window.Title))
+			continue
+		}
+		pos, err := getWindowPosition(window.Handle)
+		if err != nil {
+			log(true, "Failed to get window position:", err)
+			continue
+		}
+		captureWindowState(window.Handle, pos)
+		identifier, captured := capturedPosition(window, *pos, settings)
+		positions[identifier] = captured
+	}
+	if len(positions) == 0 {
+		return 0, nil
+	}
+	return len(positions), wm.storage.SavePositions(positions)
+}
 
-			if pos, exists := positions[identifier]; exists {
-				// Additional validation before attempting to move
-				if !isValidWindow(window.Handle) {
-					log(debug, "Skipping invalid window handle:", identifier)
-					return
-				}
+// storeWindowPosition saves a position for a window under the identifier built from the
+// current identifier composition, and returns the identifier.
+func (wm *WindowManager) storeWindowPosition(window WindowInfo, pos WindowPosition) (string, error) {
+	identifier, pos := capturedPosition(window, pos, wm.getSettings())
+	return identifier, wm.storage.SavePosition(identifier, pos)
+}
 
-				err := MoveWindowAccurate(window.Handle, pos.X, pos.Y, pos.Width, pos.Height)
-				if err != nil {
-					errorCount++
-					log(debug, "Failed to auto-position window:", identifier, err) // Changed to debug to reduce log spam
-				} else {
-					log(debug, "Auto-positioned:", identifier)
-				}
-			}
-		}()
+// capturedPosition completes a position with the attributes captured from its window when it
+// is saved, and returns it with the identifier built from the identifier composition.
+func capturedPosition(window WindowInfo, pos WindowPosition, settings Settings) (string, WindowPosition) {
+	if settings.SaveVisibleFrame {
+		pos = pos.visibleFrame(window.Handle)
 	}
-
-	if errorCount > 0 {
-		log(true, "repositionSavedWindows completed with", errorCount, "errors")
+	pos.ExStyleFlags = window.ExStyle & clickThroughExStyles
+	pos.VirtualDesktopID = windowDesktopID(window.Handle)
+	pos.Order = zOrderIndex(window.Handle) + 1
+	pos.LastMatchedAt = time.Now()
+	if normalized := normalizeTitle(window.Title, window.Executable); normalized != window.Title {
+		pos.RawTitle = window.Title
 	}
+	return buildIdentifier(window, settings.IdentifierFields), pos
 }
 
 // startMonitoringService runs a background service that periodically checks for window positions
 // and repositions them if necessary. This is useful for keeping windows in their saved positions.
+// While the window event hooks are installed, the cycles run on window events instead.
 func (wm *WindowManager) startMonitoringService(ctx context.Context) {
 	debug := true
 	log(debug, "Starting background window monitoring service.")
 	defer panicHandler()
-	// Increase interval to reduce likelihood of hitting destroyed windows
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+
+	// A nil channel never delivers, so a disabled service only waits for a new interval
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	restart := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		mode := wm.getSettings().RepositionMode
+		if !usesMonitoring(mode) {
+			log(debug, "Monitoring is off in the", repositionModeLabel(mode), "mode.")
+			return
+		}
+		interval := wm.getMonitorInterval()
+		if interval <= 0 {
+			log(debug, "Periodic repositioning is disabled.")
+			return
+		}
+		if winEvents.Running() {
+			// The hooks report window changes, a slow ticker still applies time-driven changes
+			interval = max(interval, winEventFallbackInterval)
+			log(debug, "Checking windows on window events and every", interval)
+		} else {
+			log(debug, "Checking windows every", interval)
+		}
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	restart()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	cycle := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log(true, "Panic in monitoring service:", r)
+				// Continue running the service despite the panic
+			}
+		}()
+
+		// Add additional safeguards
+		if wm == nil || wm.storage == nil {
+			log(true, "WindowManager or storage is nil, skipping monitoring cycle")
+			return
+		}
+
+		// Positioning is snoozed by the user
+		if wm.isSnoozed() {
+			log(false, "Positioning is snoozed, skipping monitoring cycle")
+			return
+		}
+
+		// Do not disturb games and videos
+		if wm.pausedForFullscreen() {
+			return
+		}
+
+		// In the off and startup modes windows are only positioned at startup and by the user
+		if !usesMonitoring(wm.getSettings().RepositionMode) {
+			return
+		}
+
+		// Only report passes that changed something, the monitoring runs every few seconds
+		if result := wm.repositionWindows(true); result.Moved > 0 || result.Failed > 0 {
+			logInfo("Monitoring cycle:", result.Summary())
+		}
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			log(debug, "Monitoring service stopped")
 			return
-		case <-ticker.C:
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log(true, "Panic in monitoring service:", r)
-						// Continue running the service despite the panic
-					}
-				}()
+		case <-wm.monitorReset:
+			restart()
+		case <-tick:
+			cycle()
+		case <-wm.windowEvent:
+			cycle()
+		}
+	}
+}
 
-				// Add additional safeguards
-				if wm == nil || wm.storage == nil {
-					log(true, "WindowManager or storage is nil, skipping monitoring cycle")
-					return
-				}
+// getMonitorInterval returns the interval of the monitoring service, 0 if it is disabled.
+func (wm *WindowManager) getMonitorInterval() time.Duration {
+	wm.monitorIntervalMutex.Lock()
+	defer wm.monitorIntervalMutex.Unlock()
+	return wm.monitorInterval
+}
 
-				wm.repositionSavedWindows()
-			}()
-		}
+// setMonitorInterval changes the interval of the monitoring service, which restarts its ticker.
+// An interval of 0 disables the periodic repositioning.
+func (wm *WindowManager) setMonitorInterval(interval time.Duration) {
+	wm.monitorIntervalMutex.Lock()
+	changed := interval != wm.monitorInterval
+	wm.monitorInterval = interval
+	wm.monitorIntervalMutex.Unlock()
+	if changed {
+		wm.resetMonitoring()
+	}
+}
+
+// resetMonitoring makes the monitoring service restart its ticker,
+// after the interval changed or the window event hooks were installed or removed.
+func (wm *WindowManager) resetMonitoring() {
+	select {
+	case wm.monitorReset <- struct{}{}:
+	default: // A restart is already pending and reads the current state
 	}
 }
 
 // setupSystemTray sets up the system tray menu for the application
 func (wm *WindowManager) setupSystemTray(desk desktop.App) {
 	log(true, "Setting up system tray menu for", strProductName+`.`)
-	menu := fyne.NewMenu(strProductName,
+	wm.desk = desk
+	wm.updateSystemTray()
+}
+
+// updateSystemTray (re)builds the system tray menu and icon from the current state.
+// It must be called on the Fyne main thread.
+func (wm *WindowManager) updateSystemTray() {
+	if wm.desk == nil {
+		return
+	}
 
+	items := []*fyne.MenuItem{
 		fyne.NewMenuItem(strProductName, func() {
 			log(true, "System tray menu title clicked")
 			//`)
@@ -463,6 +985,115 @@ func (wm *WindowManager) setupSystemTray(desk desktop.App) {
 			wm.mainWindow.RequestFocus()
 			wm.mainWindow.CenterOnScreen()
 		})),
-	)
-	desk.SetSystemTrayMenu(menu)
+		fyne.NewMenuItem("Rescue off-screen windows", safeCallback(func() {
+			wm.runRescueOffscreenWindows()
+		})),
+		fyne.NewMenuItem("Undo last arrangement", safeCallback(func() {
+			wm.runUndoLastArrangement()
+		})),
+		fyne.NewMenuItem("Show Log", safeCallback(func() {
+			wm.showLogWindow()
+		})),
+		wm.profilesTrayItem(),
+		wm.tileTrayItem(),
+		wm.repositionModeTrayItem(),
+		fyne.NewMenuItemSeparator(),
+	}
+
+	// Snooze menu items
+	until, snoozed := wm.snoozeState()
+	tooltip := strProductName
+	if snoozed {
+		remaining := time.Until(until).Round(time.Minute)
+		status := fyne.NewMenuItem(fmt.Sprintf("Snoozed until %s (%v left)", until.Format("15:04"), remaining), nil)
+		status.Disabled = true
+		tooltip += " - " + status.Label
+		items = append(items, status)
+		items = append(items, fyne.NewMenuItem("Resume Positioning", safeCallback(func() {
+			wm.resumePositioning()
+		})))
+	}
+	for _, minutes := range []int{15, 30, 60} {
+		duration := time.Duration(minutes) * time.Minute
+		items = append(items, fyne.NewMenuItem(fmt.Sprintf("Snooze %d min", minutes), safeCallback(func() {
+			wm.snoozePositioning(duration)
+		})))
+	}
+
+	wm.desk.SetSystemTrayMenu(fyne.NewMenu(strProductName, items...))
+	if snoozed {
+		wm.desk.SetSystemTrayIcon(theme.MediaPauseIcon())
+	} else if icon := wm.app.Icon(); icon != nil {
+		wm.desk.SetSystemTrayIcon(icon)
+	}
+	// Fyne has no tray tooltip, so it is set on the systray library its driver uses.
+	// The snooze timer rebuilds the tray every minute, which keeps the remaining time current.
+	systray.SetTooltip(tooltip)
+}
+
+// snoozeState returns the time positioning resumes and whether it is currently snoozed.
+func (wm *WindowManager) snoozeState() (time.Time, bool) {
+	wm.snoozeMutex.Lock()
+	defer wm.snoozeMutex.Unlock()
+	return wm.snoozeUntil, time.Now().Before(wm.snoozeUntil)
+}
+
+// isSnoozed reports whether automatic positioning is currently snoozed.
+func (wm *WindowManager) isSnoozed() bool {
+	_, snoozed := wm.snoozeState()
+	return snoozed
+}
+
+// snoozePositioning suspends automatic positioning for the given duration.
+// A timer tied to the application context resumes positioning automatically.
+// The tray menu is refreshed every minute to show the remaining snooze time.
+func (wm *WindowManager) snoozePositioning(duration time.Duration) {
+	debug := true
+	wm.snoozeMutex.Lock()
+	if wm.snoozeCancel != nil {
+		wm.snoozeCancel() // Replace a running snooze
+	}
+	ctx, cancel := context.WithCancel(wm.ctx)
+	wm.snoozeCancel = cancel
+	wm.snoozeUntil = time.Now().Add(duration)
+	until := wm.snoozeUntil
+	wm.snoozeMutex.Unlock()
+
+	log(debug, "Positioning snoozed until", until.Format("15:04:05"))
+	wm.updateSystemTray()
+
+	go func() {
+		defer panicHandler()
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fyne.Do(wm.updateSystemTray)
+			case <-timer.C:
+				log(debug, "Snooze expired, resuming positioning.")
+				fyne.Do(wm.resumePositioning)
+				return
+			}
+		}
+	}()
+}
+
+// resumePositioning ends a running snooze and re-enables automatic positioning.
+func (wm *WindowManager) resumePositioning() {
+	wm.snoozeMutex.Lock()
+	if wm.snoozeCancel != nil {
+		wm.snoozeCancel()
+		wm.snoozeCancel = nil
+	}
+	wm.snoozeUntil = time.Time{}
+	wm.snoozeMutex.Unlock()
+
+	log(true, "Positioning resumed.")
+	wm.updateSystemTray()
 }
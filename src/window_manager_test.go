@@ -0,0 +1,45 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSortWindows checks the orders of the window list.
+func TestSortWindows(t *testing.T) {
+	windows := []WindowInfo{
+		{Handle: 1, Title: "beta", Executable: `C:\Apps\zed.exe`, ProcessID: 30},
+		{Handle: 2, Title: "Alpha", Executable: `C:\Apps\Editor.exe`, ProcessID: 20},
+		{Handle: 3, Title: "gamma", Executable: `C:\Other\editor.exe`, ProcessID: 20},
+		{Handle: 4, Title: "Delta", Executable: "app.exe", ProcessID: 10},
+	}
+	tests := []struct {
+		name       string
+		order      string
+		descending bool
+		want       []uintptr
+	}{
+		{"z-order", sortZOrder, false, []uintptr{1, 2, 3, 4}},
+		{"z-order ignores descending", sortZOrder, true, []uintptr{1, 2, 3, 4}},
+		{"title ignoring case", sortTitle, false, []uintptr{2, 1, 4, 3}},
+		{"title descending", sortTitle, true, []uintptr{3, 4, 1, 2}},
+		{"executable name, then title", sortExecutable, false, []uintptr{4, 2, 3, 1}},
+		{"executable descending", sortExecutable, true, []uintptr{1, 3, 2, 4}},
+		{"process ID, then title", sortPID, false, []uintptr{4, 2, 3, 1}},
+		{"process ID descending", sortPID, true, []uintptr{1, 3, 2, 4}},
+		{"unknown order keeps the z-order", "size", false, []uintptr{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sorted := slices.Clone(windows)
+			sortWindows(sorted, test.order, test.descending)
+			var got []uintptr
+			for _, window := range sorted {
+				got = append(got, uintptr(window.Handle))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("sortWindows(%q, %v) = %v, want %v", test.order, test.descending, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+/*
+	Window state:
+	- Saving a position records whether the window was normal, maximized or minimized.
+	  For maximized and minimized windows the normal (restore) rectangle is saved, so the window
+	  gets its normal size back when the user restores it.
+	- A maximized window is moved to its normal rectangle first and then maximized, so it is
+	  maximized on the monitor of the rectangle, see maximizeOnMonitor.
+	- A minimized window is moved and minimized once. A window restored by the user stays at its
+	  rectangle and counts as in place, so it is not minimized again by the next cycle.
+	- Minimized windows are parked off-screen, so they are compared by their normal rectangle, see
+	  placementRect. A minimized window with another normal rectangle gets the target as normal
+	  rectangle through SetWindowPlacement, without being restored.
+	- Positions without a state (saved before states existed) leave the state of the window alone.
+*/
+
+// States of a window, stored in WindowPosition.State
+const (
+	windowStateNormal    = "normal"
+	windowStateMaximized = "maximized"
+	windowStateMinimized = "minimized"
+)
+
+// captureWindowState records the state of a window in a position read by getWindowPosition.
+// If the window is maximized or minimized, the rectangle is replaced by the normal rectangle.
+func captureWindowState(hwnd syscall.Handle, pos *WindowPosition) {
+	normal, err := getNormalRect(hwnd)
+	if err != nil {
+		log(true, "Saving without window state:", err)
+		return
+	}
+	switch {
+	case isWindowMinimized(hwnd):
+		pos.State = windowStateMinimized
+	case isWindowMaximized(hwnd):
+		pos.State = windowStateMaximized
+	default:
+		pos.State = windowStateNormal
+		return
+	}
+	pos.X, pos.Y = int(normal.Left), int(normal.Top)
+	pos.Width, pos.Height = int(normal.Right-normal.Left), int(normal.Bottom-normal.Top)
+}
+
+// workspaceOffset returns how far workspace coordinates, which WINDOWPLACEMENT uses for the normal
+// rectangle, are shifted against screen coordinates: by the offset of the work area of the monitor.
+func workspaceOffset(rect RECT) (int32, int32) {
+	monitors, err := getCachedMonitors()
+	if err != nil {
+		return 0, 0
+	}
+	index := monitorForRect(rect, monitors)
+	if index < 0 {
+		return 0, 0
+	}
+	monitor := monitors[index]
+	return monitor.WorkArea.Left - monitor.Bounds.Left, monitor.WorkArea.Top - monitor.Bounds.Top
+}
+
+// getNormalRect returns the normal (restore) rectangle of a window in screen coordinates.
+func getNormalRect(hwnd syscall.Handle) (RECT, error) {
+	var placement WINDOWPLACEMENT
+	placement.Length = uint32(unsafe.Sizeof(placement))
+	if ret, _, err := procGetWindowPlacement.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&placement))); ret == 0 {
+		return RECT{}, fmt.Errorf("GetWindowPlacement failed: %v", err)
+	}
+	normal := placement.RcNormalPosition
+	dx, dy := workspaceOffset(normal)
+	return RECT{Left: normal.Left + dx, Top: normal.Top + dy, Right: normal.Right + dx, Bottom: normal.Bottom + dy}, nil
+}
+
+// setNormalRect changes the normal rectangle of a minimized window to the target, leaving it minimized.
+func setNormalRect(hwnd syscall.Handle, target WindowPosition) error {
+	var placement WINDOWPLACEMENT
+	placement.Length = uint32(unsafe.Sizeof(placement))
+	if ret, _, err := procGetWindowPlacement.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&placement))); ret == 0 {
+		return fmt.Errorf("GetWindowPlacement failed: %v", err)
+	}
+	rect := target.Rect()
+	dx, dy := workspaceOffset(rect)
+	placement.RcNormalPosition = RECT{Left: rect.Left - dx, Top: rect.Top - dy, Right: rect.Right - dx, Bottom: rect.Bottom - dy}
+	placement.ShowCmd = SW_SHOWMINNOACTIVE
+	if ret, _, err := procSetWindowPlacement.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&placement))); ret == 0 {
+		return fmt.Errorf("SetWindowPlacement failed: %v", err)
+	}
+	return nil
+}
+
+// placementRect returns the rectangle of a window to compare with the target of a rule:
+// the normal rectangle of a minimized window, which is parked off-screen, otherwise the window rectangle.
+func placementRect(window WindowInfo) RECT {
+	if isWindowMinimized(window.Handle) {
+		if normal, err := getNormalRect(window.Handle); err == nil {
+			return normal
+		}
+	}
+	return window.WindowRect
+}
+
+// inSavedState reports whether a window is in the saved state, given whether its placementRect is at the target.
+// A minimized rule's window that is at the target counts as in place whether it is minimized or not.
+func inSavedState(hwnd syscall.Handle, state string, atTarget bool) bool {
+	switch state {
+	case windowStateNormal:
+		return atTarget && !isWindowMaximized(hwnd) && !isWindowMinimized(hwnd)
+	}
+	return atTarget
+}
+
+// maximizes reports whether a rule maximizes its window, on a chosen monitor or where it is placed.
+func (pos WindowPosition) maximizes() bool {
+	return pos.MaximizeMonitor != "" || pos.State == windowStateMaximized
+}
+
+// onMaximizeMonitor centers the target on the work area of the monitor the rule maximizes on,
+// so the window is maximized there and restores onto it. Rules without a monitor keep the target.
+// It fails if the monitor is not connected.
+func (pos WindowPosition) onMaximizeMonitor(target WindowPosition, monitors []Monitor) (WindowPosition, error) {
+	if pos.MaximizeMonitor == "" {
+		return target, nil
+	}
+	monitor, ok := findMonitorByDeviceName(pos.MaximizeMonitor, monitors)
+	if !ok {
+		return target, fmt.Errorf("monitor %s to maximize on is not connected", pos.MaximizeMonitor)
+	}
+	return centerOnWorkArea(target.Width, target.Height, monitor.WorkArea), nil
+}
+
+// moveToSavedState moves a window to the target of its rule in the rule's window state:
+// maximized rules are maximized on the monitor of the target, the others use moveWindowToState.
+// The target must already be placed with onMaximizeMonitor.
+func (wm *WindowManager) moveToSavedState(window WindowInfo, pos, target WindowPosition, opts moveOptions) error {
+	if pos.maximizes() {
+		return maximizeOnMonitor(window.Handle, target, opts)
+	}
+	return wm.moveWindowToState(window, target, pos.State, opts)
+}
+
+// moveWindowToState restores a window whose saved state is normal, moves it to the target
+// and minimizes it if its saved state is minimized. A window that is already minimized only gets
+// the target as normal rectangle. Maximized windows use maximizeOnMonitor.
+func (wm *WindowManager) moveWindowToState(window WindowInfo, target WindowPosition, state string, opts moveOptions) error {
+	hwnd := uintptr(window.Handle)
+	// The move captures the window for undo itself, state changes are captured before they happen,
+	// so windows that are already in place are not recorded
+	capture := func() {
+		if !opts.SkipUndo {
+			moveHistory.capture(window.Handle)
+		}
+	}
+	if state == windowStateNormal && (isWindowMaximized(window.Handle) || isWindowMinimized(window.Handle)) {
+		capture()
+		procShowWindow.Call(hwnd, SW_RESTORE)
+	}
+	if state == windowStateMinimized && isWindowMinimized(window.Handle) {
+		capture()
+		return setNormalRect(window.Handle, target)
+	}
+	if err := wm.moveWindowAnyElevation(window, target, opts); err != nil {
+		return err
+	}
+	if state == windowStateMinimized {
+		capture()
+		procShowWindow.Call(hwnd, SW_SHOWMINNOACTIVE)
+	}
+	return nil
+}
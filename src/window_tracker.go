@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+// trackedWindow holds what the window tracker knows about a window
+type trackedWindow struct {
+	FirstSeen   time.Time // First time the window was enumerated in this session
+	LastSeen    time.Time // Last time the window was enumerated
+	Title       string    // Title when the window was last enumerated
+	Rect        RECT      // Window rectangle when the window was last enumerated
+	StableSince time.Time // Since when title and rectangle did not change
+	Preexisting bool      // The window already existed when the tracker was first updated
+	DefaultRule bool      // The default rule was applied to the window
+	Matched     string    // Identifier of the rule the window was counted for, see claimMatch
+}
+
+// windowTracker records when windows were first seen in this session and since when
+// they are stable, i.e. their title and rectangle did not change.
+// Windows that disappear briefly (e.g. while they are hidden and shown again) keep their
+// first-seen time, they are only forgotten after they were gone for forgetAfter.
+type windowTracker struct {
+	mutex   sync.Mutex
+	windows map[syscall.Handle]*trackedWindow
+	updated bool // update was called at least once
+}
+
+// forgetAfter is how long a window can be gone before the tracker forgets it
+const forgetAfter = time.Minute
+
+// newWindowTracker creates an empty window tracker.
+func newWindowTracker() *windowTracker {
+	return &windowTracker{windows: make(map[syscall.Handle]*trackedWindow)}
+}
+
+// update records the currently enumerated windows.
+func (t *windowTracker) update(windows []WindowInfo, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, window := range windows {
+		tracked, ok := t.windows[window.Handle]
+		if !ok {
+			tracked = &trackedWindow{FirstSeen: now, StableSince: now, Preexisting: !t.updated}
+			t.windows[window.Handle] = tracked
+		} else if tracked.Title != window.Title || tracked.Rect != window.WindowRect {
+			tracked.StableSince = now
+		}
+		tracked.LastSeen = now
+		tracked.Title = window.Title
+		tracked.Rect = window.WindowRect
+	}
+	for hwnd, tracked := range t.windows {
+		if now.Sub(tracked.LastSeen) > forgetAfter {
+			delete(t.windows, hwnd)
+		}
+	}
+	t.updated = true
+}
+
+// age returns how long a window has been known. Unknown windows have the age 0.
+func (t *windowTracker) age(hwnd syscall.Handle, now time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.windows[hwnd]
+	if !ok {
+		return 0
+	}
+	return now.Sub(tracked.FirstSeen)
+}
+
+// stableFor returns how long the title and rectangle of a window did not change.
+// Unknown windows have the duration 0.
+func (t *windowTracker) stableFor(hwnd syscall.Handle, now time.Time) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.windows[hwnd]
+	if !ok {
+		return 0
+	}
+	return now.Sub(tracked.StableSince)
+}
+
+// claimMatch records that a window matched a rule. It returns true only the first time the
+// window matches the rule, so each window is counted once per rule and not every cycle.
+// Unknown windows are not counted.
+func (t *windowTracker) claimMatch(hwnd syscall.Handle, identifier string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.windows[hwnd]
+	if !ok || tracked.Matched == identifier {
+		return false
+	}
+	tracked.Matched = identifier
+	return true
+}
+
+// claimDefaultRule marks the default rule as applied to a window. It returns false if the
+// window is unknown, existed before the tracker was first updated, or was already claimed,
+// so the default rule is applied at most once to each window that appeared in this session.
+func (t *windowTracker) claimDefaultRule(hwnd syscall.Handle) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tracked, ok := t.windows[hwnd]
+	if !ok || tracked.Preexisting || tracked.DefaultRule {
+		return false
+	}
+	tracked.DefaultRule = true
+	return true
+}
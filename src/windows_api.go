@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf16"
 	"unsafe"
 )
 
@@ -12,29 +16,95 @@ import (
 // It includes the window handle, title, class name, process ID, executable path or name,
 // window styles, extended styles, and rectangles for the client area and window rectangle.
 type WindowInfo struct {
-	Handle           syscall.Handle
-	Title, ClassName string
-	ProcessID        uint32
-	Executable       string // Process executable path or name
-	Style            uint32 // Window styles (GWL_STYLE)
-	ExStyle          uint32 // Extended styles (GWL_EXSTYLE)
-	ClientRect       RECT   // Client area rectangle (relative to window)
-	WindowRect       RECT   // Window rectangle (screen coordinates)
+	Handle     syscall.Handle `json:"handle"`
+	Title      string         `json:"title"`
+	ClassName  string         `json:"className"`
+	ProcessID  uint32         `json:"processId"`
+	Executable string         `json:"executable"` // Process executable path or name
+	Style      uint32         `json:"style"`      // Window styles (GWL_STYLE)
+	ExStyle    uint32         `json:"exStyle"`    // Extended styles (GWL_EXSTYLE)
+	ClientRect RECT           `json:"clientRect"` // Client area rectangle (relative to window)
+	WindowRect RECT           `json:"windowRect"` // Window rectangle (screen coordinates)
 }
 
 // WindowPosition holds the position and size of a window
 // It includes the x and y coordinates, width, and height.
+// If Logical is set, Width and Height are DPI-independent (96 DPI) units that are
+// converted to physical pixels for the target monitor when the position is applied.
 type WindowPosition struct {
-	X      int `json:"x"`
-	Y      int `json:"y"`
-	Width  int `json:"width"`
-	Height int `json:"height"`
+	X       int  `json:"x"`
+	Y       int  `json:"y"`
+	Width   int  `json:"width"`
+	Height  int  `json:"height"`
+	Logical bool `json:"logical,omitempty"` // Width and Height are logical units
+
+	// The position is the visible frame without the invisible borders, see frame_bounds.go
+	VisibleFrame bool `json:"visibleFrame,omitempty"`
+
+	RawTitle string `json:"rawTitle,omitempty"` // Window title before normalization when saved, see title_normalize.go
+
+	TitlePattern bool `json:"titlePattern,omitempty"` // The title of the identifier is a glob or regular expression, see title_pattern.go
+
+	DisplayName string `json:"displayName,omitempty"` // Name the rule is listed with, the identifier stays the key, see display_name.go
+
+	// DPI of the monitor the position was saved on, physical sizes are scaled if it changed, see dpi_awareness.go
+	Dpi uint32 `json:"dpi,omitempty"`
+
+	// Monitor the position was saved on and the position relative to its top-left corner,
+	// so the position follows the monitor when the monitors are rearranged
+	MonitorDeviceName string `json:"monitorDeviceName,omitempty"`
+	RelativeX         int    `json:"relativeX,omitempty"`
+	RelativeY         int    `json:"relativeY,omitempty"`
+
+	UseScript       bool `json:"useScript,omitempty"`       // The position is computed by the script hook
+	FinishWithFocus bool `json:"finishWithFocus,omitempty"` // The window is applied last and gets the focus
+
+	// Click-through styles: ExStyleFlags holds the captured WS_EX_LAYERED/WS_EX_TRANSPARENT bits,
+	// which are reapplied on restore if RestoreExStyle is set
+	ExStyleFlags   uint32 `json:"exStyleFlags,omitempty"`
+	RestoreExStyle bool   `json:"restoreExStyle,omitempty"`
+
+	LastMatchedAt time.Time `json:"lastMatchedAt,omitzero"` // Last time an open window matched the rule, used for pruning
+	LastAppliedAt time.Time `json:"lastAppliedAt,omitzero"` // Last time a pass applied the rule to a window
+	MatchCount    int       `json:"matchCount,omitempty"`   // Number of windows that matched the rule
+
+	// Move behavior overrides for apps that are slow or fragile to move
+	MoveTimeoutMilliseconds int      `json:"moveTimeoutMilliseconds,omitempty"` // 0 uses the global move timeout
+	Strategies              []string `json:"strategies,omitempty"`              // Allowed move strategies in the order they are tried, empty allows all
+	SuccessfulMethod        string   `json:"successfulMethod,omitempty"`        // Strategy that last moved a window of the rule, tried first
+
+	ConfirmIfElevated bool `json:"confirmIfElevated,omitempty"` // Ask before moving the window of an elevated process
+	ApplyOnlyWhenNew  bool `json:"applyOnlyWhenNew,omitempty"`  // Only apply during the grace period after the window appeared
+	AlwaysOnTop       bool `json:"alwaysOnTop,omitempty"`       // Keep the window topmost, see topmost.go
+
+	Opacity int `json:"opacity,omitempty"` // Alpha value of the window (1-254), 0 or 255 leaves it opaque, see opacity.go
+
+	StableForMilliseconds int `json:"stableForMilliseconds,omitempty"` // Only apply after title and rectangle did not change for this time
+
+	Priority int `json:"priority,omitempty"` // The matching rule with the highest priority wins
+
+	Order int `json:"order,omitempty"` // Place in the stacking order when saved, 1 is the top, 0 is not recorded, see z_order.go
+
+	// Window state to restore (normal, maximized, minimized), see window_state.go, empty to keep the state
+	State string `json:"state,omitempty"`
+
+	// Maximize on the monitor with this device name (e.g. \\.\DISPLAY2), the size is the restore size
+	MaximizeMonitor string `json:"maximizeMonitor,omitempty"`
+
+	// What to do if the window is on another virtual desktop, see virtual_desktop.go
+	VirtualDesktop   string `json:"virtualDesktop,omitempty"`
+	VirtualDesktopID string `json:"virtualDesktopId,omitempty"` // Desktop GUID the window was saved on, used by virtualDesktopMove
+
+	Schedule *RuleSchedule `json:"schedule,omitempty"` // The rule is only active within the schedule, nil for always
 }
 
 // RECT represents a rectangle in screen coordinates
 // It is used to define the position and size of a window.
 type RECT struct {
-	Left, Top, Right, Bottom int32
+	Left   int32 `json:"left"`
+	Top    int32 `json:"top"`
+	Right  int32 `json:"right"`
+	Bottom int32 `json:"bottom"`
 }
 
 // POINT defines the x- and y-coordinates of a point
@@ -44,12 +114,12 @@ type POINT struct {
 
 // WINDOWPLACEMENT contains information about the placement of a window
 type WINDOWPLACEMENT struct {
-	Length           uint32  // Size of the structure in bytes
-	Flags            uint32  // Flags that specify the window's state
-	ShowCmd          uintptr // Show command for the window (SW_SHOW, SW_HIDE, etc.)
-	PtMinPosition    POINT   // Point for the minimized position of the window
-	PtMaxPosition    POINT   // Point for the maximized position of the window
-	RcNormalPosition RECT    // Normal position rectangle of the window
+	Length           uint32 // Size of the structure in bytes
+	Flags            uint32 // Flags that specify the window's state
+	ShowCmd          uint32 // Show command for the window (SW_SHOW, SW_HIDE, etc.)
+	PtMinPosition    POINT  // Point for the minimized position of the window
+	PtMaxPosition    POINT  // Point for the maximized position of the window
+	RcNormalPosition RECT   // Normal position rectangle of the window
 }
 
 // IAccessible interface definition
@@ -94,15 +164,41 @@ var (
 	procAccessibleObjectFromWindow = oleacc.NewProc("AccessibleObjectFromWindow") // Retrieves an accessible object from a window handle
 
 	// ole32.dll functions
-	ole32              = syscall.NewLazyDLL("ole32.dll") // OLE functions
-	procCoInitialize   = ole32.NewProc("CoInitialize")   // Initializes the COM library for use by the calling thread
-	procCoUninitialize = ole32.NewProc("CoUninitialize") // Uninitializes the COM library on the calling thread
+	ole32                = syscall.NewLazyDLL("ole32.dll")   // OLE functions
+	procCoCreateInstance = ole32.NewProc("CoCreateInstance") // Creates a COM object of a class
+	procCoInitialize     = ole32.NewProc("CoInitialize")     // Initializes the COM library for use by the calling thread
+	procCoUninitialize   = ole32.NewProc("CoUninitialize")   // Uninitializes the COM library on the calling thread
+
+	// advapi32.dll functions
+	advapi32                = syscall.NewLazyDLL("advapi32.dll")
+	procGetTokenInformation = advapi32.NewProc("GetTokenInformation") // Retrieves information about an access token
+	procOpenProcessToken    = advapi32.NewProc("OpenProcessToken")    // Opens the access token of a process
+
+	// dwmapi.dll functions
+	dwmapi                    = syscall.NewLazyDLL("dwmapi.dll")
+	procDwmGetWindowAttribute = dwmapi.NewProc("DwmGetWindowAttribute") // Retrieves a window attribute of the desktop window manager
+
+	// gdi32.dll functions
+	gdi32             = syscall.NewLazyDLL("gdi32.dll")
+	procDeleteObject  = gdi32.NewProc("DeleteObject")  // Deletes a GDI object like a bitmap
+	procGetBitmapBits = gdi32.NewProc("GetBitmapBits") // Copies the bits of a bitmap into a buffer
+	procGetObject     = gdi32.NewProc("GetObjectW")    // Retrieves information about a GDI object
 
 	// kernel32.dll functions
-	kernel32               = syscall.NewLazyDLL("kernel32.dll")
-	procCloseHandle        = kernel32.NewProc("CloseHandle")        // Closes a handle to a process or thread
-	procGetCurrentThreadId = kernel32.NewProc("GetCurrentThreadId") // Retrieves the thread ID of the calling thread
-	procOpenProcess        = kernel32.NewProc("OpenProcess")        // Opens a handle to a process
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	procCloseHandle          = kernel32.NewProc("CloseHandle")          // Closes a handle to a process or thread
+	procGetCurrentThreadId   = kernel32.NewProc("GetCurrentThreadId")   // Retrieves the thread ID of the calling thread
+	procGetModuleHandle      = kernel32.NewProc("GetModuleHandleW")     // Retrieves the module handle of the executable
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus") // Retrieves the power source and battery status
+	procOpenProcess          = kernel32.NewProc("OpenProcess")          // Opens a handle to a process
+
+	// shell32.dll functions
+	shell32            = syscall.NewLazyDLL("shell32.dll")
+	procShellExecuteEx = shell32.NewProc("ShellExecuteExW") // Starts a program, with a handle to wait for it
+
+	// shcore.dll functions
+	shcore               = syscall.NewLazyDLL("shcore.dll")
+	procGetDpiForMonitor = shcore.NewProc("GetDpiForMonitor") // Retrieves the DPI of a monitor (Windows 8.1+)
 
 	// psapi.dll functions
 	psapi                    = syscall.NewLazyDLL("psapi.dll")
@@ -112,45 +208,109 @@ var (
 	user32                       = syscall.NewLazyDLL("user32.dll")
 	procAllowSetForegroundWindow = user32.NewProc("AllowSetForegroundWindow") // Allows a process to set the foreground window
 	procAttachThreadInput        = user32.NewProc("AttachThreadInput")        // Attaches or detaches the input processing mechanism of one thread to another
+	procCreateWindowEx           = user32.NewProc("CreateWindowExW")          // Creates a window
+	procDefWindowProc            = user32.NewProc("DefWindowProcW")           // Default processing for window messages
+	procDestroyWindow            = user32.NewProc("DestroyWindow")            // Destroys a window
+	procDispatchMessage          = user32.NewProc("DispatchMessageW")         // Dispatches a message to a window procedure
+	procEnumDisplayMonitors      = user32.NewProc("EnumDisplayMonitors")      // Enumerates the display monitors
 	procEnumWindows              = user32.NewProc("EnumWindows")              // Enumerates all top-level windows
+	procFindWindow               = user32.NewProc("FindWindowW")              // Finds a top-level window by class name or title
+	procGetClassLongPtr          = user32.NewProc("GetClassLongPtrW")         // Retrieves a value associated with a window class
 	procGetClassName             = user32.NewProc("GetClassNameW")            // Retrieves the class name of a window
 	procGetClientRect            = user32.NewProc("GetClientRect")            // Retrieves the client area rectangle of a window
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")      // Retrieves the window the user is currently working with
+	procGetIconInfo              = user32.NewProc("GetIconInfo")              // Retrieves the bitmaps of an icon
+	procGetWindow                = user32.NewProc("GetWindow")                // Retrieves a window related to a window, like its owner
+	procGetMessage               = user32.NewProc("GetMessageW")              // Retrieves a message from the calling thread's message queue
+	procGetMonitorInfo           = user32.NewProc("GetMonitorInfoW")          // Retrieves the bounds and work area of a monitor
 	procGetSystemMetrics         = user32.NewProc("GetSystemMetrics")         // Retrieves system metrics or system configuration settings
 	procGetWindowLongPtrW        = user32.NewProc("GetWindowLongPtrW")        // Retrieves a value associated with a window (64-bit)
 	procGetWindowLongW           = user32.NewProc("GetWindowLongW")           // Retrieves a value associated with a window (32-bit fallback)
 	procGetWindowPlacement       = user32.NewProc("GetWindowPlacement")       // Retrieves the placement of a window
 	procGetWindowRect            = user32.NewProc("GetWindowRect")            // Retrieves the bounding rectangle of a window
 	procGetWindowText            = user32.NewProc("GetWindowTextW")           // Retrieves the title of a window
+	procGetWindowTextLength      = user32.NewProc("GetWindowTextLengthW")     // Retrieves the length of a window's title
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId") // Retrieves the thread and process ID of a window
+	procIsIconic                 = user32.NewProc("IsIconic")                 // Checks if a window is minimized
 	procIsWindowVisible          = user32.NewProc("IsWindowVisible")          // Checks if a window is visible
+	procIsZoomed                 = user32.NewProc("IsZoomed")                 // Checks if a window is maximized
+	procMessageBeep              = user32.NewProc("MessageBeep")              // Plays a system sound
+	procMonitorFromRect          = user32.NewProc("MonitorFromRect")          // Retrieves the monitor that has the largest intersection with a rectangle
+	procPeekMessage              = user32.NewProc("PeekMessageW")             // Checks the thread message queue for a message
 	procPostMessage              = user32.NewProc("PostMessageW")             // Posts a message to a window's message queue
+	procPostThreadMessage        = user32.NewProc("PostThreadMessageW")       // Posts a message to a thread's message queue
+	procRegisterClassEx          = user32.NewProc("RegisterClassExW")         // Registers a window class
+	procRegisterHotKey           = user32.NewProc("RegisterHotKey")           // Defines a system-wide hotkey
+	procRegisterWindowMessage    = user32.NewProc("RegisterWindowMessageW")   // Defines a window message that is unique system-wide
 	procSendMessage              = user32.NewProc("SendMessageW")             // Sends a message to a window and waits for the result
+	procSendMessageTimeout       = user32.NewProc("SendMessageTimeoutW")      // Sends a message to a window with a timeout
 	procSetForegroundWindow      = user32.NewProc("SetForegroundWindow")      // Brings a window to the foreground
+	procSetWindowLongPtrW        = user32.NewProc("SetWindowLongPtrW")        // Changes a value associated with a window (64-bit)
 	procSetWindowPlacement       = user32.NewProc("SetWindowPlacement")       // Sets the placement of a window
 	procSetWindowPos             = user32.NewProc("SetWindowPos")             // Sets the position and size of a window
+	procSetWinEventHook          = user32.NewProc("SetWinEventHook")          // Installs a hook for accessibility events
+	procSetWindowText            = user32.NewProc("SetWindowTextW")           // Changes the title of a window
 	procShowWindow               = user32.NewProc("ShowWindow")               // Shows or hides a window
+	procUnhookWinEvent           = user32.NewProc("UnhookWinEvent")           // Removes a hook installed by SetWinEventHook
+	procUnregisterHotKey         = user32.NewProc("UnregisterHotKey")         // Frees a hotkey previously registered
+
+	// user32.dll functions for layered windows
+	procGetLayeredWindowAttributes = user32.NewProc("GetLayeredWindowAttributes") // Retrieves the opacity of a layered window
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes") // Sets the opacity of a layered window
+
+	// user32.dll functions for DPI awareness (Windows 10 1607+)
+	procGetDpiForWindow               = user32.NewProc("GetDpiForWindow")               // Retrieves the DPI of the monitor a window is on
+	procSetProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext") // Sets the DPI awareness of the process
 
 )
 
 // Constants for window attributes and styles
 const (
+	DWMWA_CLOAKED                     = 14               // Why a window is cloaked (hidden by DWM), 0 if it is not
 	DWMWA_EXTENDED_FRAME_BOUNDS       = 9                // Extended frame bounds for DWM
+	DWM_CLOAKED_SHELL                 = 0x2              // Cloaked by the shell, e.g. on another virtual desktop
+	EVENT_OBJECT_LOCATIONCHANGE       = 0x800B           // WinEvent: An object moved or was resized
+	EVENT_OBJECT_SHOW                 = 0x8002           // WinEvent: An object was shown
+	EVENT_SYSTEM_FOREGROUND           = 0x0003           // WinEvent: The foreground window changed
+	GCLP_HICONSM                      = -34              // Index for the small icon of a window class
 	GWL_EXSTYLE                       = -20              // Index for extended window styles
+	GW_HWNDPREV                       = 3                // GetWindow: The window above in the Z order
+	GW_OWNER                          = 4                // GetWindow: The owner window
 	GWL_STYLE                         = -16              // Index for window styles
 	HWND_TOP                          = 0                // Place window at top of Z order
+	ICON_SMALL2                       = 2                // WM_GETICON: Small icon, or a system generated one
+	LWA_ALPHA                         = 0x00000002       // Use the alpha value of SetLayeredWindowAttributes
 	HWND_TOPMOST                      = ^uintptr(0)      // -1 in two's complement (all bits set)
 	HWND_NOTOPMOST                    = ^uintptr(0) - 1  // -2 in two's complement (all bits set except least significant)
+	MB_ICONWARNING                    = 0x00000030       // Warning sound for MessageBeep
+	MDT_EFFECTIVE_DPI                 = 0                // Effective DPI of a monitor (includes the user's scaling)
+	MOD_ALT                           = 0x0001           // Hotkey modifier: Alt key
+	MOD_CONTROL                       = 0x0002           // Hotkey modifier: Ctrl key
+	MOD_NOREPEAT                      = 0x4000           // Hotkey modifier: Do not repeat while the key is held down
+	MOD_SHIFT                         = 0x0004           // Hotkey modifier: Shift key
+	MOD_WIN                           = 0x0008           // Hotkey modifier: Windows key
+	MONITOR_DEFAULTTONEAREST          = 2                // Return the monitor nearest to the rectangle or point
+	MONITORINFOF_PRIMARY              = 0x00000001       // The monitor is the primary display
+	CLSCTX_ALL                        = 0x17             // CoCreateInstance: Any server context
 	CHILDID_SELF                      = 0                // Child ID for the window itself
 	OBJID_WINDOW                      = 0x00000000       // Object ID for a window
+	PBT_APMPOWERSTATUSCHANGE          = 0x000A           // WM_POWERBROADCAST: The power source or battery status changed
+	PM_NOREMOVE                       = 0x0000           // PeekMessage: Do not remove the message from the queue
 	PROCESS_QUERY_LIMITED_INFORMATION = 0x1000           // Access rights for OpenProcess
+	SEE_MASK_NOASYNC                  = 0x00000100       // ShellExecuteEx: Wait until the program was started
+	SEE_MASK_NOCLOSEPROCESS           = 0x00000040       // ShellExecuteEx: Return a handle to the started process
 	SC_MOVE                           = 0xF010           // System command to move a window
 	SC_RESTORE                        = 0xF120           // System command to restore a window
+	SMTO_ABORTIFHUNG                  = 0x0002           // SendMessageTimeout: Return immediately if the receiver is hung
+	SPI_SETWORKAREA                   = 0x002F           // WM_SETTINGCHANGE: The work area of a monitor changed
 	SM_CXSCREEN                       = 0                // Width of the primary display
 	SM_CXVIRTUALSCREEN                = 78               // Width of the virtual screen
 	SM_CYSCREEN                       = 1                // Height of the primary display
 	SM_CYVIRTUALSCREEN                = 79               // Height of the virtual screen
 	SM_XVIRTUALSCREEN                 = 76               // X-coordinate of the virtual screen
 	SM_YVIRTUALSCREEN                 = 77               // Y-coordinate of the virtual screen
+	TOKEN_QUERY                       = 0x0008           // Access right to query an access token
+	TokenElevation                    = 20               // TOKEN_INFORMATION_CLASS: Whether the token is elevated
 	SW_FORCEMINIMIZE                  = 11               // Force minimize window
 	SW_MAXIMIZE                       = 3                // Maximize window
 	SW_MINIMIZE                       = 6                // Minimize window
@@ -158,7 +318,11 @@ const (
 	SW_SHOW                           = 5                // Show window
 	SW_SHOWMAXIMIZED                  = 3                // Show window as maximized
 	SW_SHOWMINIMIZED                  = 2                // Show window as minimized
+	SW_SHOWMINNOACTIVE                = 7                // Minimize window without activating another
+	SW_SHOWNOACTIVATE                 = 4                // Show window in its normal size without activating it
 	SW_SHOWNORMAL                     = 1                // Show window in normal state
+	USER_DEFAULT_SCREEN_DPI           = 96               // DPI of a monitor at 100% scaling
+	VK_F1                             = 0x70             // Virtual-key code of F1, F2..F24 follow consecutively
 	SWP_ASYNCWINDOWPOS                = 0x4000           // Asynchronous window positioning
 	SWP_FRAMECHANGED                  = 0x0020           // The frame changed; send WM_NCCALCSIZE
 	SWP_DRAWFRAME                     = SWP_FRAMECHANGED // Draw the frame (if the window has a frame)
@@ -168,10 +332,69 @@ const (
 	SWP_NOZORDER                      = 0x0004           // Do not change the Z order of the window
 	SWP_SHOWWINDOW                    = 0x0040           // Show the window when setting position and size
 	SWP_STATECHANGED                  = 0x4000           // The window's state has changed; send WM_WINDOWPOSCHANGED
+	WS_CAPTION                        = 0x00C00000       // Window style for a title bar (includes WS_BORDER)
+	WS_EX_APPWINDOW                   = 0x00040000       // Extended window style forcing a taskbar button, also for tool and owned windows
+	WS_EX_LAYERED                     = 0x00080000       // Extended window style for layered (alpha blended) windows
+	WS_EX_TOOLWINDOW                  = 0x00000080       // Extended window style for tool windows (no taskbar button)
 	WS_EX_TOPMOST                     = 0x00000008       // Extended window style for topmost windows
+	WS_EX_TRANSPARENT                 = 0x00000020       // Extended window style for click-through windows (with WS_EX_LAYERED)
+	WM_DISPLAYCHANGE                  = 0x007E           // Display resolution or monitor configuration changed message
+	WM_GETICON                        = 0x007F           // Retrieves the icon of a window message
+	WM_HOTKEY                         = 0x0312           // Hotkey pressed message
+	WM_POWERBROADCAST                 = 0x0218           // Power management event message
+	WM_QUIT                           = 0x0012           // Quit message loop message
+	WM_SETTINGCHANGE                  = 0x001A           // System-wide setting changed message
 	WM_SYSCOMMAND                     = 0x0112           // System command message
+	WM_USER                           = 0x0400           // First private window message
+	WINEVENT_OUTOFCONTEXT             = 0x0000           // SetWinEventHook: The callback runs in the calling thread
+	WINEVENT_SKIPOWNPROCESS           = 0x0002           // SetWinEventHook: No events of the own process
 )
 
+// shellWindowClasses is a hardcoded safety blocklist of Windows shell window classes.
+// Moving the taskbar or desktop windows can break the shell, so these windows are never
+// listed or moved, regardless of any other filter or setting.
+var shellWindowClasses = map[string]bool{
+	"Shell_TrayWnd":          true, // Taskbar on the primary monitor
+	"Shell_SecondaryTrayWnd": true, // Taskbar on secondary monitors
+	"Progman":                true, // Desktop (Program Manager)
+	"WorkerW":                true, // Desktop wallpaper and icon host
+}
+
+// isShellWindowClass checks if a class name belongs to the shell blocklist.
+func isShellWindowClass(className string) bool {
+	return shellWindowClasses[className]
+}
+
+// getCloaked returns why DWM hides a window, 0 if the window is not cloaked or DWM is not available.
+func getCloaked(hwnd syscall.Handle) uint32 {
+	var cloaked uint32
+	if err := procDwmGetWindowAttribute.Find(); err != nil {
+		return 0
+	}
+	ret, _, _ := procDwmGetWindowAttribute.Call(uintptr(hwnd), DWMWA_CLOAKED,
+		uintptr(unsafe.Pointer(&cloaked)), unsafe.Sizeof(cloaked))
+	if ret != 0 { // HRESULT
+		return 0
+	}
+	return cloaked
+}
+
+// isHiddenByDWM checks if a visible window is not actually shown, like the suspended frames
+// of UWP apps. Windows on other virtual desktops are cloaked by the shell as well, they are kept
+// so the virtual desktop policies of their rules still apply.
+func isHiddenByDWM(hwnd syscall.Handle) bool {
+	cloaked := getCloaked(hwnd)
+	if cloaked == 0 {
+		return false
+	}
+	if cloaked&DWM_CLOAKED_SHELL != 0 {
+		if onCurrent, err := isWindowOnCurrentDesktop(hwnd); err == nil && !onCurrent {
+			return false
+		}
+	}
+	return true
+}
+
 // Global callback for window enumeration to prevent memory leaks
 var globalEnumCallback uintptr
 
@@ -179,6 +402,15 @@ var globalEnumCallback uintptr
 var enumeratedWindows []WindowInfo
 var enumMutex sync.Mutex
 
+// Last successfully enumerated windows, returned if enumeration fails
+var (
+	lastKnownWindows []WindowInfo
+	lastKnownAt      time.Time
+	lastKnownMutex   sync.Mutex
+)
+
+const enumRetryDelay = 250 * time.Millisecond // Delay before retrying a failed enumeration
+
 // init function to create the callback once
 func init() {
 	globalEnumCallback = syscall.NewCallback(enumWindowsCallbackFunc)
@@ -203,11 +435,19 @@ func enumWindowsCallbackFunc(hwnd syscall.Handle, lparam uintptr) uintptr {
 
 	if isWindowVisible(hwnd) {
 		info := getWindowInfo(hwnd)
+		if isShellWindowClass(info.ClassName) {
+			log(debug, "Skipping shell window:", info.ClassName)
+			return 1 // Continue enumeration
+		}
+		if isHiddenByDWM(hwnd) {
+			log(debug, "Skipping cloaked window:", info.ClassName)
+			return 1 // Continue enumeration
+		}
 		width := int(info.WindowRect.Right - info.WindowRect.Left)
 		height := int(info.WindowRect.Bottom - info.WindowRect.Top)
 		if width > 8 && height > 8 {
 			log(debug, "Found window via handle:", info.Handle)
-			log(debug, "- Title       :", info.Title)
+			log(debug, "- Title       :", redactTitle(info.Title))
 			log(debug, "- ClassName   :", info.ClassName)
 			log(debug, "- Executable  :", info.Executable)
 			log(debug, "- Style       :", info.Style)
@@ -233,6 +473,36 @@ func EnumerateWindows() ([]WindowInfo, error) {
 	debug := false
 	log(debug, "Enumerating visible windows.")
 
+	windows, err := enumerateWindowsOnce()
+	if err != nil {
+		// EnumWindows can fail transiently, e.g. while the desktop is switched
+		log(true, "EnumWindows failed, retrying in", enumRetryDelay, ":", err)
+		time.Sleep(enumRetryDelay)
+		windows, err = enumerateWindowsOnce()
+	}
+
+	lastKnownMutex.Lock()
+	defer lastKnownMutex.Unlock()
+	if err != nil {
+		if lastKnownWindows == nil {
+			log(true, "EnumWindows failed again, no last-known window list available:", err)
+			return nil, err
+		}
+		log(true, "WARNING: EnumWindows failed again, using the last-known window list from",
+			lastKnownAt.Format("15:04:05"), "with", len(lastKnownWindows), "windows:", err)
+		recordProblem(problemWarning, "", "Window enumeration failed, using the last-known window list:", err)
+		result := make([]WindowInfo, len(lastKnownWindows))
+		copy(result, lastKnownWindows)
+		return result, nil
+	}
+	lastKnownWindows = make([]WindowInfo, len(windows))
+	copy(lastKnownWindows, windows)
+	lastKnownAt = time.Now()
+	return windows, nil
+}
+
+// enumerateWindowsOnce enumerates the visible windows with a single EnumWindows call.
+func enumerateWindowsOnce() ([]WindowInfo, error) {
 	// Reset the shared windows slice
 	enumMutex.Lock()
 	enumeratedWindows = enumeratedWindows[:0] // Clear slice but keep capacity
@@ -240,7 +510,6 @@ func EnumerateWindows() ([]WindowInfo, error) {
 
 	ret, _, err := procEnumWindows.Call(globalEnumCallback, 0)
 	if ret == 0 {
-		log(true, "EnumWindows failed:", err)
 		return nil, fmt.Errorf("EnumWindows failed: %v", err)
 	}
 
@@ -282,8 +551,6 @@ func getWindowInfo(hwnd syscall.Handle) WindowInfo {
 		return WindowInfo{Handle: hwnd}
 	}
 
-	const maxWinText = 256
-
 	// Initialize with safe defaults
 	var title, className string
 	var processID uint32
@@ -291,27 +558,15 @@ func getWindowInfo(hwnd syscall.Handle) WindowInfo {
 	// Only proceed with API calls if the window appears to be valid
 	if isValidWindow(hwnd) {
 		// Get window title
-		titleBuf := make([]uint16, maxWinText)
-		ret, _, err := procGetWindowText.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&titleBuf[0])), uintptr(len(titleBuf)))
-		if ret == 0 {
-			log(debug, "GetWindowText failed:", err) // debug since it is common to fail
-		} else {
-			title = syscall.UTF16ToString(titleBuf)
-		}
-		log(debug, "Window title:", title)
+		title = getWindowText(hwnd)
+		log(debug, "Window title:", redactTitle(title))
 
 		// Get class name
-		classBuf := make([]uint16, maxWinText)
-		ret, _, err = procGetClassName.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&classBuf[0])), uintptr(len(classBuf)))
-		if ret == 0 {
-			log(debug, "GetClassName failed:", err)
-		} else {
-			className = syscall.UTF16ToString(classBuf)
-		}
+		className = getClassName(hwnd)
 		log(debug, "Window class name:", className)
 
 		// Get process ID
-		ret, _, err = procGetWindowThreadProcessId.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&processID)))
+		ret, _, err := procGetWindowThreadProcessId.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&processID)))
 		if ret == 0 {
 			log(debug, "GetWindowThreadProcessId failed:", err)
 		}
@@ -375,6 +630,55 @@ func getWindowInfo(hwnd syscall.Handle) WindowInfo {
 	}
 }
 
+// getWindowText retrieves the title of a window.
+// The buffer is sized from the title length, so long titles are not truncated.
+// It returns an empty string if the title cannot be retrieved.
+func getWindowText(hwnd syscall.Handle) string {
+	debug := false
+	length, _, _ := procGetWindowTextLength.Call(uintptr(hwnd))
+	if length == 0 {
+		return ""
+	}
+	titleBuf := make([]uint16, length+1) // Room for the terminating null character
+	ret, _, err := procGetWindowText.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&titleBuf[0])), uintptr(len(titleBuf)))
+	if ret == 0 {
+		log(debug, "GetWindowText failed:", err) // debug since it is common to fail
+		return ""
+	}
+	return utf16ToString(titleBuf[:ret])
+}
+
+// utf16ToString converts UTF-16 text returned by the Windows API to a string.
+// If the title changed between the length and text calls, the text can be cut in the middle
+// of a surrogate pair (e.g. an emoji). The dangling high surrogate is dropped instead of
+// being turned into a replacement character, so the title still matches saved identifiers.
+func utf16ToString(buf []uint16) string {
+	for i, c := range buf {
+		if c == 0 {
+			buf = buf[:i]
+			break
+		}
+	}
+	if n := len(buf); n > 0 && buf[n-1] >= 0xD800 && buf[n-1] <= 0xDBFF {
+		buf = buf[:n-1]
+	}
+	return string(utf16.Decode(buf))
+}
+
+// getClassName retrieves the class name of a window.
+// It returns an empty string if the class name cannot be retrieved.
+func getClassName(hwnd syscall.Handle) string {
+	debug := false
+	const maxClassName = 256
+	classBuf := make([]uint16, maxClassName)
+	ret, _, err := procGetClassName.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&classBuf[0])), uintptr(len(classBuf)))
+	if ret == 0 {
+		log(debug, "GetClassName failed:", err)
+		return ""
+	}
+	return syscall.UTF16ToString(classBuf)
+}
+
 // isValidWindow checks if a window handle is still valid
 func isValidWindow(hwnd syscall.Handle) bool {
 	if hwnd == 0 {
@@ -425,6 +729,68 @@ func getWindowPosition(hwnd syscall.Handle) (*WindowPosition, error) {
 // MoveWindowAccurate moves a window to a specified position and size.
 // It uses multiple techniques to work around elevation restrictions.
 func MoveWindowAccurate(hwnd syscall.Handle, x, y, width, height int) error {
+	return moveWindowWithOptions(hwnd, x, y, width, height, moveOptions{})
+}
+
+// positionTolerance is the number of pixels a window may differ from its target
+// in each of position and size to count as in place
+var positionTolerance atomic.Int32
+
+// setPositionTolerance sets the tolerance used by withinPositionTolerance.
+func setPositionTolerance(pixels int) {
+	positionTolerance.Store(int32(max(pixels, 0)))
+}
+
+// withinPositionTolerance checks if the position and the size of a window rectangle differ
+// from the target by at most the position tolerance. Comparing the size instead of the right and
+// bottom edges keeps a small offset of the corner from counting twice.
+func withinPositionTolerance(rect, target RECT) bool {
+	tolerance := positionTolerance.Load()
+	within := func(a, b int32) bool {
+		return a-b <= tolerance && b-a <= tolerance
+	}
+	return within(rect.Left, target.Left) && within(rect.Top, target.Top) &&
+		within(rect.Right-rect.Left, target.Right-target.Left) &&
+		within(rect.Bottom-rect.Top, target.Bottom-target.Top)
+}
+
+// moveOptions limits how a window is moved, so slow or fragile apps can be handled per rule.
+type moveOptions struct {
+	Timeout    time.Duration // No further strategies are tried after this time, 0 means no limit
+	Strategies []string      // Names of the allowed strategies in the order they are tried, empty allows all
+	Preferred  string        // Name of a strategy to try first, if it is allowed
+	Clamp      bool          // Keep the window on the monitor that contains most of it, see clampToMonitors
+	SkipUndo   bool          // Do not capture the placement for undo, see undo.go
+
+	Report func(strategy string, succeeded bool) // Called after each strategy attempt, may be nil
+}
+
+// allowedStrategies returns the move strategies permitted by the options in the configured order.
+// The preferred strategy comes first.
+func (opts moveOptions) allowedStrategies() []moveStrategy {
+	candidates := moveStrategies
+	if len(opts.Strategies) > 0 {
+		candidates = nil
+		for _, name := range opts.Strategies {
+			if strategy, ok := findMoveStrategy(name); ok {
+				candidates = append(candidates, strategy)
+			}
+		}
+	}
+	var allowed []moveStrategy
+	for _, strategy := range candidates {
+		if strategy.Name == opts.Preferred {
+			allowed = append([]moveStrategy{strategy}, allowed...)
+		} else {
+			allowed = append(allowed, strategy)
+		}
+	}
+	return allowed
+}
+
+// moveWindowWithOptions moves a window like MoveWindowAccurate, but only tries the allowed
+// strategies and gives up when the time budget is spent. A running strategy is not interrupted.
+func moveWindowWithOptions(hwnd syscall.Handle, x, y, width, height int, opts moveOptions) error {
 	debug := false
 	log(debug, "Moving window:", hwnd, "to position:", x, y, "with size:", width, height)
 
@@ -433,97 +799,231 @@ func MoveWindowAccurate(hwnd syscall.Handle, x, y, width, height int) error {
 		return fmt.Errorf("invalid or destroyed window handle: %v", hwnd)
 	}
 
+	// Never move the taskbar or the desktop
+	if className := getClassName(hwnd); isShellWindowClass(className) {
+		log(true, "-> Refusing to move shell window:", className)
+		return fmt.Errorf("refusing to move shell window of class '%s'", className)
+	}
+
+	if opts.Clamp {
+		if monitors, err := getCachedMonitors(); err == nil {
+			clamped := clampToMonitors(WindowPosition{X: x, Y: y, Width: width, Height: height}, monitors)
+			x, y, width, height = clamped.X, clamped.Y, clamped.Width, clamped.Height
+		}
+	}
+
 	// Get current position and size
 	pos, err := getWindowPosition(hwnd)
 	if err != nil {
 		log(true, "-> Failed to get current window position:", err)
 		return fmt.Errorf("failed to get current window position: %v", err)
 	}
-	if pos.X == x && pos.Y == y && pos.Width == width && pos.Height == height {
+	if withinPositionTolerance(pos.Rect(), WindowPosition{X: x, Y: y, Width: width, Height: height}.Rect()) {
 		log(debug, "-> Window already at desired position and size.")
-		return nil // Already at desired position and size
+		return nil // Already at desired position and size, within the tolerance
+	}
+	if !opts.SkipUndo {
+		moveHistory.capture(hwnd)
+	}
+
+	// Try the allowed strategies until one moves the window or the time budget is spent.
+	// A strategy can report success without the window moving, e.g. if the app ignores
+	// an asynchronous request, so the position is verified before trusting it.
+	strategies := opts.allowedStrategies()
+	target := WindowPosition{X: x, Y: y, Width: width, Height: height}
+	start := time.Now()
+	for i, strategy := range strategies {
+		succeeded := strategy.Move(hwnd, x, y, width, height)
+		if succeeded && !moveVerified(hwnd, *pos, target) {
+			log(true, strategy.Name, "strategy reported success, but the window did not reach the target.")
+			succeeded = false
+		}
+		if opts.Report != nil {
+			opts.Report(strategy.Name, succeeded)
+		}
+		if succeeded {
+			log(debug, "Window moved successfully using", strategy.Name, "strategy.")
+			return nil
+		}
+		if opts.Timeout > 0 && time.Since(start) >= opts.Timeout {
+			return fmt.Errorf("failed to move window within %v", opts.Timeout)
+		}
+		if i+1 < len(strategies) {
+			log(true, strategy.Name, "strategy failed, trying", strategies[i+1].Name, "strategy.")
+		}
 	}
 
-	// Flags for SetWindowPos
-	flags := SWP_SHOWWINDOW
+	return errMoveExhausted
+}
 
-	// Try the standard method
-	if trySetWindowPos(hwnd, x, y, width, height, uint32(flags)) {
-		log(debug, "Window moved successfully using standard SetWindowPos.")
-		return nil
-	}
-	log(true, "Standard SetWindowPos failed, trying AttachThreadInput method.")
+// errMoveExhausted is returned if no move strategy could move a window
+var errMoveExhausted = errors.New("failed to move window after multiple attempts")
 
-	// Try AttachThreadInput method
-	if tryAttachThreadInputForSetPos(hwnd, x, y, width, height, uint32(flags)) {
-		log(debug, "Window moved successfully using AttachThreadInput.")
-		return nil
-	}
-	log(true, "AttachThreadInput method failed, trying minimize/restore trick.")
+// Verification of a move, see moveVerified
+const (
+	moveVerifyTolerance = 8                      // Pixels an edge may differ from the target
+	moveVerifyTimeout   = 250 * time.Millisecond // Time asynchronous moves get to take effect
+	moveVerifyInterval  = 25 * time.Millisecond  // Time between the checks
+)
 
-	// Try minimize/restore method
-	if tryMinimizeRestoreForSetPos(hwnd, x, y, width, height, uint32(flags)) {
-		log(debug, "Window moved successfully using minimize/restore trick.")
-		return nil
+// moveVerified checks if a window reached the target after a strategy reported success.
+// The position is read again until it matches or the verification times out. Apps may limit
+// their size, e.g. to a minimum or to whole text cells, so a window whose top-left corner
+// reached the target also counts as moved, as long as it changed.
+func moveVerified(hwnd syscall.Handle, before, target WindowPosition) bool {
+	debug := false
+	deadline := time.Now().Add(moveVerifyTimeout)
+	for {
+		current, err := getWindowPosition(hwnd)
+		if err != nil {
+			return false
+		}
+		if rectDrift(current.Rect(), target.Rect()) <= moveVerifyTolerance {
+			return true
+		}
+		cornerReached := max(current.X-target.X, target.X-current.X, current.Y-target.Y, target.Y-current.Y) <= moveVerifyTolerance
+		if cornerReached && current.Rect() != before.Rect() {
+			log(debug, "Window reached the target position, the app limited its size to", current.Width, "x", current.Height)
+			return true
+		}
+		if time.Now().After(deadline) {
+			log(debug, "Window is at", current.Rect(), "instead of", target.Rect())
+			return false
+		}
+		time.Sleep(moveVerifyInterval)
 	}
-	log(true, "Minimize/restore method failed, trying SetWindowPlacement method.")
+}
 
-	// Try SetWindowPlacement method
-	if trySetWindowPlacementForSetPos(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using SetWindowPlacement.")
-		return nil
-	}
-	log(true, "SetWindowPlacement method failed, trying async window positioning.")
+// Waits of the move strategies for a window state change, see waitUntil
+const (
+	stateWaitTimeout  = 250 * time.Millisecond // Longest wait for a window to reach the expected state
+	stateWaitInterval = 10 * time.Millisecond  // Time between the checks
+)
 
-	// Try async window positioning
-	if tryAsyncWindowPos(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using async window positioning.")
-		return nil
+// waitUntil checks the condition until it is met or the timeout expires and reports whether it was met.
+// Unlike a fixed sleep it returns as soon as the window reached the expected state.
+func waitUntil(timeout time.Duration, condition func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stateWaitInterval)
 	}
-	log(true, "Async window positioning failed, trying PostMessage approach.")
+	return true
+}
 
-	// Try PostMessage approach
-	if tryPostMessageApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using PostMessage approach.")
-		return nil
-	}
-	log(true, "PostMessage approach failed, trying SendMessage approach.")
+// waitUntilMinimized waits for a minimize to complete.
+func waitUntilMinimized(hwnd syscall.Handle) bool {
+	return waitUntil(stateWaitTimeout, func() bool { return isWindowMinimized(hwnd) })
+}
 
-	// Try SendMessage approach
-	if trySendMessageApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using SendMessage approach.")
-		return nil
-	}
-	log(true, "SendMessage approach failed, trying indirect approach.")
+// waitUntilRestored waits for a restore from the minimized or maximized state to complete, so a
+// following SetWindowPos does not race the restore. A minimized window that was maximized before
+// is restored to maximized, the wait then ends at the timeout.
+func waitUntilRestored(hwnd syscall.Handle) bool {
+	return waitUntil(stateWaitTimeout, func() bool { return !isWindowMinimized(hwnd) && !isWindowMaximized(hwnd) })
+}
 
-	// Try indirect approach
-	if tryIndirectApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using indirect approach.")
-		return nil
+// moveStrategy is a named technique to move and resize a window.
+// Move returns true if the technique reports success.
+type moveStrategy struct {
+	Name string
+	Move func(hwnd syscall.Handle, x, y, width, height int) bool
+}
+
+// moveStrategies lists the move techniques in the order MoveWindowAccurate tries them.
+// Each technique works around different elevation and window state restrictions.
+var moveStrategies = []moveStrategy{
+	{Name: "SetWindowPos", Move: func(hwnd syscall.Handle, x, y, width, height int) bool {
+		return trySetWindowPos(hwnd, x, y, width, height, SWP_SHOWWINDOW)
+	}},
+	{Name: "AttachThreadInput", Move: func(hwnd syscall.Handle, x, y, width, height int) bool {
+		return tryAttachThreadInputForSetPos(hwnd, x, y, width, height, SWP_SHOWWINDOW)
+	}},
+	{Name: "MinimizeRestore", Move: func(hwnd syscall.Handle, x, y, width, height int) bool {
+		return tryMinimizeRestoreForSetPos(hwnd, x, y, width, height, SWP_SHOWWINDOW)
+	}},
+	{Name: "SetWindowPlacement", Move: trySetWindowPlacementForSetPos},
+	{Name: "AsyncWindowPos", Move: tryAsyncWindowPos},
+	{Name: "PostMessage", Move: tryPostMessageApproach},
+	{Name: "SendMessage", Move: trySendMessageApproach},
+	{Name: "Indirect", Move: tryIndirectApproach},
+	{Name: "Combined", Move: tryCombinedApproach},
+	{Name: "Accessibility", Move: tryAccessibilityApproach},
+	{Name: "UIAutomation", Move: tryWindowsAutomationApproach},
+}
+
+// findMoveStrategy returns the move strategy with the given name.
+func findMoveStrategy(name string) (moveStrategy, bool) {
+	for _, strategy := range moveStrategies {
+		if strategy.Name == name {
+			return strategy, true
+		}
 	}
-	log(true, "Indirect approach failed, trying combined approach.")
+	return moveStrategy{}, false
+}
 
-	// Try combined approach
-	if tryCombinedApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using combined approach.")
-		return nil
+// orderedStrategyNames returns the names of all move strategies, the ones in the order first
+// and the others in the default order after them. Unknown names in the order are dropped.
+func orderedStrategyNames(order []string) []string {
+	var names []string
+	for _, name := range order {
+		if _, ok := findMoveStrategy(name); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
 	}
-	log(true, "Combined approach failed, trying Accessibility approach.")
+	for _, strategy := range moveStrategies {
+		if !slices.Contains(names, strategy.Name) {
+			names = append(names, strategy.Name)
+		}
+	}
+	return names
+}
 
-	// Try Accessibility approach
-	if tryAccessibilityApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using Accessibility approach.")
-		return nil
+// testMoveStrategy moves a window by a test offset using a single strategy and reports the outcome.
+// If the window moved, it is moved back to its original position using the same strategy.
+func testMoveStrategy(hwnd syscall.Handle, strategy moveStrategy) string {
+	debug := true
+	const testOffset = 20
+	log(debug, "Testing strategy", strategy.Name, "for handle:", hwnd)
+
+	before, err := getWindowPosition(hwnd)
+	if err != nil {
+		return fmt.Sprintf("%s: cannot read window position: %v", strategy.Name, err)
+	}
+	if className := getClassName(hwnd); isShellWindowClass(className) {
+		return fmt.Sprintf("%s: refusing to move shell window of class '%s'", strategy.Name, className)
 	}
-	log(true, "Accessibility approach failed, trying Windows UI Automation approach.")
 
-	// Try Windows UI Automation approach
-	if tryWindowsAutomationApproach(hwnd, x, y, width, height) {
-		log(debug, "Window moved successfully using Windows UI Automation approach.")
-		return nil
+	reported := strategy.Move(hwnd, before.X+testOffset, before.Y+testOffset, before.Width, before.Height)
+	// Give asynchronous strategies time to take effect
+	waitUntil(300*time.Millisecond, func() bool {
+		current, err := getWindowPosition(hwnd)
+		return err == nil && (current.X != before.X || current.Y != before.Y)
+	})
+
+	after, err := getWindowPosition(hwnd)
+	if err != nil {
+		return fmt.Sprintf("%s: cannot read window position after the move: %v", strategy.Name, err)
+	}
+	moved := after.X != before.X || after.Y != before.Y
+	if moved {
+		strategy.Move(hwnd, before.X, before.Y, before.Width, before.Height)
 	}
 
-	return fmt.Errorf("failed to move window after multiple attempts")
+	outcome := "failure"
+	if reported {
+		outcome = "success"
+	}
+	movement := "did not move"
+	if moved {
+		movement = "moved"
+	}
+	result := fmt.Sprintf("%s: reported %s, window %s (%d,%d -> %d,%d)",
+		strategy.Name, outcome, movement, before.X, before.Y, after.X, after.Y)
+	log(debug, result)
+	return result
 }
 
 // trySetWindowPlacementForSetPos uses SetWindowPlacement to set window position
@@ -590,7 +1090,7 @@ func tryCombinedApproach(hwnd syscall.Handle, x, y, width, height int) bool {
 	}
 
 	// Step 2: Wait for minimize to complete
-	time.Sleep(200 * time.Millisecond)
+	waitUntilMinimized(hwnd)
 
 	// Step 3: Try to set position while minimized (this might work for some windows)
 	ret, _, err = procSetWindowPos.Call(
@@ -623,6 +1123,10 @@ func tryCombinedApproach(hwnd syscall.Handle, x, y, width, height int) bool {
 		return false
 	}
 
+	// The input state is shared, see move_concurrency.go
+	unlock := lockThreadInput()
+	defer unlock()
+
 	currentThreadID, _, err := procGetCurrentThreadId.Call()
 	if currentThreadID == 0 {
 		if errno, ok := err.(syscall.Errno); ok && errno != 0 {
@@ -844,26 +1348,9 @@ func focusWindow(hwnd syscall.Handle) error {
 
 	// Check if window is outside all displays
 	if !isRectOnScreen(rect, virtualScreen) {
-		log(debug, "Window is outside all displays, moving to primary display")
-
-		// Calculate new position (centered on primary display)
-		width := int(rect.Right - rect.Left)
-		height := int(rect.Bottom - rect.Top)
-		x := int(primaryDisplay.Left) + (int(primaryDisplay.Right-primaryDisplay.Left)-width)/2
-		y := int(primaryDisplay.Top) + (int(primaryDisplay.Bottom-primaryDisplay.Top)-height)/2
-
-		// Move window to primary display
-		ret, _, err = procSetWindowPos.Call(
-			uintptr(hwnd),
-			0, // HWND_TOP
-			uintptr(x),
-			uintptr(y),
-			uintptr(width),
-			uintptr(height),
-			SWP_SHOWWINDOW,
-		)
-		if ret == 0 {
-			log(debug, "SetWindowPos failed: %v", err)
+		log(debug, "Window is outside all displays, centering it on the nearest monitor")
+		if err := centerWindowOnMonitor(hwnd); err != nil {
+			log(debug, "Failed to center window:", err)
 		}
 	}
 
@@ -930,6 +1417,10 @@ func tryAttachThreadInput(hwnd syscall.Handle) bool {
 		return false
 	}
 
+	// The input state is shared, see move_concurrency.go
+	unlock := lockThreadInput()
+	defer unlock()
+
 	currentThreadID, _, err := procGetCurrentThreadId.Call()
 	if currentThreadID == 0 {
 		if errno, ok := err.(syscall.Errno); ok && errno != 0 {
@@ -1036,8 +1527,8 @@ func tryMinimizeRestore(hwnd syscall.Handle) bool {
 			return false
 		}
 
-		// Small delay to ensure minimize completes
-		time.Sleep(250 * time.Millisecond)
+		// Wait for the minimize to complete
+		waitUntilMinimized(hwnd)
 
 		// Restore the window
 		ret, _, err = procShowWindow.Call(uintptr(hwnd), SW_RESTORE)
@@ -1189,6 +1680,10 @@ func tryAttachThreadInputForSetPos(hwnd syscall.Handle, x, y, width, height int,
 		return false
 	}
 
+	// The input state is shared, see move_concurrency.go
+	unlock := lockThreadInput()
+	defer unlock()
+
 	currentThreadID, _, _ := procGetCurrentThreadId.Call()
 	if currentThreadID == 0 {
 		return false
@@ -1260,11 +1755,11 @@ func tryMinimizeRestoreForSetPos(hwnd syscall.Handle, x, y, width, height int, f
 			return false
 		}
 
-		// Small delay to ensure minimize completes
-		time.Sleep(100 * time.Millisecond)
+		// Wait for the minimize to complete
+		waitUntilMinimized(hwnd)
 
 		// Restore the window to its previous state
-		ret, _, _ = procShowWindow.Call(uintptr(hwnd), placement.ShowCmd)
+		ret, _, _ = procShowWindow.Call(uintptr(hwnd), uintptr(placement.ShowCmd))
 		if ret == 0 {
 			return false
 		}
@@ -1297,8 +1792,8 @@ func tryPostMessageApproach(hwnd syscall.Handle, x, y, width, height int) bool {
 		log(true, "PostMessage (SC_RESTORE) failed")
 	}
 
-	// Step 2: Small delay to allow restore to complete
-	time.Sleep(100 * time.Millisecond)
+	// Step 2: Wait for the restore to complete
+	waitUntilRestored(hwnd)
 
 	// Step 3: Try to set position with async flag
 	ret, _, err = procSetWindowPos.Call(
@@ -1344,8 +1839,8 @@ func trySendMessageApproach(hwnd syscall.Handle, x, y, width, height int) bool {
 		}
 	}
 
-	// Step 2: Small delay to allow restore to complete
-	time.Sleep(100 * time.Millisecond)
+	// Step 2: Wait for the restore to complete
+	waitUntilRestored(hwnd)
 
 	// Step 3: Try to set position
 	ret, _, _ = procSetWindowPos.Call(
@@ -1423,8 +1918,11 @@ func tryIndirectApproach(hwnd syscall.Handle, x, y, width, height int) bool {
 			log(true, "Failed to remove topmost style:", err)
 			return false
 		}
-		// Small delay to let Windows process the change
-		time.Sleep(50 * time.Millisecond)
+		// Wait for Windows to process the change
+		waitUntil(stateWaitTimeout, func() bool {
+			exStyle, err := getWindowLong(hwnd, GWL_EXSTYLE)
+			return err != nil || exStyle&WS_EX_TOPMOST == 0
+		})
 	}
 
 	// Step 3: Try to set position with minimal flags
@@ -1561,3 +2059,113 @@ func tryWindowsAutomationApproach(hwnd syscall.Handle, x, y, width, height int)
 
 	return ret != 0
 }
+
+// getDpiForRect returns the effective DPI of the monitor that contains most of the rectangle.
+// It falls back to USER_DEFAULT_SCREEN_DPI if the DPI cannot be determined.
+// Note: The process is made per-monitor DPI aware at startup, see enableDpiAwareness,
+// otherwise Windows reports 96 DPI for every monitor.
+func getDpiForRect(rect RECT) uint32 {
+	debug := false
+	hMonitor, _, _ := procMonitorFromRect.Call(uintptr(unsafe.Pointer(&rect)), MONITOR_DEFAULTTONEAREST)
+	if hMonitor == 0 {
+		log(debug, "MonitorFromRect failed for rectangle:", rect)
+		return USER_DEFAULT_SCREEN_DPI
+	}
+	if err := procGetDpiForMonitor.Find(); err != nil {
+		log(debug, "GetDpiForMonitor is not available:", err)
+		return USER_DEFAULT_SCREEN_DPI
+	}
+	var dpiX, dpiY uint32
+	ret, _, _ := procGetDpiForMonitor.Call(hMonitor, MDT_EFFECTIVE_DPI, uintptr(unsafe.Pointer(&dpiX)), uintptr(unsafe.Pointer(&dpiY)))
+	if ret != 0 || dpiX == 0 { // HRESULT S_OK is 0
+		log(debug, "GetDpiForMonitor failed with HRESULT:", ret)
+		return USER_DEFAULT_SCREEN_DPI
+	}
+	return dpiX
+}
+
+// scaleForDpi converts a value between logical (96 DPI) and physical units.
+func scaleForDpi(value int, fromDpi, toDpi uint32) int {
+	if fromDpi == 0 || toDpi == 0 || fromDpi == toDpi {
+		return value
+	}
+	return (value*int(toDpi) + int(fromDpi)/2) / int(fromDpi)
+}
+
+// Rect returns the rectangle covered by the position.
+func (pos WindowPosition) Rect() RECT {
+	return RECT{
+		Left:   int32(pos.X),
+		Top:    int32(pos.Y),
+		Right:  int32(pos.X + pos.Width),
+		Bottom: int32(pos.Y + pos.Height),
+	}
+}
+
+// Physical returns the position in physical pixels.
+// If the monitor the position was saved on is connected, the position is placed relative to it.
+// Logical sizes are converted using the DPI of the monitor the position is located on.
+// Physical sizes saved at another DPI are scaled, so the window keeps its visible size.
+func (pos WindowPosition) Physical() WindowPosition {
+	if pos.MonitorDeviceName != "" {
+		if monitors, err := getCachedMonitors(); err == nil {
+			pos = pos.placedOnMonitor(monitors)
+		}
+	}
+	if !pos.Logical {
+		return pos.scaledToCurrentDpi()
+	}
+	dpi := getDpiForRect(pos.Rect())
+	pos.Width = scaleForDpi(pos.Width, USER_DEFAULT_SCREEN_DPI, dpi)
+	pos.Height = scaleForDpi(pos.Height, USER_DEFAULT_SCREEN_DPI, dpi)
+	pos.Logical = false
+	return pos
+}
+
+// getForegroundWindow returns the handle of the window the user is currently working with.
+func getForegroundWindow() syscall.Handle {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	return syscall.Handle(hwnd)
+}
+
+// messageBeep plays the system warning sound to signal that an action was not possible.
+func messageBeep() {
+	procMessageBeep.Call(MB_ICONWARNING)
+}
+
+// clickThroughExStyles are the extended styles overlay tools use to make a window click-through
+const clickThroughExStyles = WS_EX_LAYERED | WS_EX_TRANSPARENT
+
+// applyExStyleFlags sets the bits of mask in the extended window style to the values in flags.
+// If WS_EX_LAYERED is added, the window is made fully opaque, as a layered window without
+// attributes is invisible.
+func applyExStyleFlags(hwnd syscall.Handle, mask, flags uint32) error {
+	debug := false
+	current, err := getWindowLong(hwnd, GWL_EXSTYLE)
+	if err != nil {
+		return err
+	}
+	exStyle := uint32(current)
+	desired := exStyle&^mask | flags&mask
+	if desired == exStyle {
+		return nil
+	}
+	log(debug, fmt.Sprintf("Changing extended style of %v from 0x%08X to 0x%08X", hwnd, exStyle, desired))
+
+	// GWL_EXSTYLE is negative, so pass it sign-extended
+	index := int32(GWL_EXSTYLE)
+	ret, _, err := procSetWindowLongPtrW.Call(uintptr(hwnd), uintptr(index), uintptr(desired))
+	if ret == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno != 0 {
+			return fmt.Errorf("SetWindowLongPtrW failed: %v", errno)
+		}
+	}
+	if desired&WS_EX_LAYERED != 0 && exStyle&WS_EX_LAYERED == 0 {
+		procSetLayeredWindowAttributes.Call(uintptr(hwnd), 0, 255, LWA_ALPHA)
+	}
+
+	// Let the window apply the changed styles
+	procSetWindowPos.Call(uintptr(hwnd), 0, 0, 0, 0, 0,
+		SWP_NOMOVE|SWP_NOSIZE|SWP_NOZORDER|SWP_NOACTIVATE|SWP_FRAMECHANGED)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode/utf16"
+)
+
+// TestUTF16ToString checks the conversion of window titles returned by GetWindowText.
+func TestUTF16ToString(t *testing.T) {
+	emoji := utf16.Encode([]rune("📝 Notes"))
+	tests := []struct {
+		name string
+		buf  []uint16
+		want string
+	}{
+		{"ascii", utf16.Encode([]rune("Notepad")), "Notepad"},
+		{"emoji surrogate pair", emoji, "📝 Notes"},
+		{"dangling high surrogate at the end", append(utf16.Encode([]rune("Chat ")), 0xD83D), "Chat "},
+		{"only a dangling high surrogate", []uint16{0xD83D}, ""},
+		{"embedded NUL", append(utf16.Encode([]rune("Title")), 0, 'x', 'y'), "Title"},
+		{"NUL after a surrogate pair", append(emoji, 0, 0xD83D), "📝 Notes"},
+		{"empty", nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := utf16ToString(test.buf); got != test.want {
+				t.Errorf("utf16ToString(%#v) = %q, want %q", test.buf, got, test.want)
+			}
+		})
+	}
+}
+
+// TestEmojiIdentifierRoundTrip checks that a window with an emoji title still matches its
+// identifier after the identifier was saved as a JSON map key and loaded again.
+func TestEmojiIdentifierRoundTrip(t *testing.T) {
+	window := WindowInfo{
+		Title:      utf16ToString(utf16.Encode([]rune("🚀 Launch – Päckchen 日本"))),
+		ClassName:  "Chrome_WidgetWin_1",
+		Executable: `C:\Program Files\App\app.exe`,
+		Style:      0x14CF0000,
+		ExStyle:    0x00000100,
+	}
+	identifier := buildIdentifier(window, allIdentifierFields())
+
+	data, err := json.Marshal(map[string]WindowPosition{identifier: {X: 10, Y: 20, Width: 800, Height: 600}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded map[string]WindowPosition
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("got %d positions, want 1", len(loaded))
+	}
+	for key := range loaded {
+		if key != identifier {
+			t.Fatalf("identifier changed in JSON: got %q, want %q", key, identifier)
+		}
+		parts, ok := splitIdentifier(key)
+		if !ok || parts[0] != window.Title {
+			t.Fatalf("splitIdentifier(%q) = %q, %v, want title %q", key, parts, ok, window.Title)
+		}
+		if !identifierMatches(key, false, window) {
+			t.Fatalf("identifier %q does not match its window", key)
+		}
+		if _, _, ok := findSavedPosition(window, loaded); !ok {
+			t.Fatal("findSavedPosition does not find the loaded position")
+		}
+	}
+}
+
+// TestWithinPositionTolerance checks the tolerance for the corner and the size of a window.
+func TestWithinPositionTolerance(t *testing.T) {
+	previous := int(positionTolerance.Load())
+	setPositionTolerance(4)
+	defer setPositionTolerance(previous)
+
+	target := RECT{Left: 100, Top: 100, Right: 900, Bottom: 700}
+	tests := []struct {
+		name string
+		rect RECT
+		want bool
+	}{
+		{"identical", target, true},
+		{"corner within the tolerance", RECT{Left: 104, Top: 96, Right: 904, Bottom: 696}, true},
+		{"corner beyond the tolerance", RECT{Left: 105, Top: 100, Right: 905, Bottom: 700}, false},
+		{"size within the tolerance", RECT{Left: 100, Top: 100, Right: 904, Bottom: 696}, true},
+		{"size beyond the tolerance", RECT{Left: 100, Top: 100, Right: 895, Bottom: 700}, false},
+		{"corner and size offsets do not add up", RECT{Left: 104, Top: 100, Right: 908, Bottom: 700}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := withinPositionTolerance(test.rect, target); got != test.want {
+				t.Errorf("withinPositionTolerance(%v, %v) = %v, want %v", test.rect, target, got, test.want)
+			}
+		})
+	}
+
+	left := RECT{Left: -1920, Top: -8, Right: -1120, Bottom: 592}
+	if !withinPositionTolerance(RECT{Left: -1916, Top: -12, Right: -1116, Bottom: 588}, left) {
+		t.Error("an offset within the tolerance was rejected for negative coordinates")
+	}
+
+	setPositionTolerance(0)
+	if withinPositionTolerance(RECT{Left: 101, Top: 100, Right: 901, Bottom: 700}, target) {
+		t.Error("a zero tolerance accepted an offset of one pixel")
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+)
+
+// workspaceVersion is the version of the workspace file format
+const workspaceVersion = 1
+
+// keepCoordinates is the mapping choice that imports positions without translating them
+const keepCoordinates = "Keep coordinates"
+
+// Workspace is an exported set of saved positions together with the monitor topology
+// they were captured on, so they can be translated to the monitors of another machine.
+type Workspace struct {
+	Version   int                       `json:"version"`
+	Monitors  []Monitor                 `json:"monitors"`
+	Positions map[string]WindowPosition `json:"positions"`
+}
+
+// workspaceMenu creates the menu with the workspace export and import actions.
+func (wm *WindowManager) workspaceMenu() *fyne.Menu {
+	return fyne.NewMenu("",
+		fyne.NewMenuItem("Export workspace…", safeCallback(wm.exportWorkspace)),
+		fyne.NewMenuItem("Import workspace…", safeCallback(wm.importWorkspace)),
+		fyne.NewMenuItem("Export window list…", safeCallback(wm.exportWindowList)),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Snapshot windows to staging", safeCallback(wm.snapshotToStaging)),
+		fyne.NewMenuItem("Review staging…", safeCallback(wm.showStagingDialog)),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("Rescue off-screen windows", safeCallback(wm.runRescueOffscreenWindows)),
+	)
+}
+
+// exportWorkspace writes all saved positions and the current monitors to a file chosen by the user.
+func (wm *WindowManager) exportWorkspace() {
+	debug := true
+	monitors, err := GetMonitors()
+	if err != nil {
+		log(true, "Failed to get monitors:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+	workspace := Workspace{
+		Version:   workspaceVersion,
+		Monitors:  monitors,
+		Positions: wm.storage.GetAllPositions(),
+	}
+	data, err := json.MarshalIndent(workspace, "", "  ")
+	if err != nil {
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		defer panicHandler()
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		defer writer.Close()
+		if _, err := writer.Write(data); err != nil {
+			log(true, "Failed to export workspace:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Exported workspace with", len(workspace.Positions), "positions to", writer.URI().Path())
+	}, wm.mainWindow)
+	save.SetFileName("workspace.json")
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	save.Show()
+}
+
+// importWorkspace reads a workspace file chosen by the user and asks how its monitors map
+// to the local monitors before the positions are saved.
+func (wm *WindowManager) importWorkspace() {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		defer panicHandler()
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		if reader == nil {
+			return // Cancelled
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		var workspace Workspace
+		if err := json.Unmarshal(data, &workspace); err != nil {
+			dialog.ShowError(fmt.Errorf("not a workspace file: %v", err), wm.mainWindow)
+			return
+		}
+		if workspace.Version > workspaceVersion {
+			dialog.ShowError(fmt.Errorf("the workspace was exported by a newer version (format %d)", workspace.Version), wm.mainWindow)
+			return
+		}
+		wm.showMonitorMapping(workspace)
+	}, wm.mainWindow)
+	open.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+	open.Show()
+}
+
+// showMonitorMapping asks the user to map the source monitors of a workspace to local monitors
+// and imports the translated positions. Source monitors default to the local monitor with the
+// same index, or the primary monitor if there are fewer local monitors.
+func (wm *WindowManager) showMonitorMapping(workspace Workspace) {
+	debug := true
+	local, err := GetMonitors()
+	if err != nil {
+		log(true, "Failed to get monitors:", err)
+		dialog.ShowError(err, wm.mainWindow)
+		return
+	}
+
+	options := []string{keepCoordinates}
+	for _, monitor := range local {
+		options = append(options, monitor.String())
+	}
+	selects := make([]*widget.Select, len(workspace.Monitors))
+	var items []*widget.FormItem
+	for i, source := range workspace.Monitors {
+		selects[i] = widget.NewSelect(options, nil)
+		if len(local) == 0 {
+			selects[i].SetSelected(keepCoordinates)
+		} else if i < len(local) {
+			selects[i].SetSelected(local[i].String())
+		} else {
+			selects[i].SetSelected(local[primaryMonitor(local)].String())
+		}
+		items = append(items, widget.NewFormItem(source.String(), selects[i]))
+	}
+	if len(items) == 0 {
+		items = append(items, widget.NewFormItem("", widget.NewLabel("The workspace has no monitor information, coordinates are kept.")))
+	}
+
+	title := fmt.Sprintf("Import %d positions", len(workspace.Positions))
+	dialog.ShowForm(title, "Import", "Cancel", items, func(confirmed bool) {
+		defer panicHandler()
+		if !confirmed {
+			return
+		}
+		// Resolve the chosen target monitor of every source monitor, nil keeps the coordinates
+		targets := make([]*Monitor, len(selects))
+		for i, sel := range selects {
+			for j := range local {
+				if local[j].String() == sel.Selected {
+					targets[i] = &local[j]
+				}
+			}
+		}
+
+		translated := make(map[string]WindowPosition, len(workspace.Positions))
+		for identifier, pos := range workspace.Positions {
+			if source := monitorForRect(pos.Rect(), workspace.Monitors); source >= 0 && targets[source] != nil {
+				pos = translateToMonitor(pos, workspace.Monitors[source], *targets[source])
+			}
+			translated[identifier] = pos
+		}
+		if err := wm.storage.SavePositions(translated); err != nil {
+			log(true, "Failed to import workspace:", err)
+			dialog.ShowError(err, wm.mainWindow)
+			return
+		}
+		log(debug, "Imported", len(translated), "positions from workspace.")
+		wm.setupMainWindowContent() // Refresh the UI
+	}, wm.mainWindow)
+}
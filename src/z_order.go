@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sort"
+	"syscall"
+)
+
+/*
+	Stacking order:
+	- Saving a position records how far from the top of the Z-order the window is, as Order
+	  (1 is the topmost window, 0 means not recorded). Snapshots record the order of all windows at once.
+	- Applying a profile raises the matched windows from the bottom to the top, so the window with
+	  the lowest Order ends up on top. Windows of other processes cannot always be raised, so the
+	  stacking order is restored on a best-effort basis.
+*/
+
+// zOrderIndex returns the number of top-level windows above a window, including invisible ones.
+// Only the relative order of the indexes is meaningful.
+func zOrderIndex(hwnd syscall.Handle) int {
+	index := 0
+	for above := hwnd; ; index++ {
+		previous, _, _ := procGetWindow.Call(uintptr(above), GW_HWNDPREV)
+		if previous == 0 {
+			return index
+		}
+		above = syscall.Handle(previous)
+	}
+}
+
+// raiseWindow places a window at the top of the Z-order without activating it.
+// Topmost windows stay above the others.
+func raiseWindow(hwnd syscall.Handle) bool {
+	ret, _, _ := procSetWindowPos.Call(uintptr(hwnd), HWND_TOP, 0, 0, 0, 0, SWP_NOMOVE|SWP_NOSIZE|SWP_NOACTIVATE)
+	return ret != 0
+}
+
+// restoreZOrder raises the open windows matching the positions in the recorded stacking order.
+// The primary window of the layout is raised last, so it keeps the focus it got.
+func (wm *WindowManager) restoreZOrder(positions map[string]WindowPosition) {
+	debug := true
+	windows, err := EnumerateWindows()
+	if err != nil {
+		logWarn("Failed to enumerate windows to restore the stacking order:", err)
+		return
+	}
+	type stacked struct {
+		hwnd    syscall.Handle
+		pos     WindowPosition
+		primary bool
+	}
+	var stack []stacked
+	for _, window := range withoutExcluded(windows, wm.getSettings()) {
+		if _, pos, ok := findSavedPosition(window, positions); ok && pos.Order > 0 && !isWindowMinimized(window.Handle) {
+			stack = append(stack, stacked{window.Handle, pos, pos.FinishWithFocus})
+		}
+	}
+	if len(stack) < 2 {
+		return
+	}
+	// Bottom first: the highest Order is raised first and ends up below the others
+	sort.SliceStable(stack, func(i, j int) bool {
+		if stack[i].primary != stack[j].primary {
+			return !stack[i].primary
+		}
+		return stack[i].pos.Order > stack[j].pos.Order
+	})
+	raised := 0
+	for _, window := range stack {
+		if raiseWindow(window.hwnd) {
+			raised++
+		}
+	}
+	log(debug, "Restored the stacking order of", raised, "of", len(stack), "windows.")
+}